@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// errAlreadyRunning is returned by lockInstance when another KEF Bar holds
+// the lock.
+var errAlreadyRunning = errors.New("another instance is already running")
+
+// lockFilePath returns ~/Library/Application Support/kefbar/kefbar.lock.
+func lockFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kefbar", "kefbar.lock"), nil
+}
+
+// lockInstance takes the single-instance lock, returning the lock file,
+// which holds the lock until it is closed or the process exits, so a
+// crashed instance never leaves a stale lock behind. When another instance
+// has it, the error wraps errAlreadyRunning and names its PID.
+func lockInstance() (*os.File, error) {
+	path, err := lockFilePath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			if pid, ok := lockHolder(f); ok {
+				return nil, fmt.Errorf("%w (pid %d)", errAlreadyRunning, pid)
+			}
+			return nil, errAlreadyRunning
+		}
+		return nil, err
+	}
+
+	// Record our PID for the next instance to report; the lock works
+	// without it
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		slog.Debug("Could not record PID in lock file", "path", path, "error", err)
+	}
+	return f, nil
+}
+
+// lockHolder reads the PID the instance holding the lock recorded.
+func lockHolder(f *os.File) (int, bool) {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	return pid, err == nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/inquire/kefbar-go/internal/config"
+)
+
+// Environment variables overriding the log_level and log_format settings.
+const (
+	logLevelEnv  = "KEFBAR_LOG_LEVEL"
+	logFormatEnv = "KEFBAR_LOG_FORMAT"
+)
+
+// setupLogging installs the default logger for the configured level and
+// format, either of which the environment may override, writing to stderr
+// and, when LogToFile is set, the log file too. The returned function
+// closes the log file; it is never nil. On a bad level or format the
+// logger is left as it was.
+func setupLogging(cfg *config.Config) (func(), error) {
+	level, format := cfg.LogLevel, cfg.LogFormat
+	if env := os.Getenv(logLevelEnv); env != "" {
+		level = env
+	}
+	if env := os.Getenv(logFormatEnv); env != "" {
+		format = env
+	}
+
+	var (
+		out      io.Writer = os.Stderr
+		closeLog           = func() {}
+		fileErr  error
+	)
+	if cfg.LogToFile {
+		if path, err := logFilePath(); err != nil {
+			fileErr = err
+		} else if file, err := openLogFile(path); err != nil {
+			fileErr = err
+		} else {
+			out = io.MultiWriter(os.Stderr, file)
+			closeLog = func() { file.Close() }
+		}
+	}
+
+	handler, err := newLogHandler(out, level, format)
+	if err != nil {
+		closeLog()
+		return func() {}, err
+	}
+	slog.SetDefault(slog.New(handler))
+
+	if fileErr != nil {
+		return closeLog, fmt.Errorf("log file: %w", fileErr)
+	}
+	return closeLog, nil
+}
+
+// newLogHandler returns a handler writing to w at level, e.g. "debug", in
+// format, "text" or "json". Empty values mean info and text.
+func newLogHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: use debug, info, warn or error", level)
+		}
+		opts.Level = l
+	}
+
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: use text or json", format)
+	}
+}
@@ -2,17 +2,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github/com/inquire/kefbar-go/internal/config"
-	"github/com/inquire/kefbar-go/internal/controller"
-	"github/com/inquire/kefbar-go/internal/hotkeys"
-	"github/com/inquire/kefbar-go/internal/ui"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/hotkeys"
+	"github.com/inquire/kefbar-go/internal/mqtt"
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/internal/server"
+	"github.com/inquire/kefbar-go/internal/ui"
 )
 
+// serverShutdownTimeout bounds how long in-flight control server requests
+// may delay quitting.
+const serverShutdownTimeout = 2 * time.Second
+
 func main() {
 	// Setup structured logging
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
@@ -21,6 +31,19 @@ func main() {
 
 	slog.Info("KEF Bar starting...")
 
+	// Only one instance may run, or the two fight over the hotkeys. Take
+	// the lock before opening the log file, which belongs to the running
+	// instance.
+	lock, err := lockInstance()
+	if errors.Is(err, errAlreadyRunning) {
+		slog.Info("KEF Bar is already running, exiting", "error", err)
+		os.Exit(0)
+	} else if err != nil {
+		slog.Warn("Could not take the single-instance lock", "error", err)
+	} else {
+		defer lock.Close()
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -28,29 +51,72 @@ func main() {
 		cfg = config.New()
 	}
 
+	// Switch to the configured log level and format
+	closeLog, err := setupLogging(cfg)
+	if err != nil {
+		slog.Warn("Could not apply logging settings", "error", err)
+	}
+	defer closeLog()
+
+	if !ui.DialogsAvailable() {
+		slog.Warn("Native dialogs unavailable, alerts will only be logged")
+	}
+
 	// Create controller
 	ctrl := controller.New(cfg)
 	defer ctrl.Close()
+	ctrl.SetAutoReconnect(cfg.AutoReconnect)
+	ctrl.StartSchedules()
 
 	// Auto-connect if we have a saved IP
 	if cfg.SpeakerIP != "" {
 		slog.Info("Loading saved IP", "ip", cfg.SpeakerIP)
 		ctrl.SetIP(cfg.SpeakerIP)
 
-		go func() {
+		safe.Go("initial connect", func() {
 			if err := ctrl.Connect(); err != nil {
 				slog.Warn("Failed to connect to saved IP", "ip", cfg.SpeakerIP, "error", err)
 			} else {
 				slog.Info("Connected to speaker", "ip", cfg.SpeakerIP)
 			}
-		}()
+		})
 	}
 
 	// Register global hotkeys
+	// Check for missing macOS permissions once hotkeys have had a go
+	perms := ui.NewPermissionCheck(cfg.NotifyOnTrackChange)
 	hotkeyMgr := hotkeys.NewManager(ctrl, cfg)
+	hotkeyMgr.SetRegistrationFailedCallback(func() {
+		perms.Report(ui.PermissionAccessibility, false)
+	})
 	hotkeyMgr.Register()
+	safe.Go("permission check", perms.Run)
 	defer hotkeyMgr.Unregister()
 
+	// Start the local control server if enabled
+	var srv *server.Server
+	if cfg.ServerEnabled {
+		srv = server.New(ctrl, cfg.ServerAddress)
+		if err := srv.Start(); err != nil {
+			slog.Error("Failed to start control server", "error", err)
+			srv = nil
+		}
+	}
+	// Bridge to MQTT if a broker is configured
+	bridge := mqtt.New(ctrl, cfg)
+	bridge.Start()
+
+	stopServer := func() {
+		if srv == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Warn("Control server did not shut down cleanly", "error", err)
+		}
+	}
+
 	// Handle OS signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -58,6 +124,9 @@ func main() {
 	go func() {
 		<-sigChan
 		slog.Info("Received interrupt signal, quitting...")
+		stopServer()
+		bridge.Stop()
+		closeLog()
 		os.Exit(0)
 	}()
 
@@ -72,8 +141,11 @@ func main() {
 
 	onExit := func() {
 		slog.Info("KEF Bar shutting down...")
+		stopServer()
+		bridge.Stop()
 		hotkeyMgr.Unregister()
 		ctrl.Close()
+		closeLog()
 		os.Exit(0)
 	}
 
@@ -0,0 +1,369 @@
+// kefctl - command-line control for KEF speakers, for scripting without the
+// menu bar app. It uses the speaker IP saved by KEF Bar unless -ip is given.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/discovery"
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// discoveryTimeout bounds the discover command.
+const discoveryTimeout = 10 * time.Second
+
+// errUsage marks errors caused by bad arguments.
+var errUsage = errors.New("usage")
+
+// command is a kefctl subcommand.
+type command struct {
+	usage       string
+	description string
+	needsIP     bool
+	run         func(ctrl *controller.Controller, cfg *config.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"status": {
+		usage:       "status",
+		description: "Show volume, mute, source and what's playing",
+		needsIP:     true,
+		run:         runStatus,
+	},
+	"volume": {
+		usage:       "volume [0-100|+N|-N]",
+		description: "Show the volume, or set it (absolute or relative)",
+		needsIP:     true,
+		run:         runVolume,
+	},
+	"mute": {
+		usage:       "mute",
+		description: "Mute the speaker",
+		needsIP:     true,
+		run:         func(ctrl *controller.Controller, _ *config.Config, _ []string) error { return ctrl.SetMute(true) },
+	},
+	"unmute": {
+		usage:       "unmute",
+		description: "Unmute the speaker",
+		needsIP:     true,
+		run:         func(ctrl *controller.Controller, _ *config.Config, _ []string) error { return ctrl.SetMute(false) },
+	},
+	"play-pause": {
+		usage:       "play-pause",
+		description: "Toggle playback",
+		needsIP:     true,
+		run:         func(ctrl *controller.Controller, _ *config.Config, _ []string) error { return ctrl.PlayPause() },
+	},
+	"next": {
+		usage:       "next",
+		description: "Skip to the next track",
+		needsIP:     true,
+		run:         func(ctrl *controller.Controller, _ *config.Config, _ []string) error { return ctrl.NextTrack() },
+	},
+	"previous": {
+		usage:       "previous",
+		description: "Go back to the previous track",
+		needsIP:     true,
+		run:         func(ctrl *controller.Controller, _ *config.Config, _ []string) error { return ctrl.PreviousTrack() },
+	},
+	"source": {
+		usage:       "source [name]",
+		description: "Show the input, or switch it (" + strings.Join(kef.Sources, ", ") + ")",
+		needsIP:     true,
+		run:         runSource,
+	},
+	"name": {
+		usage:       "name [new name]",
+		description: "Show the speaker's name, or rename it",
+		needsIP:     true,
+		run:         runName,
+	},
+	"config": {
+		usage:       "config dump",
+		description: "Print the effective configuration as JSON, secrets redacted",
+		run:         runConfig,
+	},
+	"discover": {
+		usage:       "discover [-all] [-json]",
+		description: "Find a speaker and print its IP, or list every speaker",
+		run:         runDiscover,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the arguments and runs the command, returning the exit code.
+func run(args []string) int {
+	flags := flag.NewFlagSet("kefctl", flag.ContinueOnError)
+	ip := flags.String("ip", "", "speaker IP address (default: the saved one)")
+	verbose := flags.Bool("v", false, "enable debug logging")
+	flags.Usage = func() { printUsage(flags) }
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	level := slog.LevelWarn
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if flags.NArg() == 0 {
+		printUsage(flags)
+		return exitUsage
+	}
+
+	name, cmdArgs := flags.Arg(0), flags.Args()[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "kefctl: unknown command %q\n\n", name)
+		printUsage(flags)
+		return exitUsage
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Warn("Failed to load config", "error", err)
+	}
+	if *ip != "" {
+		cfg.SpeakerIP = *ip
+	}
+
+	if cmd.needsIP && cfg.SpeakerIP == "" {
+		fmt.Fprintln(os.Stderr, "kefctl: no speaker IP saved; pass -ip or run 'kefctl discover'")
+		return exitError
+	}
+
+	ctrl := controller.New(cfg)
+	defer ctrl.Close()
+	if cfg.SpeakerIP != "" {
+		ctrl.SetIP(cfg.SpeakerIP)
+	}
+
+	if err := cmd.run(ctrl, cfg, cmdArgs); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "usage: kefctl %s\n", cmd.usage)
+			return exitUsage
+		}
+		fmt.Fprintln(os.Stderr, "kefctl:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// printUsage prints the flags and commands.
+func printUsage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintln(out, "usage: kefctl [flags] <command> [args]")
+	fmt.Fprintln(out, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(out, "  %-28s %s\n", cmd.usage, cmd.description)
+	}
+
+	fmt.Fprintln(out, "\nFlags:")
+	flags.PrintDefaults()
+}
+
+// runStatus prints the speaker state.
+func runStatus(ctrl *controller.Controller, _ *config.Config, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	volume, err := ctrl.GetVolume()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Volume: %d%%\n", volume)
+
+	if muted, err := ctrl.GetMute(); err == nil {
+		fmt.Printf("Muted:  %t\n", muted)
+	}
+	if source, err := ctrl.GetSource(); err == nil {
+		fmt.Printf("Source: %s\n", kef.SourceLabel(source))
+	}
+	if info, err := ctrl.GetPlaybackInfo(); err == nil && info.Title != "" {
+		track := info.Title
+		if info.Artist != "" {
+			track += " - " + info.Artist
+		}
+		fmt.Printf("Track:  %s (%s)\n", track, info.State)
+	}
+	return nil
+}
+
+// runVolume prints or sets the volume. A leading sign makes the change
+// relative to the current level.
+func runVolume(ctrl *controller.Controller, _ *config.Config, args []string) error {
+	switch len(args) {
+	case 0:
+		volume, err := ctrl.GetVolume()
+		if err != nil {
+			return err
+		}
+		fmt.Println(volume)
+		return nil
+	case 1:
+	default:
+		return errUsage
+	}
+
+	arg := args[0]
+	value, err := strconv.Atoi(arg)
+	if err != nil {
+		return errUsage
+	}
+
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		// Relative changes start from, and unmute like, the menu bar steps
+		if _, err := ctrl.GetVolume(); err != nil {
+			return err
+		}
+		if _, err := ctrl.GetMute(); err != nil {
+			return err
+		}
+		return ctrl.AdjustVolume(value)
+	}
+
+	if value < 0 || value > 100 {
+		return errUsage
+	}
+	return ctrl.SetVolume(value)
+}
+
+// runSource prints or switches the input.
+func runSource(ctrl *controller.Controller, _ *config.Config, args []string) error {
+	switch len(args) {
+	case 0:
+		source, err := ctrl.GetSource()
+		if err != nil {
+			return err
+		}
+		fmt.Println(source)
+		return nil
+	case 1:
+	default:
+		return errUsage
+	}
+
+	source := strings.ToLower(args[0])
+	if !slices.Contains(kef.Sources, source) {
+		return errUsage
+	}
+	return ctrl.SetSource(source)
+}
+
+// runName prints the speaker's name, or renames it. The new name may be
+// given as several arguments, so it doesn't need quoting.
+func runName(ctrl *controller.Controller, _ *config.Config, args []string) error {
+	if len(args) == 0 {
+		name, err := ctrl.GetDeviceName()
+		if err != nil {
+			return err
+		}
+		fmt.Println(name)
+		return nil
+	}
+
+	return ctrl.SetDeviceName(strings.Join(args, " "))
+}
+
+// runDiscover finds a speaker and prints its IP. With -all or -json it
+// lists every speaker found instead.
+func runDiscover(_ *controller.Controller, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("discover", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	all := flags.Bool("all", false, "list every speaker found")
+	asJSON := flags.Bool("json", false, "list every speaker found as JSON")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	opts := discovery.Options{Methods: cfg.DiscoveryMethods, Scheme: cfg.Scheme, Port: cfg.Port}
+	if *all || *asJSON {
+		return listSpeakers(opts, *asJSON)
+	}
+
+	speaker, err := discovery.DiscoverSpeaker(context.Background(), discoveryTimeout, opts)
+	if err != nil {
+		return err
+	}
+
+	if speaker.Model != "" {
+		fmt.Printf("%s\t%s\n", speaker.IP, speaker.Model)
+	} else {
+		fmt.Println(speaker.IP)
+	}
+	return nil
+}
+
+// listSpeakers prints every speaker found, as tab-separated lines or JSON.
+func listSpeakers(opts discovery.Options, asJSON bool) error {
+	speakers, err := discovery.DiscoverAll(context.Background(), discoveryTimeout, opts)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(speakers)
+	}
+
+	if len(speakers) == 0 {
+		return fmt.Errorf("no speakers found")
+	}
+	for _, sp := range speakers {
+		fmt.Printf("%s\t%s\t%s\t%s\n", sp.IP, sp.Model, sp.Name, sp.MAC)
+	}
+	return nil
+}
+
+// runConfig prints the effective configuration, including any -ip given.
+func runConfig(_ *controller.Controller, cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "dump" {
+		return errUsage
+	}
+
+	values, err := cfg.Effective("")
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(values)
+}
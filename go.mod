@@ -1,4 +1,4 @@
-module github/com/inquire/kefbar-go
+module github.com/inquire/kefbar-go
 
 go 1.24.0
 
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// minBatchPaths is the smallest request worth batching; below it the event
+// queue saves nothing over plain getData calls.
+const minBatchPaths = 3
+
+// maxFreshQueueFailures is how many new queues in a row may fail their
+// first poll without a response before batching is given up on. One
+// network hiccup on reconnect shouldn't disable it.
+const maxFreshQueueFailures = 3
+
+// eventQueue is a speaker-side subscription to a set of paths. Polling it
+// returns the paths that changed since the last poll, so GetMany keeps the
+// latest value of each path and only fetches the changes.
+type eventQueue struct {
+	id     string
+	key    string // the subscribed paths, sorted and joined
+	values map[string]map[string]interface{}
+}
+
+// queueEvent is an entry returned by /api/event/pollQueue.
+type queueEvent struct {
+	Path      string      `json:"path"`
+	ItemType  string      `json:"itemType"`
+	ItemValue interface{} `json:"itemValue"`
+}
+
+// GetMany reads the values of several paths, keyed by path, in as few round
+// trips as the firmware allows. Each value is the object FirstValue would
+// return; paths whose value is unavailable are left out. Where the speaker
+// has no event queue, the paths are read one by one. An error is returned
+// only if nothing could be read.
+func (c *Client) GetMany(paths []string) (map[string]map[string]interface{}, error) {
+	if len(paths) >= minBatchPaths && !c.batchUnsupported.Load() {
+		values, err := c.getQueued(paths)
+		if err == nil || isTransportError(err) {
+			// An unreachable speaker won't answer one by one either
+			return values, err
+		}
+		slog.Debug("Batched read failed, reading paths one by one", "error", err)
+	}
+	return c.getSequential(paths)
+}
+
+// getQueued reads paths through an event queue, creating it on first use
+// or when the paths change.
+func (c *Client) getQueued(paths []string) (map[string]map[string]interface{}, error) {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+
+	sorted := slices.Clone(paths)
+	slices.Sort(sorted)
+	key := strings.Join(sorted, "\n")
+
+	fresh := false
+	if c.queue == nil || c.queue.key != key {
+		id, err := c.createQueue(c.ctx, sorted)
+		if err != nil {
+			if !isTransportError(err) {
+				// The firmware has no event queue; don't keep trying
+				c.batchUnsupported.Store(true)
+			}
+			return nil, err
+		}
+		c.queue = &eventQueue{id: id, key: key, values: make(map[string]map[string]interface{})}
+		fresh = true
+	}
+
+	events, err := c.pollQueue(c.queue.id)
+	if err != nil {
+		// The queue may have expired; start a new one next time. A queue
+		// the speaker rejects straight away isn't going to work, though,
+		// and neither is one whose first poll keeps getting no answer
+		// (e.g., it blocks until the request times out).
+		c.queue = nil
+		if fresh {
+			c.freshQueueFailures++
+			if !isTransportError(err) || c.freshQueueFailures >= maxFreshQueueFailures {
+				c.batchUnsupported.Store(true)
+			}
+		}
+		return nil, err
+	}
+	c.freshQueueFailures = 0
+
+	for _, ev := range events {
+		if ev.ItemType == "delete" {
+			delete(c.queue.values, ev.Path)
+			continue
+		}
+		if v, err := FirstValue([]interface{}{ev.ItemValue}); err == nil {
+			c.queue.values[ev.Path] = v
+		} else {
+			delete(c.queue.values, ev.Path)
+		}
+	}
+
+	// Read anything the queue hasn't reported yet directly
+	var missing []string
+	for _, path := range sorted {
+		if _, ok := c.queue.values[path]; !ok {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		values, err := c.getSequential(missing)
+		if err != nil && len(missing) == len(sorted) {
+			return nil, err
+		}
+		maps.Copy(c.queue.values, values)
+	}
+
+	return maps.Clone(c.queue.values), nil
+}
+
+// getSequential reads paths one getData call at a time.
+func (c *Client) getSequential(paths []string) (map[string]map[string]interface{}, error) {
+	values := make(map[string]map[string]interface{}, len(paths))
+	var errs []error
+	for _, path := range paths {
+		result, err := c.GetData(path, "value")
+		if err == nil {
+			var v map[string]interface{}
+			if v, err = FirstValue(result); err == nil {
+				values[path] = v
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+
+	if len(values) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return values, nil
+}
+
+// createQueue subscribes to paths and returns the new queue's ID.
+func (c *Client) createQueue(ctx context.Context, paths []string) (string, error) {
+	type subscription struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	body := struct {
+		Subscribe   []subscription `json:"subscribe"`
+		Unsubscribe []subscription `json:"unsubscribe"`
+	}{Unsubscribe: []subscription{}}
+	for _, path := range paths {
+		body.Subscribe = append(body.Subscribe, subscription{Path: path, Type: "itemWithValue"})
+	}
+
+	var id string
+	if err := c.post(ctx, "event/modifyQueue", body, &id); err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("speaker returned no queue ID")
+	}
+	return id, nil
+}
+
+// pollQueue returns the changes queued since the last poll without waiting
+// for new ones.
+func (c *Client) pollQueue(id string) ([]queueEvent, error) {
+	params := url.Values{}
+	params.Set("queueId", id)
+	params.Set("timeout", "0")
+
+	var events []queueEvent
+	if err := c.get("event/pollQueue", params, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// post performs a POST request to /api/<endpoint> with a JSON body and
+// decodes the JSON response into out.
+func (c *Client) post(ctx context.Context, endpoint string, body, out interface{}) error {
+	if c.host == "" {
+		return fmt.Errorf("no host configured")
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL(endpoint, nil), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &transportError{err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return httpError(resp)
+	}
+
+	return decodeJSON(resp, out)
+}
+
+// transportError marks a request that never got an HTTP response, which
+// says nothing about what the firmware supports.
+type transportError struct{ err error }
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// isTransportError reports whether err is a transportError.
+func isTransportError(err error) bool {
+	var te *transportError
+	return errors.As(err, &te)
+}
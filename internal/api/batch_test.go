@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+var batchPaths = []string{"player:volume", "settings:/releasetext", "settings:/mediaPlayer/mute"}
+
+// dropConnection answers a request by closing the connection, so the client
+// gets no response at all.
+func dropConnection(t *testing.T, w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
+
+func TestGetManyKeepsBatchingAfterNetworkHiccup(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/event/pollQueue" && down.Load() {
+			dropConnection(t, w)
+			return
+		}
+		stubSpeaker(w, r)
+	})
+
+	if _, err := client.GetMany(batchPaths); err == nil {
+		t.Fatal("GetMany() with the connection dropped succeeded")
+	}
+	if client.batchUnsupported.Load() {
+		t.Fatal("one dropped connection disabled batching")
+	}
+	down.Store(false)
+
+	values, err := client.GetMany(batchPaths)
+	if err != nil {
+		t.Fatalf("GetMany() after the hiccup error = %v", err)
+	}
+	if len(values) != len(batchPaths) {
+		t.Errorf("GetMany() read %d values, want %d", len(values), len(batchPaths))
+	}
+	if client.batchUnsupported.Load() {
+		t.Error("batching disabled after a successful batched read")
+	}
+}
+
+func TestGetManyGivesUpOnBatching(t *testing.T) {
+	t.Run("rejected queue", func(t *testing.T) {
+		client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/event/pollQueue" {
+				http.Error(w, "no such queue", http.StatusInternalServerError)
+				return
+			}
+			stubSpeaker(w, r)
+		})
+
+		// Falls back to reading one by one
+		if _, err := client.GetMany(batchPaths); err != nil {
+			t.Fatalf("GetMany() error = %v", err)
+		}
+		if !client.batchUnsupported.Load() {
+			t.Error("a queue the speaker rejects left batching enabled")
+		}
+	})
+
+	t.Run("repeated failures", func(t *testing.T) {
+		client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/event/pollQueue" {
+				dropConnection(t, w)
+				return
+			}
+			stubSpeaker(w, r)
+		})
+
+		for i := 1; i <= maxFreshQueueFailures; i++ {
+			if client.batchUnsupported.Load() {
+				t.Fatalf("batching disabled after %d failures, want %d", i-1, maxFreshQueueFailures)
+			}
+			_, _ = client.GetMany(batchPaths)
+		}
+		if !client.batchUnsupported.Load() {
+			t.Error("batching still enabled after repeated failures")
+		}
+
+		client.SetHost(client.host)
+		if client.batchUnsupported.Load() {
+			t.Error("SetHost kept batching disabled")
+		}
+	})
+}
@@ -2,37 +2,129 @@
 package api
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// maxDownloadSize bounds the size of downloaded resources such as album art.
+const maxDownloadSize = 8 << 20
+
+// maxResponseSize bounds the size of API responses.
+const maxResponseSize = 4 << 20
+
+// ErrNotJSON is returned when the host answers with something other than
+// JSON, usually because it isn't a KEF speaker (a router or captive portal,
+// say).
+var ErrNotJSON = errors.New("response is not JSON; the address may not be a KEF speaker")
+
+// ErrValueUnavailable is returned when the speaker reports a value as null or
+// empty, which happens briefly while it changes state (e.g., switching
+// sources). Callers should treat it as transient and retry later.
+var ErrValueUnavailable = errors.New("value not yet available")
+
 // Client communicates with the KEF speaker HTTP API.
 type Client struct {
+	scheme     string
 	host       string
 	port       int
 	httpClient *http.Client
 	ctx        context.Context
+
+	// longPollClient has no overall timeout; each long poll gets its own
+	// deadline instead
+	longPollClient *http.Client
+
+	// writes spaces out setData calls, see SetWriteInterval; nil when
+	// they aren't limited
+	writesMu sync.Mutex
+	writes   *writeLimiter
+
+	// Batched reads, see GetMany. freshQueueFailures counts new queues in
+	// a row whose first poll got no response.
+	batchMu            sync.Mutex
+	queue              *eventQueue
+	freshQueueFailures int
+	batchUnsupported   atomic.Bool
+}
+
+// ClientOption configures a Client in NewClient.
+type ClientOption func(*Client)
+
+// WithTransport makes the client send requests through transport instead
+// of DefaultTransport, e.g. a stub that answers without a speaker.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) { c.SetTransport(transport) }
+}
+
+// WithHTTPClient makes the client send requests with httpClient, whose own
+// timeout applies instead of the one given to NewClient. Long polls use its
+// transport without the timeout.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+		c.longPollClient = &http.Client{Transport: httpClient.Transport}
+	}
 }
 
 // NewClient creates a new API client.
-func NewClient(host string, port int, timeout time.Duration) *Client {
-	return &Client{
-		host: host,
-		port: port,
+func NewClient(host string, port int, timeout time.Duration, opts ...ClientOption) *Client {
+	c := &Client{
+		scheme: "http",
+		host:   host,
+		port:   port,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: DefaultTransport,
 		},
-		ctx: context.Background(),
+		longPollClient: &http.Client{Transport: DefaultTransport},
+		ctx:            context.Background(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
+}
+
+// SetTransport makes the client use its own connection pool instead of
+// DefaultTransport.
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+	c.longPollClient.Transport = transport
 }
 
 // SetHost updates the target host.
 func (c *Client) SetHost(host string) {
 	c.host = host
+
+	// A different speaker needs its own queue and may support batching
+	c.batchMu.Lock()
+	c.queue = nil
+	c.freshQueueFailures = 0
+	c.batchMu.Unlock()
+	c.batchUnsupported.Store(false)
+}
+
+// SetScheme sets the URL scheme, "http" (the default) or "https" for a
+// speaker behind a TLS proxy.
+func (c *Client) SetScheme(scheme string) error {
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", scheme)
+	}
+	c.scheme = scheme
+	return nil
 }
 
 // SetContext sets the context for requests.
@@ -42,52 +134,207 @@ func (c *Client) SetContext(ctx context.Context) {
 
 // GetData performs a GET request to /api/getData.
 func (c *Client) GetData(path, roles string) ([]interface{}, error) {
-	if c.host == "" {
-		return nil, fmt.Errorf("no host configured")
-	}
+	return c.GetDataContext(c.ctx, path, roles)
+}
 
+// GetDataContext is GetData bounded by ctx instead of the client's context.
+func (c *Client) GetDataContext(ctx context.Context, path, roles string) ([]interface{}, error) {
 	params := url.Values{}
 	params.Set("path", path)
 	params.Set("roles", roles)
 
-	apiURL := fmt.Sprintf("http://%s:%d/api/getData?%s", c.host, c.port, params.Encode())
-	req, err := http.NewRequestWithContext(c.ctx, "GET", apiURL, nil)
+	var result []interface{}
+	if err := c.getWith(ctx, c.httpClient, "getData", params, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// pingPath is read by Ping; every model has a device name.
+const pingPath = "settings:/deviceName"
+
+// Ping checks that the host answers as a KEF speaker by reading its device
+// name. It changes nothing on the speaker or the client, and ctx bounds it
+// instead of the client's context.
+func (c *Client) Ping(ctx context.Context) error {
+	params := url.Values{}
+	params.Set("path", pingPath)
+	params.Set("roles", "value")
+
+	var result []interface{}
+	if err := c.getWith(ctx, c.httpClient, "getData", params, &result); err != nil {
+		return err
+	}
+
+	data, err := FirstValue(result)
 	if err != nil {
+		return err
+	}
+	if _, ok := data["string_"].(string); !ok {
+		return fmt.Errorf("invalid device name format")
+	}
+
+	return nil
+}
+
+// Rows is a page of child nodes returned by /api/getRows.
+type Rows struct {
+	Count int                      `json:"rowsCount"`
+	Rows  []map[string]interface{} `json:"rows"`
+}
+
+// GetRows performs a GET request to /api/getRows, listing the children of
+// path in the range [from, to).
+func (c *Client) GetRows(path string, from, to int) (*Rows, error) {
+	params := url.Values{}
+	params.Set("path", path)
+	params.Set("roles", "@all")
+	params.Set("from", strconv.Itoa(from))
+	params.Set("to", strconv.Itoa(to))
+
+	var rows Rows
+	if err := c.get("getRows", params, &rows); err != nil {
 		return nil, err
 	}
+
+	return &rows, nil
+}
+
+// get performs a GET request to /api/<endpoint> and decodes the JSON
+// response into out.
+func (c *Client) get(endpoint string, params url.Values, out interface{}) error {
+	return c.getWith(c.ctx, c.httpClient, endpoint, params, out)
+}
+
+// getWith is get using the given context and HTTP client.
+func (c *Client) getWith(ctx context.Context, httpClient *http.Client, endpoint string, params url.Values, out interface{}) error {
+	if c.host == "" {
+		return fmt.Errorf("no host configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.apiURL(endpoint, params), nil)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return &transportError{err}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		return httpError(resp)
 	}
 
-	var result []interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	return decodeJSON(resp, out)
+}
+
+// maxErrorBodySize bounds how much of an error response is read for its
+// message.
+const maxErrorBodySize = 4 << 10
+
+// httpError describes a failed response, with the speaker's error message
+// if the body carries one, e.g. {"error":{"message":"..."}}.
+func httpError(resp *http.Response) error {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if json.Unmarshal(data, &body) == nil {
+		if msg := cmp.Or(body.Error.Message, body.Message); msg != "" {
+			return fmt.Errorf("HTTP error: %d: %s", resp.StatusCode, msg)
+		}
 	}
+	return fmt.Errorf("HTTP error: %d", resp.StatusCode)
+}
 
-	return result, nil
+// decodeJSON decodes a bounded JSON response body into out. Some firmware
+// labels JSON as text/plain, so the Content-Type only words the error when
+// the body isn't JSON.
+func decodeJSON(resp *http.Response, out interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return &transportError{err}
+	}
+	if len(body) > maxResponseSize {
+		return fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			if contentType := resp.Header.Get("Content-Type"); contentType != "" && !isJSONType(contentType) {
+				return fmt.Errorf("%w (got %s)", ErrNotJSON, contentType)
+			}
+			return fmt.Errorf("%w (%v)", ErrNotJSON, err)
+		}
+		return fmt.Errorf("unexpected response: %w", err)
+	}
+	return nil
+}
+
+// isJSONType reports whether contentType names a JSON media type.
+func isJSONType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.Contains(mediaType, "json")
 }
 
 // SetData performs a GET request to /api/setData.
 func (c *Client) SetData(path, roles, value string) error {
+	return c.SetDataContext(c.ctx, path, roles, value)
+}
+
+// SetDataContext is SetData bounded by ctx instead of the client's context.
+func (c *Client) SetDataContext(ctx context.Context, path, roles, value string) error {
+	_, err := c.sendData(ctx, path, roles, value)
+	return err
+}
+
+// sendData is SetDataContext returning the value that was sent, which is a
+// later call's when the write limiter merged them.
+func (c *Client) sendData(ctx context.Context, path, roles, value string) (string, error) {
 	if c.host == "" {
-		return fmt.Errorf("no host configured")
+		return "", fmt.Errorf("no host configured")
 	}
 
+	c.writesMu.Lock()
+	writes := c.writes
+	c.writesMu.Unlock()
+	if writes != nil {
+		return writes.do(ctx, path, roles, value, c.setData)
+	}
+	return value, c.setData(ctx, path, roles, value)
+}
+
+// SetWriteInterval spaces setData calls at least interval apart, so rapid
+// changes don't overwhelm the speaker. Writes to a path made while an
+// earlier one waits are merged into it; only the latest value is sent.
+// Zero or less removes the limit.
+func (c *Client) SetWriteInterval(interval time.Duration) {
+	c.writesMu.Lock()
+	defer c.writesMu.Unlock()
+
+	if interval <= 0 {
+		c.writes = nil
+		return
+	}
+	c.writes = &writeLimiter{interval: interval}
+}
+
+// setData sends a request to /api/setData.
+func (c *Client) setData(ctx context.Context, path, roles, value string) error {
 	params := url.Values{}
 	params.Set("path", path)
 	params.Set("roles", roles)
 	params.Set("value", value)
 
-	apiURL := fmt.Sprintf("http://%s:%d/api/setData?%s", c.host, c.port, params.Encode())
-	req, err := http.NewRequestWithContext(c.ctx, "GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.apiURL("setData", params), nil)
 	if err != nil {
 		return err
 	}
@@ -100,34 +347,107 @@ func (c *Client) SetData(path, roles, value string) error {
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		return httpError(resp)
 	}
 
 	return nil
 }
 
+// apiURL returns the URL of /api/<endpoint> on the speaker.
+func (c *Client) apiURL(endpoint string, params url.Values) string {
+	u := url.URL{Scheme: c.scheme, Host: c.hostPort(), Path: "/api/" + endpoint}
+	if params != nil {
+		u.RawQuery = params.Encode()
+	}
+	return u.String()
+}
+
+// hostPort returns the speaker's host and port for use in a URL.
+func (c *Client) hostPort() string {
+	return net.JoinHostPort(c.host, strconv.Itoa(c.port))
+}
+
+// Download fetches an arbitrary resource (e.g., album art) using the
+// client's timeout. Relative URLs are resolved against the speaker.
+func (c *Client) Download(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if !u.IsAbs() {
+		if c.host == "" {
+			return nil, fmt.Errorf("no host configured")
+		}
+		base := &url.URL{Scheme: c.scheme, Host: c.hostPort()}
+		u = base.ResolveReference(u)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme: %s", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("resource exceeds %d bytes", maxDownloadSize)
+	}
+
+	return data, nil
+}
+
 // GetInt retrieves an integer value from the API.
 func (c *Client) GetInt(path string) (int, error) {
-	result, err := c.GetData(path, "value")
+	return c.GetIntContext(c.ctx, path)
+}
+
+// GetIntContext is GetInt bounded by ctx instead of the client's context.
+func (c *Client) GetIntContext(ctx context.Context, path string) (int, error) {
+	result, err := c.GetDataContext(ctx, path, "value")
 	if err != nil {
 		return 0, err
 	}
 
-	if len(result) == 0 {
-		return 0, fmt.Errorf("empty response")
+	data, err := FirstValue(result)
+	if err != nil {
+		return 0, err
 	}
 
-	data, ok := result[0].(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("invalid response format")
-	}
+	return IntValue(data)
+}
 
-	v, ok := data["i32_"].(float64)
-	if !ok {
-		return 0, fmt.Errorf("invalid integer format")
+// IntValue extracts an integer from a value object. Most values are i32_,
+// but some firmware reports i64_ or a number in a string_.
+func IntValue(data map[string]interface{}) (int, error) {
+	for _, key := range []string{"i32_", "i64_"} {
+		if v, ok := data[key].(float64); ok {
+			return int(v), nil
+		}
+	}
+	if s, ok := data["string_"].(string); ok {
+		if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return v, nil
+		}
 	}
 
-	return int(v), nil
+	return 0, fmt.Errorf("invalid integer format: no i32_, i64_ or numeric string_ in %v", data)
 }
 
 // GetString retrieves a string value from the API.
@@ -137,13 +457,9 @@ func (c *Client) GetString(path string) (string, error) {
 		return "", err
 	}
 
-	if len(result) == 0 {
-		return "", fmt.Errorf("empty response")
-	}
-
-	data, ok := result[0].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("invalid response format")
+	data, err := FirstValue(result)
+	if err != nil {
+		return "", err
 	}
 
 	v, ok := data["string_"].(string)
@@ -154,8 +470,187 @@ func (c *Client) GetString(path string) (string, error) {
 	return v, nil
 }
 
+// SetString sets a string value via the API.
+func (c *Client) SetString(path, value string) error {
+	return c.SetTypedString(path, "string_", value)
+}
+
 // SetInt sets an integer value via the API.
 func (c *Client) SetInt(path string, value int) error {
+	return c.SetIntContext(c.ctx, path, value)
+}
+
+// SetIntContext is SetInt bounded by ctx instead of the client's context.
+func (c *Client) SetIntContext(ctx context.Context, path string, value int) error {
+	_, err := c.SetIntSentContext(ctx, path, value)
+	return err
+}
+
+// SetIntSentContext is SetIntContext returning the value that was sent.
+// With a write interval set, a later write to path may have merged into
+// this one and been sent in its place.
+func (c *Client) SetIntSentContext(ctx context.Context, path string, value int) (int, error) {
 	jsonValue := fmt.Sprintf(`{"type":"i32_","i32_":%d}`, value)
-	return c.SetData(path, "value", jsonValue)
+	sent, err := c.sendData(ctx, path, "value", jsonValue)
+	if err != nil {
+		return 0, err
+	}
+	if sent == jsonValue {
+		return value, nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(sent), &data); err != nil {
+		return 0, err
+	}
+	return IntValue(data)
+}
+
+// GetBool retrieves a boolean value from the API.
+func (c *Client) GetBool(path string) (bool, error) {
+	return c.GetBoolContext(c.ctx, path)
+}
+
+// GetBoolContext is GetBool bounded by ctx instead of the client's context.
+func (c *Client) GetBoolContext(ctx context.Context, path string) (bool, error) {
+	result, err := c.GetDataContext(ctx, path, "value")
+	if err != nil {
+		return false, err
+	}
+
+	data, err := FirstValue(result)
+	if err != nil {
+		return false, err
+	}
+
+	return BoolValue(data)
+}
+
+// BoolValue extracts a boolean from a value object.
+func BoolValue(data map[string]interface{}) (bool, error) {
+	v, ok := data["bool_"].(bool)
+	if !ok {
+		return false, fmt.Errorf("invalid boolean format")
+	}
+
+	return v, nil
+}
+
+// SetBool sets a boolean value via the API.
+func (c *Client) SetBool(path string, value bool) error {
+	return c.SetBoolContext(c.ctx, path, value)
+}
+
+// SetBoolContext is SetBool bounded by ctx instead of the client's context.
+func (c *Client) SetBoolContext(ctx context.Context, path string, value bool) error {
+	jsonValue := fmt.Sprintf(`{"type":"bool_","bool_":%t}`, value)
+	return c.SetDataContext(ctx, path, "value", jsonValue)
+}
+
+// GetTypedString retrieves a string value stored under a KEF-specific type
+// key (e.g., "kefPhysicalSource") rather than "string_".
+func (c *Client) GetTypedString(path, valueType string) (string, error) {
+	return c.GetTypedStringContext(c.ctx, path, valueType)
+}
+
+// GetTypedStringContext is GetTypedString bounded by ctx instead of the
+// client's context.
+func (c *Client) GetTypedStringContext(ctx context.Context, path, valueType string) (string, error) {
+	result, err := c.GetDataContext(ctx, path, "value")
+	if err != nil {
+		return "", err
+	}
+
+	data, err := FirstValue(result)
+	if err != nil {
+		return "", err
+	}
+
+	return TypedStringValue(data, valueType)
+}
+
+// TypedStringValue extracts a string stored under a KEF-specific type key
+// from a value object.
+func TypedStringValue(data map[string]interface{}, valueType string) (string, error) {
+	v, ok := data[valueType].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid %s format", valueType)
+	}
+
+	return v, nil
+}
+
+// SetTypedString sets a string value stored under a KEF-specific type key.
+func (c *Client) SetTypedString(path, valueType, value string) error {
+	return c.SetTypedStringContext(c.ctx, path, valueType, value)
+}
+
+// SetTypedStringContext is SetTypedString bounded by ctx instead of the
+// client's context.
+func (c *Client) SetTypedStringContext(ctx context.Context, path, valueType, value string) error {
+	payload, err := json.Marshal(map[string]string{
+		"type":    valueType,
+		valueType: value,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SetDataContext(ctx, path, "value", string(payload))
+}
+
+// GetObject retrieves an object-typed value (e.g., an EQ profile), returning
+// its fields with the type wrapper removed.
+func (c *Client) GetObject(path, valueType string) (map[string]interface{}, error) {
+	result, err := c.GetData(path, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := FirstValue(result)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := data[valueType].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid %s format", valueType)
+	}
+
+	return obj, nil
+}
+
+// SetObject sets an object-typed value. The speaker replaces the whole
+// object, so value must include every field, not just the changed ones.
+func (c *Client) SetObject(path, valueType string, value map[string]interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":    valueType,
+		valueType: value,
+	})
+	if err != nil {
+		return err
+	}
+	return c.SetData(path, "value", string(payload))
+}
+
+// FirstValue returns the first element of a getData result as an object.
+// It returns ErrValueUnavailable if the element is null or an empty object.
+func FirstValue(result []interface{}) (map[string]interface{}, error) {
+	if len(result) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	if result[0] == nil {
+		return nil, ErrValueUnavailable
+	}
+
+	data, ok := result[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid response format")
+	}
+
+	if len(data) == 0 {
+		return nil, ErrValueUnavailable
+	}
+
+	return data, nil
 }
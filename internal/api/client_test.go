@@ -0,0 +1,383 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// playbackPayload is a representative player:player/data response.
+const playbackPayload = `[{
+	"state": "playing",
+	"status": {"duration": 254000, "queueIndex": 3, "queueLength": 12},
+	"trackRoles": {
+		"title": "Windowlicker",
+		"icon": "http://i.scdn.co/image/ab67616d0000b273",
+		"mediaData": {
+			"metaData": {"artist": "Aphex Twin", "album": "Windowlicker EP", "serviceID": "spotify"},
+			"resources": [{"mimeType": "audio/mpeg", "uri": "spotify:track:1"}]
+		}
+	},
+	"controls": {"pause": true, "next_": true, "previous": true}
+}]`
+
+// stubSpeaker answers getData, setData and event queue requests like a KEF
+// speaker would. The queue never reports changes, as when nothing is
+// playing.
+func stubSpeaker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/api/event/modifyQueue":
+		_, _ = w.Write([]byte(`"{stub-queue}"`))
+		return
+	case "/api/event/pollQueue":
+		_, _ = w.Write([]byte(`[]`))
+		return
+	case "/api/setData":
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+
+	switch r.URL.Query().Get("path") {
+	case "player:volume":
+		_, _ = w.Write([]byte(`[{"type":"i32_","i32_":42}]`))
+	case "settings:/mediaPlayer/mute":
+		_, _ = w.Write([]byte(`[{"type":"bool_","bool_":false}]`))
+	case "settings:/releasetext":
+		_, _ = w.Write([]byte(`[{"type":"string_","string_":"LSXII_4.0.1"}]`))
+	case "player:player/data":
+		_, _ = w.Write([]byte(playbackPayload))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newStubClient returns a client talking to a test server that runs
+// handler, closed when the test ends.
+func newStubClient(tb testing.TB, handler http.HandlerFunc) *Client {
+	tb.Helper()
+
+	server := httptest.NewServer(handler)
+	tb.Cleanup(server.Close)
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewClient(host, port, time.Second, WithTransport(server.Client().Transport))
+}
+
+// respond returns a handler that answers every request with status and
+// body, labelled with contentType.
+func respond(status int, contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestGetInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+		wantErr error  // Matched with errors.Is
+		errText string // Matched exactly
+	}{
+		{name: "success", handler: stubSpeaker, want: 42},
+		{
+			name:    "HTTP error",
+			handler: respond(http.StatusInternalServerError, "application/json", `{"error":{"message":"busy"}}`),
+			errText: "HTTP error: 500: busy",
+		},
+		{
+			name:    "not JSON",
+			handler: respond(http.StatusOK, "text/html", `<html>router login</html>`),
+			wantErr: ErrNotJSON,
+		},
+		{
+			name:    "null value",
+			handler: respond(http.StatusOK, "application/json", `[null]`),
+			wantErr: ErrValueUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStubClient(t, tt.handler)
+			got, err := client.GetInt("player:volume")
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetInt() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.errText != "":
+				if err == nil || err.Error() != tt.errText {
+					t.Fatalf("GetInt() error = %v, want %q", err, tt.errText)
+				}
+			case err != nil:
+				t.Fatalf("GetInt() error = %v", err)
+			case got != tt.want:
+				t.Errorf("GetInt() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetString(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+		wantErr error
+		anyErr  bool
+	}{
+		{name: "success", handler: stubSpeaker, want: "LSXII_4.0.1"},
+		{name: "HTTP error", handler: respond(http.StatusNotFound, "text/plain", "not found"), anyErr: true},
+		{name: "not JSON", handler: respond(http.StatusOK, "text/html", `<html></html>`), wantErr: ErrNotJSON},
+		{name: "null value", handler: respond(http.StatusOK, "application/json", `[null]`), wantErr: ErrValueUnavailable},
+		{name: "wrong type", handler: respond(http.StatusOK, "application/json", `[{"type":"i32_","i32_":1}]`), anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStubClient(t, tt.handler)
+			got, err := client.GetString("settings:/releasetext")
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetString() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil {
+					t.Fatalf("GetString() = %q, want an error", got)
+				}
+			case err != nil:
+				t.Fatalf("GetString() error = %v", err)
+			case got != tt.want:
+				t.Errorf("GetString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetInt(t *testing.T) {
+	var gotPath, gotRoles, gotValue string
+	client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/setData" {
+			t.Errorf("request to %s, want /api/setData", r.URL.Path)
+		}
+		q := r.URL.Query()
+		gotPath, gotRoles, gotValue = q.Get("path"), q.Get("roles"), q.Get("value")
+		stubSpeaker(w, r)
+	})
+
+	if err := client.SetInt("player:volume", 35); err != nil {
+		t.Fatalf("SetInt() error = %v", err)
+	}
+	if gotPath != "player:volume" || gotRoles != "value" {
+		t.Errorf("SetInt() sent path %q roles %q", gotPath, gotRoles)
+	}
+	if want := `{"type":"i32_","i32_":35}`; gotValue != want {
+		t.Errorf("SetInt() sent value %s, want %s", gotValue, want)
+	}
+}
+
+func TestSetIntHTTPError(t *testing.T) {
+	client := newStubClient(t, respond(http.StatusForbidden, "application/json", `{"message":"read only"}`))
+
+	err := client.SetInt("player:volume", 35)
+	if err == nil || !strings.Contains(err.Error(), "403: read only") {
+		t.Fatalf("SetInt() error = %v, want the speaker's message", err)
+	}
+}
+
+func TestNoHost(t *testing.T) {
+	client := NewClient("", 80, time.Second)
+
+	if _, err := client.GetInt("player:volume"); err == nil {
+		t.Error("GetInt() without a host succeeded")
+	}
+	if err := client.SetInt("player:volume", 1); err == nil {
+		t.Error("SetInt() without a host succeeded")
+	}
+}
+
+func BenchmarkGetInt(b *testing.B) {
+	client := newStubClient(b, stubSpeaker)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := client.GetInt("player:volume"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetString(b *testing.B) {
+	client := newStubClient(b, stubSpeaker)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := client.GetString("settings:/releasetext"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetMany compares a batched poll through the event queue with
+// the sequential reads used for firmware without one.
+func BenchmarkGetMany(b *testing.B) {
+	paths := []string{"player:volume", "settings:/releasetext", "player:player/data", "settings:/mediaPlayer/mute"}
+	noQueue := func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/event/") {
+			http.NotFound(w, r)
+			return
+		}
+		stubSpeaker(w, r)
+	}
+
+	for _, bm := range []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"queued", stubSpeaker},
+		{"sequential", noQueue},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			client := newStubClient(b, bm.handler)
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := client.GetMany(paths); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeJSONContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     string // Empty if decoding should succeed
+	}{
+		{name: "JSON", contentType: "application/json", body: `[{"type":"i32_","i32_":7}]`},
+		{name: "JSON labelled text", contentType: "text/plain; charset=utf-8", body: `[{"type":"i32_","i32_":7}]`},
+		{name: "unlabelled JSON", body: `[{"type":"i32_","i32_":7}]`},
+		{name: "HTML", contentType: "text/html", body: `<html></html>`, wantErr: "(got text/html)"},
+		{name: "broken JSON", contentType: "application/json", body: `[{"type":`, wantErr: ErrNotJSON.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+				// Set explicitly, or net/http would sniff one
+				w.Header()["Content-Type"] = []string{tt.contentType}
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			got, err := client.GetInt("player:volume")
+			if tt.wantErr == "" {
+				if err != nil || got != 7 {
+					t.Errorf("GetInt() = %d, %v; want 7", got, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNotJSON) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetInt() error = %v, want ErrNotJSON mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIntValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: `{"type":"i32_","i32_":42}`, want: 42},
+		{value: `{"type":"i32_","i32_":-3}`, want: -3},
+		{value: `{"type":"i64_","i64_":1700000000}`, want: 1700000000},
+		{value: `{"type":"string_","string_":"35"}`, want: 35},
+		{value: `{"type":"string_","string_":" 7 "}`, want: 7},
+		{value: `{"type":"string_","string_":"loud"}`, wantErr: true},
+		{value: `{"type":"bool_","bool_":true}`, wantErr: true},
+		{value: `{"type":"i32_","i32_":"42"}`, wantErr: true},
+		{value: `{}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.value), &data); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := IntValue(data)
+		switch {
+		case tt.wantErr:
+			if err == nil {
+				t.Errorf("IntValue(%s) = %d, want an error", tt.value, got)
+			}
+		case err != nil:
+			t.Errorf("IntValue(%s) error = %v", tt.value, err)
+		case got != tt.want:
+			t.Errorf("IntValue(%s) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFirstValue(t *testing.T) {
+	tests := []struct {
+		result  string
+		wantErr error // Matched with errors.Is
+		anyErr  bool
+	}{
+		{result: `[{"type":"i32_","i32_":42}]`},
+		{result: `[{"type":"bool_","bool_":false}, {"type":"i32_","i32_":1}]`},
+		{result: `[null]`, wantErr: ErrValueUnavailable},
+		{result: `[{}]`, wantErr: ErrValueUnavailable},
+		{result: `[]`, anyErr: true},
+		{result: `["wifi"]`, anyErr: true},
+	}
+
+	for _, tt := range tests {
+		var result []interface{}
+		if err := json.Unmarshal([]byte(tt.result), &result); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := FirstValue(result)
+		switch {
+		case tt.wantErr != nil:
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("FirstValue(%s) error = %v, want %v", tt.result, err, tt.wantErr)
+			}
+		case tt.anyErr:
+			if err == nil || errors.Is(err, ErrValueUnavailable) {
+				t.Errorf("FirstValue(%s) error = %v, want a format error", tt.result, err)
+			}
+		case err != nil:
+			t.Errorf("FirstValue(%s) error = %v", tt.result, err)
+		case got["type"] != result[0].(map[string]interface{})["type"]:
+			t.Errorf("FirstValue(%s) = %v, want the first element", tt.result, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// eventPollTimeout is how long the speaker holds a poll open waiting for a
+// change before answering with nothing.
+const eventPollTimeout = 10 * time.Second
+
+// ErrEventsUnsupported is returned by WatchEvents when the speaker has no
+// event queue, so callers should keep polling instead.
+var ErrEventsUnsupported = errors.New("event subscriptions not supported")
+
+// Event is a change pushed by the speaker. Value is the object FirstValue
+// would return, or nil if the value became unavailable.
+type Event struct {
+	Path  string
+	Value map[string]interface{}
+}
+
+// WatchEvents subscribes to paths and calls fn with each change the speaker
+// pushes, until ctx is done or the subscription fails. The speaker answers
+// a long poll as soon as something changes, so changes arrive without
+// waiting for the next timed poll.
+func (c *Client) WatchEvents(ctx context.Context, paths []string, fn func(Event)) error {
+	id, err := c.createQueue(ctx, paths)
+	if err != nil {
+		if isTransportError(err) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrEventsUnsupported, err)
+	}
+
+	for {
+		events, err := c.longPoll(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("event poll failed: %w", err)
+		}
+
+		for _, ev := range events {
+			event := Event{Path: ev.Path}
+			if ev.ItemType != "delete" {
+				event.Value, _ = FirstValue([]interface{}{ev.ItemValue})
+			}
+			fn(event)
+		}
+	}
+}
+
+// longPoll waits for the changes queued on id, up to eventPollTimeout.
+func (c *Client) longPoll(ctx context.Context, id string) ([]queueEvent, error) {
+	params := url.Values{}
+	params.Set("queueId", id)
+	params.Set("timeout", strconv.Itoa(int(eventPollTimeout/time.Second)))
+
+	// Allow the speaker the full wait plus the usual request timeout
+	ctx, cancel := context.WithTimeout(ctx, eventPollTimeout+c.httpClient.Timeout)
+	defer cancel()
+
+	var events []queueEvent
+	if err := c.getWith(ctx, c.longPollClient, "event/pollQueue", params, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
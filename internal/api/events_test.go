@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// queueSpeaker is a stub speaker with an event queue. The first long poll
+// answers with events; later ones hold the request until the client gives
+// up, as a speaker with nothing to report would.
+func queueSpeaker(events string) http.HandlerFunc {
+	var polled atomic.Bool
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/event/modifyQueue":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`"{queue-1}"`))
+		case "/api/event/pollQueue":
+			if r.URL.Query().Get("queueId") != "{queue-1}" {
+				http.Error(w, "no such queue", http.StatusNotFound)
+				return
+			}
+			if polled.Swap(true) {
+				<-r.Context().Done()
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(events))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	client := newStubClient(t, queueSpeaker(`[
+		{"path": "player:volume", "itemType": "itemWithValue", "itemValue": {"type": "i32_", "i32_": 30}},
+		{"path": "settings:/mediaPlayer/mute", "itemType": "delete"}
+	]`))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []Event
+	err := client.WatchEvents(ctx, []string{"player:volume", "settings:/mediaPlayer/mute"}, func(ev Event) {
+		got = append(got, ev)
+		if len(got) == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchEvents() error = %v, want context.Canceled", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("WatchEvents() delivered %d events, want 2", len(got))
+	}
+	if got[0].Path != "player:volume" {
+		t.Errorf("event 0 path = %q, want player:volume", got[0].Path)
+	}
+	if v, err := IntValue(got[0].Value); err != nil || v != 30 {
+		t.Errorf("event 0 value = %v, %v, want 30", v, err)
+	}
+	if got[1].Path != "settings:/mediaPlayer/mute" || got[1].Value != nil {
+		t.Errorf("event 1 = %+v, want a deleted mute value", got[1])
+	}
+}
+
+func TestWatchEventsUnsupported(t *testing.T) {
+	client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown endpoint", http.StatusNotFound)
+	})
+
+	err := client.WatchEvents(context.Background(), []string{"player:volume"}, func(Event) {
+		t.Error("WatchEvents() delivered an event without a queue")
+	})
+	if !errors.Is(err, ErrEventsUnsupported) {
+		t.Errorf("WatchEvents() error = %v, want ErrEventsUnsupported", err)
+	}
+}
+
+func TestWatchEventsHonoursContextWhileSubscribing(t *testing.T) {
+	client := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Read the body, so the server notices when the client hangs up
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.WatchEvents(ctx, []string{"player:volume"}, func(Event) {})
+	if err == nil || errors.Is(err, ErrEventsUnsupported) {
+		t.Errorf("WatchEvents() error = %v, want a transport error", err)
+	}
+	// The client timeout is a second; ctx must cut the subscription short
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("WatchEvents() returned after %v, want it bounded by ctx", elapsed)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// writeLimiter spaces setData calls at least interval apart. A write to a
+// path that already has one waiting replaces the waiting value instead of
+// taking a turn of its own, so a burst of volume changes sends only the
+// latest level, and always sends it.
+type writeLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time                  // when the next write may be sent
+	pending  map[writeKey]*pendingWrite // waiting writes by path and roles
+}
+
+// writeKey identifies the writes that may be merged into one.
+type writeKey struct {
+	path, roles string
+}
+
+// pendingWrite is a write waiting for its turn. ctx and value are those of
+// the latest caller; err is set before done is closed.
+type pendingWrite struct {
+	ctx   context.Context
+	value string
+	done  chan struct{}
+	err   error
+}
+
+// writeFunc sends one setData call.
+type writeFunc func(ctx context.Context, path, roles, value string) error
+
+// do sends the write through send once its turn comes, or merges it into
+// the write already waiting for path and roles. It returns the value that
+// was sent, which is a later caller's if one merged in, and the result of
+// the write, or ctx's error if ctx ends first; the value may still be sent
+// then.
+func (l *writeLimiter) do(ctx context.Context, path, roles, value string, send writeFunc) (string, error) {
+	key := writeKey{path: path, roles: roles}
+
+	l.mu.Lock()
+	if p, ok := l.pending[key]; ok {
+		p.ctx, p.value = ctx, value
+		l.mu.Unlock()
+		return wait(ctx, p)
+	}
+
+	p := &pendingWrite{ctx: ctx, value: value, done: make(chan struct{})}
+	if l.pending == nil {
+		l.pending = make(map[writeKey]*pendingWrite)
+	}
+	l.pending[key] = p
+
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	// Wait out the turn even if ctx ends: later writes may have merged in
+	time.Sleep(at.Sub(now))
+
+	l.mu.Lock()
+	delete(l.pending, key)
+	ctx, value = p.ctx, p.value
+	l.mu.Unlock()
+
+	p.err = send(ctx, path, roles, value)
+	close(p.done)
+	return value, p.err
+}
+
+// wait waits for a merged write to be sent. p.value is final once done is
+// closed.
+func wait(ctx context.Context, p *pendingWrite) (string, error) {
+	select {
+	case <-p.done:
+		return p.value, p.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
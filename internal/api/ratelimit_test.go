@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sentWrite is a write that reached the speaker.
+type sentWrite struct {
+	path, roles, value string
+	at                 time.Time
+	ctxErr             error // of the context it was sent with
+}
+
+// writeRecorder is a writeFunc that records what it sends.
+type writeRecorder struct {
+	mu   sync.Mutex
+	sent []sentWrite
+}
+
+func (r *writeRecorder) send(ctx context.Context, path, roles, value string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, sentWrite{path: path, roles: roles, value: value, at: time.Now(), ctxErr: ctx.Err()})
+	return nil
+}
+
+func (r *writeRecorder) writes() []sentWrite {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]sentWrite(nil), r.sent...)
+}
+
+// waitForPending waits until the write waiting for path and roles carries
+// value.
+func waitForPending(t *testing.T, l *writeLimiter, path, roles, value string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		p, ok := l.pending[writeKey{path: path, roles: roles}]
+		got := ok && p.value == value
+		l.mu.Unlock()
+		if got {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no write of %s waiting for %s", value, path)
+}
+
+// result is what one do call returned.
+type result struct {
+	sent string
+	err  error
+}
+
+// goDo runs l.do in the background and delivers its result on the
+// returned channel.
+func goDo(ctx context.Context, l *writeLimiter, rec *writeRecorder, path, roles, value string) <-chan result {
+	ch := make(chan result, 1)
+	go func() {
+		sent, err := l.do(ctx, path, roles, value, rec.send)
+		ch <- result{sent, err}
+	}()
+	return ch
+}
+
+func TestWriteLimiterMergesWaitingWrites(t *testing.T) {
+	l := &writeLimiter{interval: 200 * time.Millisecond}
+	rec := &writeRecorder{}
+	ctx := context.Background()
+
+	if sent, err := l.do(ctx, "player:volume", "value", "10", rec.send); err != nil || sent != "10" {
+		t.Fatalf("first do() = %q, %v, want 10, nil", sent, err)
+	}
+
+	// The next write waits out the interval; the ones after merge into it
+	results := []<-chan result{goDo(ctx, l, rec, "player:volume", "value", "11")}
+	waitForPending(t, l, "player:volume", "value", "11")
+	results = append(results, goDo(ctx, l, rec, "player:volume", "value", "12"))
+	waitForPending(t, l, "player:volume", "value", "12")
+	results = append(results, goDo(ctx, l, rec, "player:volume", "value", "13"))
+	waitForPending(t, l, "player:volume", "value", "13")
+
+	for i, ch := range results {
+		if r := <-ch; r.err != nil || r.sent != "13" {
+			t.Errorf("do() #%d = %q, %v, want 13, nil", i+2, r.sent, r.err)
+		}
+	}
+
+	var values []string
+	for _, w := range rec.writes() {
+		values = append(values, w.value)
+	}
+	if len(values) != 2 || values[0] != "10" || values[1] != "13" {
+		t.Errorf("sent %v, want [10 13]", values)
+	}
+}
+
+func TestWriteLimiterSendsTrailingWrite(t *testing.T) {
+	l := &writeLimiter{interval: 200 * time.Millisecond}
+	rec := &writeRecorder{}
+
+	if _, err := l.do(context.Background(), "player:volume", "value", "10", rec.send); err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller that took the turn gives up; the latest level, merged in
+	// by a caller still waiting, must reach the speaker regardless
+	ctx, cancel := context.WithCancel(context.Background())
+	first := goDo(ctx, l, rec, "player:volume", "value", "20")
+	waitForPending(t, l, "player:volume", "value", "20")
+	last := goDo(context.Background(), l, rec, "player:volume", "value", "21")
+	waitForPending(t, l, "player:volume", "value", "21")
+	cancel()
+
+	for _, ch := range []<-chan result{first, last} {
+		if r := <-ch; r.err != nil || r.sent != "21" {
+			t.Errorf("do() = %q, %v, want 21, nil", r.sent, r.err)
+		}
+	}
+
+	writes := rec.writes()
+	if len(writes) != 2 {
+		t.Fatalf("sent %d writes, want 2", len(writes))
+	}
+	if w := writes[1]; w.value != "21" || w.ctxErr != nil {
+		t.Errorf("trailing write sent %q with context error %v, want 21 with a live context", w.value, w.ctxErr)
+	}
+}
+
+func TestWriteLimiterSpacesWrites(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	l := &writeLimiter{interval: interval}
+	rec := &writeRecorder{}
+	ctx := context.Background()
+
+	// Different paths and roles never merge; each takes a turn
+	start := time.Now()
+	results := []<-chan result{
+		goDo(ctx, l, rec, "player:volume", "value", "30"),
+		goDo(ctx, l, rec, "settings:/mediaPlayer/mute", "value", "true"),
+		goDo(ctx, l, rec, "player:volume", "activate", "{}"),
+	}
+	for _, ch := range results {
+		if r := <-ch; r.err != nil {
+			t.Fatal(r.err)
+		}
+	}
+
+	writes := rec.writes()
+	if len(writes) != len(results) {
+		t.Fatalf("sent %d writes, want %d", len(writes), len(results))
+	}
+	for i, w := range writes {
+		if earliest := start.Add(time.Duration(i) * interval); w.at.Before(earliest) {
+			t.Errorf("write %d sent %v after start, want at least %v", i, w.at.Sub(start), earliest.Sub(start))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportOptions tunes an HTTP connection pool.
+type TransportOptions struct {
+	// MaxIdleConns caps idle connections kept across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost caps idle connections kept per speaker. A long
+	// poll holds one, so regular requests need a few more.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes connections left idle this long.
+	IdleConnTimeout time.Duration
+	// DialTimeout bounds connecting, so a scan doesn't wait on hosts that
+	// never answer.
+	DialTimeout time.Duration
+	// KeepAlive is the TCP keep-alive period.
+	KeepAlive time.Duration
+}
+
+// DefaultTransportOptions suit one app talking to a handful of speakers,
+// plus occasional network scans.
+var DefaultTransportOptions = TransportOptions{
+	MaxIdleConns:        64,
+	MaxIdleConnsPerHost: 4,
+	IdleConnTimeout:     90 * time.Second,
+	DialTimeout:         2 * time.Second,
+	KeepAlive:           30 * time.Second,
+}
+
+// DefaultTransport is the connection pool shared by clients and discovery
+// scans that aren't given their own, so requests reuse connections.
+var DefaultTransport = NewTransport(DefaultTransportOptions)
+
+// NewTransport creates a connection pool with the given options.
+func NewTransport(opts TransportOptions) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: opts.KeepAlive,
+	}
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+}
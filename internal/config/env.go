@@ -0,0 +1,111 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// setting, e.g. KEFBAR_SPEAKER_IP for speaker_ip.
+const EnvPrefix = "KEFBAR_"
+
+// envOverride is a setting's value from the config file and the value the
+// environment replaced it with.
+type envOverride struct {
+	file, env any
+}
+
+// envSettings returns pointers to the settings the environment may
+// override, keyed by variable name. Each is a *string, *int or *bool.
+func (c *Config) envSettings() map[string]any {
+	return map[string]any{
+		EnvPrefix + "SPEAKER_IP":       &c.SpeakerIP,
+		EnvPrefix + "PORT":             &c.Port,
+		EnvPrefix + "SCHEME":           &c.Scheme,
+		EnvPrefix + "VOLUME_STEP":      &c.VolumeStep,
+		EnvPrefix + "MAX_VOLUME":       &c.MaxVolume,
+		EnvPrefix + "POLL_INTERVAL_MS": &c.PollIntervalMs,
+		EnvPrefix + "TIMEOUT_MS":       &c.TimeoutMs,
+		EnvPrefix + "AUTO_RECONNECT":   &c.AutoReconnect,
+		EnvPrefix + "SERVER_ENABLED":   &c.ServerEnabled,
+		EnvPrefix + "SERVER_ADDRESS":   &c.ServerAddress,
+		EnvPrefix + "MQTT_BROKER_URL":  &c.MQTTBrokerURL,
+		EnvPrefix + "MQTT_USERNAME":    &c.MQTTUsername,
+		EnvPrefix + "MQTT_PASSWORD":    &c.MQTTPassword,
+	}
+}
+
+// applyEnv overrides settings from the environment, remembering the values
+// replaced so Save can put them back. Values that don't parse are logged
+// and ignored.
+func (c *Config) applyEnv() {
+	for name, setting := range c.envSettings() {
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		raw = strings.TrimSpace(raw)
+
+		file := settingValue(setting)
+		var err error
+		switch p := setting.(type) {
+		case *string:
+			*p = raw
+		case *int:
+			*p, err = strconv.Atoi(raw)
+		case *bool:
+			*p, err = strconv.ParseBool(raw)
+		}
+		if err != nil {
+			setSettingValue(setting, file)
+			slog.Warn("Ignoring invalid environment override", "variable", name, "value", raw)
+			continue
+		}
+
+		if c.envOverrides == nil {
+			c.envOverrides = make(map[string]envOverride)
+		}
+		c.envOverrides[name] = envOverride{file: file, env: settingValue(setting)}
+	}
+}
+
+// withoutEnv returns a copy of c with the settings the environment
+// overrode back at their config file values, unless they have been changed
+// since, so overrides are never saved.
+func (c *Config) withoutEnv() *Config {
+	saved := *c
+	settings := saved.envSettings()
+	for name, o := range c.envOverrides {
+		if settingValue(settings[name]) == o.env {
+			setSettingValue(settings[name], o.file)
+		}
+	}
+	return &saved
+}
+
+// settingValue returns the value setting points to.
+func settingValue(setting any) any {
+	switch p := setting.(type) {
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *bool:
+		return *p
+	}
+	return nil
+}
+
+// setSettingValue sets the value setting points to.
+func setSettingValue(setting, value any) {
+	switch p := setting.(type) {
+	case *string:
+		*p = value.(string)
+	case *int:
+		*p = value.(int)
+	case *bool:
+		*p = value.(bool)
+	}
+}
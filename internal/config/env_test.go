@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestEnvPrecedence(t *testing.T) {
+	useConfigFile(t, `{"speaker_ip": "192.168.1.20", "volume_step": 3, "auto_reconnect": false}`)
+	t.Setenv(EnvPrefix+"VOLUME_STEP", "7")
+	t.Setenv(EnvPrefix+"AUTO_RECONNECT", "true")
+	t.Setenv(EnvPrefix+"SCHEME", " https ") // Surrounding space is trimmed
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.VolumeStep != 7 || !cfg.AutoReconnect || cfg.Scheme != "https" {
+		t.Errorf("environment over file: volume_step %d, auto_reconnect %t, scheme %q", cfg.VolumeStep, cfg.AutoReconnect, cfg.Scheme)
+	}
+	if cfg.SpeakerIP != "192.168.1.20" {
+		t.Errorf("file over default: speaker_ip %q, want the file's", cfg.SpeakerIP)
+	}
+	if cfg.Port != DefaultPort {
+		t.Errorf("default: port %d, want %d", cfg.Port, DefaultPort)
+	}
+}
+
+func TestEnvInvalidIgnored(t *testing.T) {
+	useConfigFile(t, `{"port": 8080, "server_enabled": true}`)
+	t.Setenv(EnvPrefix+"PORT", "eighty")
+	t.Setenv(EnvPrefix+"SERVER_ENABLED", "sometimes")
+	t.Setenv(EnvPrefix+"MAX_VOLUME", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 8080 || !cfg.ServerEnabled || cfg.MaxVolume != DefaultMaxVolume {
+		t.Errorf("invalid overrides changed settings: port %d, server_enabled %t, max_volume %d", cfg.Port, cfg.ServerEnabled, cfg.MaxVolume)
+	}
+	if len(cfg.envOverrides) != 0 {
+		t.Errorf("invalid overrides recorded: %v", cfg.envOverrides)
+	}
+}
+
+func TestEnvNotSaved(t *testing.T) {
+	path := useConfigFile(t, `{"speaker_ip": "192.168.1.20", "volume_step": 3, "mqtt_password": "file-secret"}`)
+	t.Setenv(EnvPrefix+"SPEAKER_IP", "10.0.0.5")
+	t.Setenv(EnvPrefix+"VOLUME_STEP", "7")
+	t.Setenv(EnvPrefix+"MQTT_PASSWORD", "env-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changed in the app after loading, so it is saved
+	cfg.VolumeStep = 4
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		SpeakerIP    string `json:"speaker_ip"`
+		VolumeStep   int    `json:"volume_step"`
+		MQTTPassword string `json:"mqtt_password"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.SpeakerIP != "192.168.1.20" || saved.MQTTPassword != "file-secret" {
+		t.Errorf("saved speaker_ip %q, mqtt_password %q; want the file values", saved.SpeakerIP, saved.MQTTPassword)
+	}
+	if saved.VolumeStep != 4 {
+		t.Errorf("saved volume_step %d, want the value set since loading", saved.VolumeStep)
+	}
+
+	// Saving leaves the running config overridden
+	if cfg.SpeakerIP != "10.0.0.5" {
+		t.Errorf("Save() changed speaker_ip to %q", cfg.SpeakerIP)
+	}
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// bluetoothPairPath makes the speaker discoverable for Bluetooth pairing,
+// as the pairing button on the remote does.
+const bluetoothPairPath = "bluetooth:externalDiscoverable"
+
+// SupportsBluetooth reports whether the speaker has a Bluetooth input.
+func (c *Controller) SupportsBluetooth() bool {
+	return c.Supports(kef.SourceFeature(kef.SourceBluetooth))
+}
+
+// EnterBluetoothPairing switches to the Bluetooth input and makes the
+// speaker discoverable, so a phone can pair with it. It returns
+// ErrUnsupported if the model has no Bluetooth.
+func (c *Controller) EnterBluetoothPairing() error {
+	if !c.SupportsBluetooth() {
+		return ErrUnsupported
+	}
+
+	if err := c.SetSource(kef.SourceBluetooth); err != nil {
+		return fmt.Errorf("switch to bluetooth: %w", err)
+	}
+	if err := c.client.SetData(bluetoothPairPath, "activate", `{"type":"bool_","bool_":true}`); err != nil {
+		return fmt.Errorf("start pairing: %w", err)
+	}
+
+	slog.Info("Bluetooth pairing started")
+	return nil
+}
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+func TestStartupVolumeOnFirstConnectOnly(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+	c.cfg.RestoreVolumeOnConnect = true
+	c.cfg.StartupVolume = 20
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if got := c.GetState().Volume; got != 20 {
+		t.Fatalf("volume after first connect = %d, want the startup volume", got)
+	}
+
+	// Turned up, then a network blip and a reconnect
+	if err := c.SetVolume(55); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("reconnect error = %v", err)
+	}
+	if got := c.GetState().Volume; got != 55 {
+		t.Errorf("volume after reconnect = %d, want it left at 55", got)
+	}
+
+	// Switching speakers starts over
+	c.SetIP(c.GetState().IPAddress)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() after SetIP error = %v", err)
+	}
+	if got := c.GetState().Volume; got != 20 {
+		t.Errorf("volume after SetIP and connect = %d, want the startup volume", got)
+	}
+}
+
+func TestConnectSerialized(t *testing.T) {
+	speaker := newFakeSpeaker()
+	var inFlight, most atomic.Int32
+	c := newTestController(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Connect starts by reading the volume
+		if r.URL.Query().Get("path") == volumePath {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			if n > most.Load() {
+				most.Store(n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		speaker.ServeHTTP(w, r)
+	}))
+	c.cfg.PollInterval = time.Hour
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Connect(); err != nil {
+				t.Errorf("Connect() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := most.Load(); n != 1 {
+		t.Errorf("%d connects ran at once, want 1", n)
+	}
+}
+
+func TestReconnectSkipsConnectInProgress(t *testing.T) {
+	c := newTestController(t, newFakeSpeaker())
+	c.cfg.PollInterval = time.Hour
+
+	c.connectMu.Lock()
+	attempted, _ := c.reconnect()
+	c.connectMu.Unlock()
+	if attempted {
+		t.Error("reconnect() tried while another connect held the lock")
+	}
+
+	if attempted, err := c.reconnect(); !attempted || err != nil {
+		t.Fatalf("reconnect() = %t, %v; want a successful attempt", attempted, err)
+	}
+	if attempted, _ := c.reconnect(); attempted {
+		t.Error("reconnect() tried again while connected")
+	}
+}
+
+func TestConnectDiscoveredModel(t *testing.T) {
+	speaker := newFakeSpeaker() // Reports LSXII_4.0.1
+	c := newTestController(t, speaker)
+	c.cfg.PollInterval = time.Hour
+	host := c.GetState().IPAddress
+
+	// Without a discovered model, it comes from the release text
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if state := c.GetState(); state.Model != "LSXII" || state.Firmware != "4.0.1" {
+		t.Errorf("model %q firmware %q, want LSXII 4.0.1 from the speaker", state.Model, state.Firmware)
+	}
+
+	// A discovered model wins; the firmware is still read
+	c.SetIP(host)
+	c.SetDiscoveredModel("LS60")
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if state := c.GetState(); state.Model != "LS60" || state.Firmware != "4.0.1" {
+		t.Errorf("model %q firmware %q, want the discovered LS60 and 4.0.1", state.Model, state.Firmware)
+	}
+
+	// Even when the release text can't be read
+	speaker.mu.Lock()
+	delete(speaker.values, releaseTextPath)
+	speaker.mu.Unlock()
+	c.SetIP(host)
+	c.SetDiscoveredModel("LS50W2")
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if got := c.GetState().Model; got != "LS50W2" {
+		t.Errorf("model %q, want the discovered LS50W2", got)
+	}
+
+	// Switching speakers forgets it
+	speaker.set(releaseTextPath, `{"type":"string_","string_":"LSXII_4.0.2"}`)
+	c.SetIP(host)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if got := c.GetState().Model; got != "LSXII" {
+		t.Errorf("model after SetIP %q, want LSXII from the speaker", got)
+	}
+}
+
+func TestConnectInStandby(t *testing.T) {
+	speaker := newFakeSpeaker()
+	// Some firmware doesn't answer for the volume in standby
+	delete(speaker.values, volumePath)
+	speaker.set(sourcePath, sourceValue(kef.SourceStandby))
+	c := newTestController(t, speaker)
+	c.cfg.PollInterval = time.Hour
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() in standby error = %v", err)
+	}
+	state := c.GetState()
+	if !state.Connected || state.IsPoweredOn || state.Error != "" {
+		t.Errorf("state after connecting in standby = connected %t, powered on %t, error %q; want connected and off",
+			state.Connected, state.IsPoweredOn, state.Error)
+	}
+}
+
+func TestConnectFailsWithoutVolume(t *testing.T) {
+	speaker := newFakeSpeaker()
+	delete(speaker.values, volumePath)
+	c := newTestController(t, speaker)
+	c.cfg.PollInterval = time.Hour
+
+	// Awake but not answering for the volume is still a failure
+	if err := c.Connect(); err == nil {
+		t.Fatal("Connect() without a volume succeeded")
+	}
+	if state := c.GetState(); state.Connected || state.Error == "" {
+		t.Errorf("state after failed connect = connected %t, error %q; want disconnected with an error", state.Connected, state.Error)
+	}
+}
+
+func TestStartupVolumeWaitsForMuteState(t *testing.T) {
+	speaker := newFakeSpeaker()
+	delete(speaker.values, mutePath)
+	c := newTestController(t, speaker)
+	c.cfg.PollInterval = time.Hour
+	c.cfg.RestoreVolumeOnConnect = true
+	c.cfg.StartupVolume = 20
+
+	// Without the mute state the restore can't be decided yet
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if got := c.GetState().Volume; got != 42 {
+		t.Fatalf("volume after connect without mute state = %d, want it left at 42", got)
+	}
+
+	speaker.set(mutePath, boolValue(false))
+	if err := c.Connect(); err != nil {
+		t.Fatalf("reconnect error = %v", err)
+	}
+	if got := c.GetState().Volume; got != 20 {
+		t.Errorf("volume after reconnect with mute state = %d, want the startup volume", got)
+	}
+}
@@ -3,57 +3,232 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
-	"github/com/inquire/kefbar-go/internal/api"
-	"github/com/inquire/kefbar-go/internal/config"
-	"github/com/inquire/kefbar-go/pkg/kef"
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/pkg/kef"
 )
 
+// Poll cadence bounds, see pollDelay.
+const (
+	idlePollInterval = 15 * time.Second
+	maxPollBackoff   = 2 * time.Minute
+)
+
+// volumeSettleWindow is how long after setting the volume polled and pushed
+// volume readings are ignored. A poll that started before the write, or a
+// change pushed for an earlier step of a burst, would otherwise move the
+// volume back.
+const volumeSettleWindow = 2 * time.Second
+
+// Settings paths read on every poll.
+const (
+	volumePath     = "player:volume"
+	mutePath       = "settings:/mediaPlayer/mute"
+	sourcePath     = "settings:/kef/play/physicalSource"
+	sourceType     = "kefPhysicalSource"
+	playerDataPath = "player:player/data"
+	deviceNamePath = "settings:/deviceName"
+)
+
+// pingTimeout bounds Ping, so checking a wrong address fails quickly.
+const pingTimeout = 3 * time.Second
+
+// maxVolumePath holds the volume limit set in the KEF app. Not every
+// firmware has it; the limit is then 100.
+const maxVolumePath = "settings:/kef/host/maximumVolume"
+
+// speakerRolePath holds whether the speaker is the primary or secondary of
+// a stereo pair. Unpaired speakers and older firmware may not have it.
+const speakerRolePath = "settings:/kef/host/speakerRole"
+
+// ErrSecondarySpeaker is returned by Connect for the secondary speaker of a
+// stereo pair, which only the primary can control.
+var ErrSecondarySpeaker = errors.New("this is the secondary speaker of a stereo pair; connect to the primary instead")
+
+// releaseTextPath holds the model and firmware version, e.g. "LSXII_4.0.1".
+const releaseTextPath = "settings:/releasetext"
+
 // Controller manages the KEF speaker state and operations.
 type Controller struct {
-	client *api.Client
-	state  *kef.SpeakerState
-	mu     sync.RWMutex
-	ctx    context.Context
-	cancel context.CancelFunc
-	cfg    *config.Config
+	client     *api.Client
+	clientOpts []api.ClientOption // for the clients SetVolumeAll creates
+	state      *kef.SpeakerState
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	cfg        *config.Config
+
+	// connectMu serializes Connect, which the initial connect, the menu
+	// and the reconnect loop may all call at once
+	connectMu sync.Mutex
+
+	pollOnce sync.Once
+	pollWake chan struct{} // signalled on connect to reset the poll cadence
+
+	eventsOnce sync.Once
+	streaming  atomic.Bool // an event stream is delivering changes
+
+	reconnectOnce sync.Once
+	autoReconnect atomic.Bool
+
+	scheduleOnce sync.Once
+
+	// fadeCancel stops the volume fade in progress, if any; fadeID tells
+	// fades apart
+	fadeMu     sync.Mutex
+	fadeCancel context.CancelFunc
+	fadeID     uint64
+
+	// sleep is the pending sleep timer, if any
+	sleepMu sync.Mutex
+	sleep   *sleepTimer
+
+	// lastStreamingSource is the most recent streaming source seen, used
+	// to return to it when AutoSwitchSource is enabled
+	lastStreamingSource string
+
+	// discoveredModel is the model reported by discovery for the current
+	// IP, used instead of fetching it on connect
+	discoveredModel string
+
+	// startupVolumeChecked is set once Connect has considered restoring
+	// the startup volume for the current IP, so reconnects leave the
+	// volume alone. SetIP clears it.
+	startupVolumeChecked bool
+
+	// onTrackChange is called when a new track starts playing, see
+	// SetTrackChangeCallback
+	onTrackChange func(kef.PlaybackInfo)
+
+	// onPlaybackStateChange is called when the playback state changes,
+	// see OnPlaybackStateChange
+	onPlaybackStateChange func(old, new string)
+
+	// playMode is the last shuffle and repeat mode read, applied to
+	// playback info as it is read
+	playMode playMode
+
+	// volumeSteps holds volume changes queued by QueueVolumeStep
+	volumeSteps     chan int
+	volumeQueueOnce sync.Once
+
+	// volumeSetAt is when the volume was last set, see volumeSettleWindow
+	volumeSetAt time.Time
+
+	// premuteVolume is the volume when the speaker was muted, restored
+	// when a volume step unmutes it
+	premuteVolume int
+
+	// features records which optional features the speaker supports,
+	// probed on connect
+	features map[string]bool
+
+	// eqMu serializes EQ read-modify-write cycles
+	eqMu sync.Mutex
+
+	// State subscribers, see Subscribe
+	subMu     sync.Mutex
+	subs      []chan kef.SpeakerState
+	published *kef.SpeakerState
+	closed    bool
+
+	// Album art cache, keyed by URL
+	artURL  string
+	artData []byte
 }
 
-// New creates a new Controller.
-func New(cfg *config.Config) *Controller {
+// New creates a new Controller. opts configure its API client, e.g. to
+// answer requests from a stub instead of a speaker.
+func New(cfg *config.Config, opts ...api.ClientOption) *Controller {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	client := api.NewClient(cfg.SpeakerIP, cfg.Port, cfg.Timeout)
+	client := api.NewClient(cfg.SpeakerIP, cfg.Port, cfg.Timeout, opts...)
 	client.SetContext(ctx)
+	client.SetWriteInterval(cfg.WriteInterval())
+	if cfg.Scheme != "" {
+		if err := client.SetScheme(cfg.Scheme); err != nil {
+			slog.Warn("Ignoring configured scheme", "error", err)
+		}
+	}
 
 	return &Controller{
-		client: client,
+		client:     client,
+		clientOpts: opts,
 		state: &kef.SpeakerState{
 			Port: cfg.Port,
 		},
-		ctx:    ctx,
-		cancel: cancel,
-		cfg:    cfg,
+		ctx:         ctx,
+		cancel:      cancel,
+		cfg:         cfg,
+		pollWake:    make(chan struct{}, 1),
+		volumeSteps: make(chan int, volumeQueueSize),
 	}
 }
 
 // SetIP sets the speaker IP address.
 func (c *Controller) SetIP(ip string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	c.state.IPAddress = ip
 	c.state.Error = ""
+	c.state.Name = ""
+	c.state.Firmware = ""
+	c.state.MaxVolume = 0
+	c.state.Role = ""
+	c.state.IsPoweredOn = false
+	c.discoveredModel = ""
+	c.startupVolumeChecked = false
 	c.client.SetHost(ip)
+	c.mu.Unlock()
+	c.publish()
 }
 
-// Connect establishes a connection to the speaker.
+// SetDiscoveredModel records the model discovery found for the current IP so
+// Connect can skip the firmware model lookup. Call it after SetIP.
+func (c *Controller) SetDiscoveredModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.discoveredModel = model
+}
+
+// Ping checks that a speaker answers at ip, using the configured scheme and
+// port, without connecting to it: the controller's state is left alone and
+// nothing is started. Use it to validate an address before switching to it.
+func (c *Controller) Ping(ctx context.Context, ip string) error {
+	client := api.NewClient(ip, c.cfg.Port, pingTimeout)
+	if c.cfg.Scheme != "" {
+		if err := client.SetScheme(c.cfg.Scheme); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return client.Ping(ctx)
+}
+
+// Connect establishes a connection to the speaker. Concurrent calls run
+// one at a time.
 func (c *Controller) Connect() error {
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+
+	return c.connect()
+}
+
+// connect is Connect for callers holding c.connectMu.
+func (c *Controller) connect() error {
 	c.mu.RLock()
 	ip := c.state.IPAddress
 	c.mu.RUnlock()
@@ -62,160 +237,647 @@ func (c *Controller) Connect() error {
 		return fmt.Errorf("no IP address set")
 	}
 
-	// Test connection by getting volume
+	// Test connection by getting volume. A speaker in standby may not
+	// answer for the volume but still reports its source.
 	_, err := c.GetVolume()
+	standby := false
+	if err != nil {
+		if source, sourceErr := c.GetSource(); sourceErr == nil && source == kef.SourceStandby {
+			slog.Info("Speaker is in standby", "volume_error", err)
+			standby, err = true, nil
+		}
+	}
 	if err != nil {
 		c.mu.Lock()
 		c.state.Connected = false
 		c.state.Error = err.Error()
 		c.mu.Unlock()
+		c.publish()
 		return err
 	}
 
-	// Get speaker model
-	model, err := c.GetSpeakerModel()
-	if err != nil {
+	if role, err := c.GetSpeakerRole(); err == nil && role == kef.RoleSecondary {
+		c.mu.Lock()
+		c.state.Connected = false
+		c.state.Error = ErrSecondarySpeaker.Error()
+		c.mu.Unlock()
+		c.publish()
+		return ErrSecondarySpeaker
+	}
+
+	if limit, err := c.GetSpeakerMaxVolume(); err != nil {
+		slog.Debug("Speaker has no volume limit, using 100", "error", err)
+	} else if limit < 100 {
+		slog.Info("Speaker volume limit", "max", limit)
+	}
+
+	// Restore the volume on the first connect only; a reconnect after a
+	// network blip shouldn't reset the volume mid-listening. A speaker in
+	// standby has no volume to restore yet. If the mute state can't be
+	// read, the next connect decides instead.
+	if !standby {
+		muted, err := c.GetMute()
+		if err != nil {
+			slog.Warn("Could not get mute state", "error", err)
+		} else {
+			c.mu.Lock()
+			firstConnect := !c.startupVolumeChecked
+			c.startupVolumeChecked = true
+			c.mu.Unlock()
+
+			if c.cfg.RestoreVolumeOnConnect && firstConnect && !muted {
+				c.restoreStartupVolume()
+			}
+		}
+	}
+
+	// Use the model from discovery if we have one, otherwise ask the speaker
+	c.mu.Lock()
+	model := c.discoveredModel
+	if model != "" {
+		c.state.Model = model
+	}
+	c.mu.Unlock()
+	c.publish()
+
+	if model != "" {
+		slog.Info("Speaker model from discovery", "model", model)
+		if firmware, err := c.GetFirmwareVersion(); err != nil {
+			slog.Warn("Could not get firmware version", "error", err)
+		} else {
+			slog.Info("Speaker firmware", "version", firmware)
+		}
+	} else if model, err := c.GetSpeakerModel(); err != nil {
 		slog.Warn("Could not get speaker model", "error", err)
 	} else {
-		slog.Info("Speaker model detected", "model", model)
+		slog.Info("Speaker model detected", "model", model, "firmware", c.GetState().Firmware)
+	}
+
+	if _, err := c.GetDeviceName(); err != nil {
+		slog.Warn("Could not get speaker name", "error", err)
+	}
+
+	c.probeFeatures()
+
+	if _, err := c.GetSource(); err != nil {
+		slog.Warn("Could not get source", "error", err)
 	}
 
 	c.mu.Lock()
 	c.state.Connected = true
+	// Assume powered on when the source couldn't be read
+	c.state.IsPoweredOn = c.state.Source != kef.SourceStandby
 	c.state.Error = ""
 	c.mu.Unlock()
+	c.publish()
+
+	// Start periodic updates; reconnecting reuses the running poller
+	c.pollOnce.Do(func() {
+		safe.GoRestart("periodic updates", c.startPeriodicUpdates)
+	})
 
-	// Start periodic updates
-	go c.startPeriodicUpdates()
+	// Follow pushed changes where the firmware supports it
+	c.eventsOnce.Do(func() {
+		safe.GoRestart("event stream", c.streamEvents)
+	})
+
+	// Return a backed-off poller to the fast interval
+	select {
+	case c.pollWake <- struct{}{}:
+	default:
+	}
 
 	return nil
 }
 
-// Close shuts down the controller.
+// restoreStartupVolume sets the configured startup volume, so a speaker
+// left loud overnight comes back at a sensible level.
+func (c *Controller) restoreStartupVolume() {
+	level := c.cfg.StartupVolume
+	if err := c.SetVolume(level); err != nil {
+		slog.Warn("Could not set startup volume", "volume", level, "error", err)
+		return
+	}
+	slog.Info("Startup volume set", "volume", c.GetState().Volume)
+}
+
+// Close shuts down the controller and closes all subscriptions.
 func (c *Controller) Close() {
 	c.cancel()
+	c.closeSubscriptions()
 }
 
-// GetState returns a copy of the current speaker state.
+// GetState returns a copy of the current speaker state. PlaybackInfo is
+// copied too, so callers never share it with the controller.
 func (c *Controller) GetState() kef.SpeakerState {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return *c.state
+
+	state := *c.state
+	if state.PlaybackInfo != nil {
+		info := *state.PlaybackInfo
+		state.PlaybackInfo = &info
+	}
+	return state
 }
 
-// GetVolume retrieves the current volume level.
+// GetVolume retrieves the current volume level. While the speaker is muted
+// this is the level it will return to, see storeVolumeLocked.
 func (c *Controller) GetVolume() (int, error) {
-	volume, err := c.client.GetInt("player:volume")
+	return c.GetVolumeContext(c.ctx)
+}
+
+// GetVolumeContext is GetVolume bounded by ctx.
+func (c *Controller) GetVolumeContext(ctx context.Context) (int, error) {
+	volume, err := c.client.GetIntContext(ctx, volumePath)
 	if err != nil {
 		return 0, err
 	}
 
+	// A zero may just mean muted, so check before trusting it
+	muteKnown := false
+	var muted bool
+	if volume == 0 {
+		muted, err = c.client.GetBoolContext(ctx, mutePath)
+		muteKnown = err == nil
+	}
+
 	c.mu.Lock()
-	c.state.Volume = volume
+	if muteKnown {
+		c.state.Muted = muted
+	}
+	c.storeVolumeLocked(volume)
+	volume = c.state.Volume
 	c.mu.Unlock()
+	c.publish()
 
 	return volume, nil
 }
 
-// SetVolume sets the volume level (0-100).
+// storeVolumeLocked records a volume read from the speaker. Speakers may
+// report 0 while muted; that is ignored so the level from before muting is
+// kept, unmuting restores it and the icon doesn't drop to empty. c.mu must
+// be held, and the mute state should be current.
+func (c *Controller) storeVolumeLocked(volume int) {
+	if volume == 0 && c.state.Muted {
+		return
+	}
+	c.state.Volume = volume
+}
+
+// SetVolume sets the volume level, from 0 up to MaxVolume, stopping any
+// fade in progress.
 func (c *Controller) SetVolume(level int) error {
+	return c.SetVolumeContext(c.ctx, level)
+}
+
+// SetVolumeContext is SetVolume bounded by ctx. It takes over from any
+// volume fade in progress.
+func (c *Controller) SetVolumeContext(ctx context.Context, level int) error {
+	c.cancelFade()
+	return c.setVolume(ctx, level)
+}
+
+// setVolume is SetVolumeContext without cancelling fades, for the fades
+// themselves.
+func (c *Controller) setVolume(ctx context.Context, level int) error {
 	if level < 0 {
 		level = 0
 	}
-	if level > 100 {
-		level = 100
+	if limit := c.MaxVolume(); level > limit {
+		level = limit
 	}
 
-	err := c.client.SetInt("player:volume", level)
+	// A later change may have merged into this write; keep what was sent
+	sent, err := c.client.SetIntSentContext(ctx, volumePath, level)
 	if err != nil {
 		return err
 	}
 
 	c.mu.Lock()
-	c.state.Volume = level
+	c.state.Volume = sent
+	c.volumeSetAt = time.Now()
 	c.mu.Unlock()
+	c.publish()
 
 	return nil
 }
 
+// GetMute retrieves the current mute state.
+func (c *Controller) GetMute() (bool, error) {
+	return c.GetMuteContext(c.ctx)
+}
+
+// GetMuteContext is GetMute bounded by ctx.
+func (c *Controller) GetMuteContext(ctx context.Context) (bool, error) {
+	muted, err := c.client.GetBoolContext(ctx, mutePath)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.state.Muted = muted
+	c.mu.Unlock()
+	c.publish()
+
+	return muted, nil
+}
+
+// SetMute mutes or unmutes the speaker.
+func (c *Controller) SetMute(muted bool) error {
+	return c.SetMuteContext(c.ctx, muted)
+}
+
+// SetMuteContext is SetMute bounded by ctx.
+func (c *Controller) SetMuteContext(ctx context.Context, muted bool) error {
+	err := c.client.SetBoolContext(ctx, mutePath, muted)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if muted && !c.state.Muted {
+		c.premuteVolume = c.state.Volume
+	}
+	c.state.Muted = muted
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
+
+// ToggleMute flips the current mute state.
+func (c *Controller) ToggleMute() error {
+	return c.ToggleMuteContext(c.ctx)
+}
+
+// ToggleMuteContext is ToggleMute bounded by ctx.
+func (c *Controller) ToggleMuteContext(ctx context.Context) error {
+	c.mu.RLock()
+	muted := c.state.Muted
+	c.mu.RUnlock()
+
+	return c.SetMuteContext(ctx, !muted)
+}
+
 // VolumeUp increases volume by the configured step.
 func (c *Controller) VolumeUp() error {
+	return c.AdjustVolume(c.cfg.VolumeStep)
+}
+
+// VolumeDown decreases volume by the configured step.
+func (c *Controller) VolumeDown() error {
+	return c.AdjustVolume(-c.cfg.VolumeStep)
+}
+
+// AdjustVolume changes the volume by delta. If the speaker is muted it is
+// unmuted first and the delta applied to the level from before muting,
+// unless StickyMute is configured.
+func (c *Controller) AdjustVolume(delta int) error {
+	return c.AdjustVolumeContext(c.ctx, delta)
+}
+
+// AdjustVolumeContext is AdjustVolume bounded by ctx.
+func (c *Controller) AdjustVolumeContext(ctx context.Context, delta int) error {
 	c.mu.RLock()
 	current := c.state.Volume
+	muted := c.state.Muted
+	premute := c.premuteVolume
 	c.mu.RUnlock()
 
-	newVol := current + c.cfg.VolumeStep
-	if newVol > 100 {
-		newVol = 100
+	if muted && !c.cfg.StickyMute {
+		if premute > 0 {
+			current = premute
+		}
+		if err := c.SetMuteContext(ctx, false); err != nil {
+			return err
+		}
 	}
 
-	return c.SetVolume(newVol)
+	// SetVolume clamps to the allowed range
+	return c.SetVolumeContext(ctx, current+delta)
 }
 
-// VolumeDown decreases volume by the configured step.
-func (c *Controller) VolumeDown() error {
+// MaxVolume returns the highest volume the app will set: the lower of
+// config.Config.MaxVolume and the speaker's own limit.
+func (c *Controller) MaxVolume() int {
+	limit := c.cfg.VolumeLimit()
+
 	c.mu.RLock()
-	current := c.state.Volume
+	defer c.mu.RUnlock()
+	if speaker := c.state.MaxVolume; speaker > 0 && speaker < limit {
+		limit = speaker
+	}
+	return limit
+}
+
+// GetSpeakerRole reads the speaker's role in a stereo pair into the state,
+// kef.RolePrimary or kef.RoleSecondary, or "" if it doesn't report one.
+func (c *Controller) GetSpeakerRole() (string, error) {
+	reported, err := c.client.GetString(speakerRolePath)
+	if err != nil {
+		return "", err
+	}
+	role := kef.SpeakerRole(reported)
+
+	c.mu.Lock()
+	c.state.Role = role
+	c.mu.Unlock()
+	c.publish()
+
+	return role, nil
+}
+
+// GetSpeakerMaxVolume reads the speaker's volume limit into the state. When
+// the speaker doesn't report one, or reports one outside 1-100, the limit
+// is taken to be 100 and the error, if any, returned.
+func (c *Controller) GetSpeakerMaxVolume() (int, error) {
+	limit, err := c.client.GetInt(maxVolumePath)
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	c.mu.Lock()
+	c.state.MaxVolume = limit
+	c.mu.Unlock()
+	c.publish()
+
+	return limit, err
+}
+
+// GetSource retrieves the active physical source.
+func (c *Controller) GetSource() (string, error) {
+	return c.GetSourceContext(c.ctx)
+}
+
+// GetSourceContext is GetSource bounded by ctx.
+func (c *Controller) GetSourceContext(ctx context.Context) (string, error) {
+	source, err := c.client.GetTypedStringContext(ctx, sourcePath, sourceType)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.setSourceLocked(source)
+	c.mu.Unlock()
+	c.publish()
+
+	return source, nil
+}
+
+// ToggleBetweenSources switches to b when on a, and to a otherwise.
+func (c *Controller) ToggleBetweenSources(a, b string) error {
+	current, err := c.GetSource()
+	if err != nil {
+		return err
+	}
+
+	target := toggleTarget(current, a, b)
+	slog.Info("Toggling source", "from", current, "to", target)
+	return c.SetSource(target)
+}
+
+// CycleSource switches to the source after the current one in order,
+// wrapping around, or to the first when the current source isn't listed.
+func (c *Controller) CycleSource(order []string) error {
+	if len(order) == 0 {
+		return fmt.Errorf("no sources to cycle through")
+	}
+
+	current, err := c.GetSource()
+	if err != nil {
+		return err
+	}
+
+	target := cycleTarget(current, order)
+	slog.Info("Cycling source", "from", current, "to", target)
+	return c.SetSource(target)
+}
+
+// cycleTarget returns the source after current in order, wrapping around,
+// or the first one when current isn't in order.
+func cycleTarget(current string, order []string) string {
+	i := slices.Index(order, current)
+	return order[(i+1)%len(order)]
+}
+
+// toggleTarget returns the source to switch to from current: the other one
+// of a and b, or a when current is neither.
+func toggleTarget(current, a, b string) string {
+	if current == a {
+		return b
+	}
+	return a
+}
+
+// setSourceLocked records the active source. Callers must hold c.mu.
+func (c *Controller) setSourceLocked(source string) {
+	c.state.Source = source
+	// A speaker in standby still answers, it just reports standby as its source
+	poweredOn := source != kef.SourceStandby
+	if poweredOn != c.state.IsPoweredOn && c.state.Connected {
+		slog.Info("Speaker power changed", "powered_on", poweredOn)
+	}
+	c.state.IsPoweredOn = poweredOn
+	if kef.IsStreamingSource(source) {
+		c.lastStreamingSource = source
+	}
+}
+
+// SetSource switches the speaker to the given physical source.
+func (c *Controller) SetSource(source string) error {
+	return c.SetSourceContext(c.ctx, source)
+}
+
+// SetSourceContext is SetSource bounded by ctx.
+func (c *Controller) SetSourceContext(ctx context.Context, source string) error {
+	err := c.client.SetTypedStringContext(ctx, sourcePath, sourceType, source)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.setSourceLocked(source)
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
+
+// transportSource decides whether a transport command needs a source switch
+// first. Only wired inputs are switched away from; standby and unknown
+// sources are left alone so the speaker isn't woken or yanked unexpectedly.
+func transportSource(current, lastStreaming string) (string, bool) {
+	if !kef.IsWiredInput(current) {
+		return "", false
+	}
+	if lastStreaming == "" {
+		return kef.SourceWifi, true
+	}
+	return lastStreaming, true
+}
+
+// prepareTransport switches to a streaming source before a transport
+// command when AutoSwitchSource is enabled.
+func (c *Controller) prepareTransport(ctx context.Context) {
+	if !c.cfg.AutoSwitchSource {
+		return
+	}
+
+	current, err := c.GetSourceContext(ctx)
+	if err != nil {
+		slog.Warn("Could not get source before transport command", "error", err)
+		return
+	}
+
+	c.mu.RLock()
+	lastStreaming := c.lastStreamingSource
 	c.mu.RUnlock()
 
-	newVol := current - c.cfg.VolumeStep
-	if newVol < 0 {
-		newVol = 0
+	target, ok := transportSource(current, lastStreaming)
+	if !ok {
+		return
 	}
 
-	return c.SetVolume(newVol)
+	slog.Info("Switching source for transport command", "from", current, "to", target)
+	if err := c.SetSourceContext(ctx, target); err != nil {
+		slog.Warn("Failed to switch source for transport command", "error", err)
+	}
 }
 
-// GetSpeakerModel retrieves the speaker model from firmware info.
-func (c *Controller) GetSpeakerModel() (string, error) {
-	releaseText, err := c.client.GetString("settings:/releasetext")
+// GetDeviceName retrieves the speaker's name, as set in the KEF app.
+func (c *Controller) GetDeviceName() (string, error) {
+	name, err := c.client.GetString(deviceNamePath)
 	if err != nil {
 		return "", err
 	}
 
-	// Model is the first part before underscore (e.g., "LSXII_4.0.1")
-	parts := strings.Split(releaseText, "_")
-	if len(parts) == 0 {
-		return "", fmt.Errorf("invalid release text format")
+	c.mu.Lock()
+	c.state.Name = name
+	c.mu.Unlock()
+	c.publish()
+
+	return name, nil
+}
+
+// SetDeviceName renames the speaker. Leading and trailing space is
+// trimmed; the name may contain any Unicode, including emoji.
+func (c *Controller) SetDeviceName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("speaker name can't be empty")
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("speaker name isn't valid UTF-8")
+	}
+
+	if err := c.client.SetString(deviceNamePath, name); err != nil {
+		return err
 	}
 
-	model := parts[0]
+	c.mu.Lock()
+	c.state.Name = name
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
+
+// GetSpeakerModel retrieves the speaker model from firmware info. The
+// firmware version, read along with it, is stored too.
+func (c *Controller) GetSpeakerModel() (string, error) {
+	model, firmware, err := c.readReleaseText()
+	if err != nil {
+		return "", err
+	}
 
 	c.mu.Lock()
 	c.state.Model = model
+	c.state.Firmware = firmware
 	c.mu.Unlock()
+	c.publish()
 
 	return model, nil
 }
 
+// GetFirmwareVersion retrieves the speaker's firmware version (e.g.,
+// "4.0.1").
+func (c *Controller) GetFirmwareVersion() (string, error) {
+	_, firmware, err := c.readReleaseText()
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.state.Firmware = firmware
+	c.mu.Unlock()
+	c.publish()
+
+	return firmware, nil
+}
+
+// readReleaseText fetches the firmware release text and splits it into
+// the model and firmware version.
+func (c *Controller) readReleaseText() (model, firmware string, err error) {
+	releaseText, err := c.client.GetString(releaseTextPath)
+	if err != nil {
+		return "", "", err
+	}
+	return parseReleaseText(releaseText)
+}
+
+// parseReleaseText splits release text such as "LSXII_4.0.1" into the model
+// and the firmware version. The version is empty if the text has none.
+func parseReleaseText(releaseText string) (model, firmware string, err error) {
+	model, firmware, _ = strings.Cut(strings.TrimSpace(releaseText), "_")
+	if model == "" {
+		return "", "", fmt.Errorf("invalid release text format %q", releaseText)
+	}
+	return model, firmware, nil
+}
+
 // NextTrack skips to the next track.
 func (c *Controller) NextTrack() error {
-	err := c.client.SetData("player:player/control", "activate", `{"control":"next"}`)
+	return c.NextTrackContext(c.ctx)
+}
+
+// NextTrackContext is NextTrack bounded by ctx.
+func (c *Controller) NextTrackContext(ctx context.Context) error {
+	c.prepareTransport(ctx)
+
+	err := c.client.SetDataContext(ctx, "player:player/control", "activate", `{"control":"next"}`)
 	if err != nil {
 		return err
 	}
 
 	// Refresh playback info after a delay
-	go func() {
+	safe.Go("playback refresh", func() {
 		time.Sleep(500 * time.Millisecond)
 		_, _ = c.GetPlaybackInfo()
-	}()
+	})
 
 	return nil
 }
 
 // PreviousTrack skips to the previous track.
 func (c *Controller) PreviousTrack() error {
-	err := c.client.SetData("player:player/control", "activate", `{"control":"previous"}`)
+	return c.PreviousTrackContext(c.ctx)
+}
+
+// PreviousTrackContext is PreviousTrack bounded by ctx.
+func (c *Controller) PreviousTrackContext(ctx context.Context) error {
+	c.prepareTransport(ctx)
+
+	err := c.client.SetDataContext(ctx, "player:player/control", "activate", `{"control":"previous"}`)
 	if err != nil {
 		return err
 	}
 
 	// Refresh playback info after a delay
-	go func() {
+	safe.Go("playback refresh", func() {
 		time.Sleep(500 * time.Millisecond)
 		_, _ = c.GetPlaybackInfo()
-	}()
+	})
 
 	return nil
 }
@@ -223,18 +885,33 @@ func (c *Controller) PreviousTrack() error {
 // PlayPause toggles between play and pause.
 // KEF speakers use "pause" as a toggle command.
 func (c *Controller) PlayPause() error {
+	return c.PlayPauseContext(c.ctx)
+}
+
+// PlayPauseContext is PlayPause bounded by ctx.
+func (c *Controller) PlayPauseContext(ctx context.Context) error {
+	c.prepareTransport(ctx)
+
+	if c.cfg.FadeOnPlayPause {
+		return c.fadePlayPause(ctx)
+	}
+	return c.togglePlayback(ctx)
+}
+
+// togglePlayback sends the play/pause toggle.
+func (c *Controller) togglePlayback(ctx context.Context) error {
 	// KEF treats "pause" as a play/pause toggle
 	slog.Info("Sending pause toggle command")
-	err := c.client.SetData("player:player/control", "activate", `{"control":"pause"}`)
+	err := c.client.SetDataContext(ctx, "player:player/control", "activate", `{"control":"pause"}`)
 	if err != nil {
 		return err
 	}
 
 	// Refresh playback info after a delay
-	go func() {
+	safe.Go("playback refresh", func() {
 		time.Sleep(500 * time.Millisecond)
 		_, _ = c.GetPlaybackInfo()
-	}()
+	})
 
 	return nil
 }
@@ -248,20 +925,76 @@ func (c *Controller) IsPlaying() bool {
 
 // GetPlaybackInfo retrieves current playback information.
 func (c *Controller) GetPlaybackInfo() (*kef.PlaybackInfo, error) {
-	result, err := c.client.GetData("player:player/data", "value")
+	result, err := c.client.GetData(playerDataPath, "value")
 	if err != nil {
 		return nil, err
 	}
 
-	if len(result) == 0 {
-		return nil, fmt.Errorf("empty playback response")
+	data, err := api.FirstValue(result)
+	if err != nil {
+		return nil, fmt.Errorf("playback info: %w", err)
 	}
 
-	data, ok := result[0].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid playback response format")
+	// Not every source has a play mode; keep the last one known if so
+	_, _, _ = c.GetPlayMode()
+
+	return c.storePlaybackInfo(parsePlaybackInfo(data)), nil
+}
+
+// storePlaybackInfo records freshly read playback info, announces a new
+// track or playback state, and returns a copy; the stored one is only read
+// under c.mu.
+func (c *Controller) storePlaybackInfo(info *kef.PlaybackInfo) *kef.PlaybackInfo {
+	c.mu.Lock()
+	prev := c.state.PlaybackInfo
+	changed := trackChanged(prev, info)
+	stateChanged := prev != nil && prev.State != info.State
+	onTrackChange, onStateChange := c.onTrackChange, c.onPlaybackStateChange
+	info.Shuffle, info.Repeat = c.playMode.shuffle, c.playMode.repeat
+	c.state.PlaybackInfo = info
+	c.mu.Unlock()
+	c.publish()
+
+	infoCopy := *info
+	if changed && onTrackChange != nil && info.State == "playing" {
+		onTrackChange(infoCopy)
+	}
+	if stateChanged && onStateChange != nil {
+		onStateChange(prev.State, info.State)
 	}
+	return &infoCopy
+}
 
+// OnPlaybackStateChange sets a function called with the old and new
+// playback state, e.g. "paused" and "playing", whenever it changes. The
+// first state seen after starting only sets the baseline. It is called
+// from the polling or event goroutine, so it should return quickly.
+func (c *Controller) OnPlaybackStateChange(cb func(old, new string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onPlaybackStateChange = cb
+}
+
+// SetTrackChangeCallback sets a function called with the new track whenever
+// a different track starts playing. It is called from the polling goroutine.
+func (c *Controller) SetTrackChangeCallback(cb func(kef.PlaybackInfo)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onTrackChange = cb
+}
+
+// trackChanged reports whether next is a different track from prev. The
+// first track seen after connecting only sets the baseline.
+func trackChanged(prev, next *kef.PlaybackInfo) bool {
+	if prev == nil || next.Title == "" {
+		return false
+	}
+	return next.Title != prev.Title || next.Artist != prev.Artist
+}
+
+// parsePlaybackInfo extracts playback information from a player:player/data
+// value.
+func parsePlaybackInfo(data map[string]interface{}) *kef.PlaybackInfo {
 	info := &kef.PlaybackInfo{}
 
 	// Extract state
@@ -298,31 +1031,243 @@ func (c *Controller) GetPlaybackInfo() (*kef.PlaybackInfo, error) {
 		}
 	}
 
+	info.QueueIndex, info.QueueLength = parseQueuePosition(data)
+	info.SampleRate, info.BitDepth, info.Codec = parseStreamQuality(data)
+
+	return info
+}
+
+// parseStreamQuality finds the sample rate, bit depth and codec of the
+// playing stream in the media resource of trackRoles: the active one, or
+// else the first. The codec falls back to the resource's MIME subtype, e.g.
+// "FLAC" for audio/flac. All are zero or empty if not reported.
+func parseStreamQuality(data map[string]interface{}) (sampleRate, bitDepth int, codec string) {
+	trackRoles, _ := data["trackRoles"].(map[string]interface{})
+	mediaData, _ := trackRoles["mediaData"].(map[string]interface{})
+
+	resource, ok := mediaData["activeResource"].(map[string]interface{})
+	if !ok {
+		resources, _ := mediaData["resources"].([]interface{})
+		if len(resources) == 0 {
+			return 0, 0, ""
+		}
+		if resource, ok = resources[0].(map[string]interface{}); !ok {
+			return 0, 0, ""
+		}
+	}
+
+	if rate, ok := resource["sampleFrequency"].(float64); ok {
+		sampleRate = int(rate)
+	}
+	if bits, ok := resource["bitsPerSample"].(float64); ok {
+		bitDepth = int(bits)
+	}
+	if c, ok := resource["codec"].(string); ok && c != "" {
+		codec = strings.ToUpper(c)
+	} else if mimeType, ok := resource["mimeType"].(string); ok {
+		if _, subtype, found := strings.Cut(mimeType, "/"); found {
+			codec = strings.ToUpper(strings.TrimPrefix(subtype, "x-"))
+		}
+	}
+	return sampleRate, bitDepth, codec
+}
+
+// parseQueuePosition finds the queue index and length, which depending on
+// the source are reported in "status" or "trackRoles". The speaker reports a
+// zero-based index; the returned index is one-based. Both are zero when the
+// source has no queue.
+func parseQueuePosition(data map[string]interface{}) (index, length int) {
+	for _, key := range []string{"status", "trackRoles"} {
+		m, ok := data[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		idx, okIdx := m["queueIndex"].(float64)
+		n, okLen := m["queueLength"].(float64)
+		if okIdx && okLen && n > 0 && idx >= 0 && idx < n {
+			return int(idx) + 1, int(n)
+		}
+	}
+	return 0, 0
+}
+
+// FetchAlbumArt downloads the album art for the current track. The result is
+// cached by URL so repeated calls for the same track don't refetch.
+func (c *Controller) FetchAlbumArt(ctx context.Context) ([]byte, error) {
+	c.mu.RLock()
+	artURL := ""
+	if c.state.PlaybackInfo != nil {
+		artURL = c.state.PlaybackInfo.AlbumArt
+	}
+	if artURL != "" && artURL == c.artURL {
+		data := c.artData
+		c.mu.RUnlock()
+		return data, nil
+	}
+	c.mu.RUnlock()
+
+	if artURL == "" {
+		return nil, fmt.Errorf("no album art available")
+	}
+
+	data, err := c.client.Download(ctx, artURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch album art: %w", err)
+	}
+
 	c.mu.Lock()
-	c.state.PlaybackInfo = info
+	c.artURL = artURL
+	c.artData = data
 	c.mu.Unlock()
 
-	return info, nil
+	return data, nil
 }
 
 // startPeriodicUpdates polls the speaker for state updates.
 func (c *Controller) startPeriodicUpdates() {
-	ticker := time.NewTicker(c.cfg.PollInterval)
-	defer ticker.Stop()
+	delay := c.cfg.PollInterval
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-c.pollWake:
+			// Reconnected: resume at the fast interval right away
+			delay = c.cfg.PollInterval
+		case <-timer.C:
 			c.mu.RLock()
 			connected := c.state.Connected
 			c.mu.RUnlock()
 
 			if connected {
-				_, _ = c.GetVolume()
-				_, _ = c.GetPlaybackInfo()
+				c.pollState()
+			}
+
+			base := c.cfg.PollInterval
+			if c.streaming.Load() {
+				// Changes are pushed; polling only catches anything missed
+				base = max(base, streamPollInterval)
 			}
+			delay = pollDelay(base, delay, connected, c.IsPlaying())
+		}
+		timer.Reset(delay)
+	}
+}
+
+// pollState refreshes the polled parts of the speaker state in one batched
+// read. A value that can't be read leaves the previous one in place until
+// the next poll, but a failed read marks the speaker disconnected.
+func (c *Controller) pollState() {
+	values, err := c.client.GetMany(c.polledPaths())
+	if err != nil && !errors.Is(err, api.ErrValueUnavailable) {
+		// The speaker stopped answering (deep sleep, network change); a
+		// speaker in standby still answers and stays connected
+		slog.Warn("Lost connection to speaker", "error", err)
+		c.mu.Lock()
+		c.state.Connected = false
+		c.state.Error = err.Error()
+		c.mu.Unlock()
+		c.publish()
+		return
+	}
+
+	c.applyValues(values)
+}
+
+// polledPaths returns the paths read on every poll. In standby only the
+// source is read, which is enough to see the speaker wake.
+func (c *Controller) polledPaths() []string {
+	c.mu.RLock()
+	standby := !c.state.IsPoweredOn
+	c.mu.RUnlock()
+	if standby {
+		return []string{sourcePath}
+	}
+	return c.watchedPaths()
+}
+
+// watchedPaths returns the paths the controller follows, including those
+// of the supported optional features. Event streams subscribe to all of
+// them even in standby, as a stream outlives the standby it started in.
+func (c *Controller) watchedPaths() []string {
+	paths := []string{volumePath, mutePath, sourcePath, playModePath, playerDataPath}
+	for _, feature := range polledFeatures {
+		if c.Supports(feature) {
+			paths = append(paths, featureProbes[feature])
 		}
 	}
+	return paths
+}
+
+// applyValues stores the polled paths present in values, keyed by path.
+func (c *Controller) applyValues(values map[string]map[string]interface{}) {
+	c.mu.Lock()
+	// Mute first, so a muted zero volume is recognized
+	setPolledBool(values, mutePath, &c.state.Muted)
+	if v, ok := values[volumePath]; ok {
+		volume, err := api.IntValue(v)
+		logPollError("volume", err)
+		// A reading this soon after a write may predate it
+		if err == nil && time.Since(c.volumeSetAt) >= volumeSettleWindow {
+			c.storeVolumeLocked(volume)
+		}
+	}
+	if v, ok := values[sourcePath]; ok {
+		source, err := api.TypedStringValue(v, sourceType)
+		logPollError("source", err)
+		if err == nil {
+			c.setSourceLocked(source)
+		}
+	}
+	setPolledBool(values, voiceAssistantPath, &c.state.VoiceAssistant)
+	setPolledBool(values, headphonesPath, &c.state.Headphones)
+	setPolledBool(values, autoPowerOnPath, &c.state.AutoPowerOn)
+	if v, ok := values[playModePath]; ok {
+		c.applyPlayModeLocked(v)
+	}
+	c.mu.Unlock()
+	c.publish()
+
+	if v, ok := values[playerDataPath]; ok {
+		c.storePlaybackInfo(parsePlaybackInfo(v))
+	}
+}
+
+// setPolledBool stores the boolean polled at path, if it was read.
+func setPolledBool(values map[string]map[string]interface{}, path string, dst *bool) {
+	v, ok := values[path]
+	if !ok {
+		return
+	}
+	b, err := api.BoolValue(v)
+	logPollError(path, err)
+	if err == nil {
+		*dst = b
+	}
+}
+
+// logPollError logs a failed poll read. Values that are briefly unavailable,
+// e.g. during a source switch, are expected and not logged.
+func logPollError(value string, err error) {
+	if err == nil || errors.Is(err, api.ErrValueUnavailable) {
+		return
+	}
+	slog.Debug("Poll read failed", "value", value, "error", err)
+}
+
+// pollDelay returns how long to wait before the next poll. Playback polls
+// at the base interval and stopped playback at idlePollInterval. While
+// disconnected the delay doubles from prev, up to maxPollBackoff.
+func pollDelay(base, prev time.Duration, connected, playing bool) time.Duration {
+	switch {
+	case !connected:
+		return min(max(prev*2, base), maxPollBackoff)
+	case playing:
+		return base
+	default:
+		return max(base, idlePollInterval)
+	}
 }
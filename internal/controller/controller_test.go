@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/internal/config"
+)
+
+// playbackPayload is a representative player:player/data value.
+const playbackPayload = `{
+	"state": "playing",
+	"status": {"duration": 254000, "queueIndex": 3, "queueLength": 12},
+	"trackRoles": {
+		"title": "Windowlicker",
+		"icon": "http://i.scdn.co/image/ab67616d0000b273",
+		"mediaData": {
+			"metaData": {"artist": "Aphex Twin", "album": "Windowlicker EP", "serviceID": "spotify"},
+			"resources": [{"mimeType": "audio/mpeg", "uri": "spotify:track:1"}]
+		}
+	},
+	"controls": {"pause": true, "next_": true, "previous": true}
+}`
+
+// fakeSpeaker is a stub KEF speaker that holds a JSON value per path.
+// Paths it doesn't hold answer 404, and it has no event queue, so clients
+// fall back to sequential reads and controllers poll.
+type fakeSpeaker struct {
+	mu     sync.Mutex
+	values map[string]string
+	writes []fakeWrite
+}
+
+// fakeWrite is a setData request received by a fakeSpeaker.
+type fakeWrite struct {
+	path, roles, value string
+}
+
+// newFakeSpeaker returns a playing LSX II at volume 42.
+func newFakeSpeaker() *fakeSpeaker {
+	return &fakeSpeaker{values: map[string]string{
+		volumePath:      `{"type":"i32_","i32_":42}`,
+		mutePath:        `{"type":"bool_","bool_":false}`,
+		sourcePath:      `{"type":"kefPhysicalSource","kefPhysicalSource":"wifi"}`,
+		releaseTextPath: `{"type":"string_","string_":"LSXII_4.0.1"}`,
+		deviceNamePath:  `{"type":"string_","string_":"Office"}`,
+		playerDataPath:  playbackPayload,
+	}}
+}
+
+// set stores the JSON value at path.
+func (s *fakeSpeaker) set(path, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[path] = value
+}
+
+// writesTo returns the values written to path, oldest first.
+func (s *fakeSpeaker) writesTo(path string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var values []string
+	for _, w := range s.writes {
+		if w.path == path {
+			values = append(values, w.value)
+		}
+	}
+	return values
+}
+
+func (s *fakeSpeaker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	path := q.Get("path")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/getData":
+		value, ok := s.values[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[" + value + "]"))
+	case "/api/setData":
+		s.writes = append(s.writes, fakeWrite{path, q.Get("roles"), q.Get("value")})
+		if q.Get("roles") == "value" && json.Valid([]byte(q.Get("value"))) {
+			s.values[path] = q.Get("value")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestController returns a controller for a test server running
+// handler, with writes unlimited. It is closed when the test ends.
+func newTestController(tb testing.TB, handler http.Handler) *Controller {
+	tb.Helper()
+
+	server := httptest.NewServer(handler)
+	tb.Cleanup(server.Close)
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.SpeakerIP = host
+	cfg.Port = port
+	cfg.WriteIntervalMs = 0
+
+	c := New(cfg, api.WithTransport(server.Client().Transport))
+	c.SetIP(host)
+	tb.Cleanup(c.Close)
+	return c
+}
+
+func BenchmarkGetPlaybackInfo(b *testing.B) {
+	c := newTestController(b, newFakeSpeaker())
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := c.GetPlaybackInfo(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+)
+
+// The EQ settings live in a single profile object that the speaker replaces
+// wholesale on write.
+const (
+	eqProfilePath = "kef:eqProfile/v2"
+	eqProfileType = "kefEqProfileV2"
+)
+
+// EQ profile fields.
+const (
+	eqBassExtension = "bassExtension"
+	eqTrebleAmount  = "trebleAmount"
+	eqBalance       = "balance"
+)
+
+// MaxBalance is the furthest the balance goes either way: -MaxBalance is
+// fully left, MaxBalance fully right.
+const MaxBalance = 30
+
+// optionalEQFields are only in the profiles of some models. Setting one the
+// profile lacks returns ErrUnsupported rather than adding it.
+var optionalEQFields = map[string]bool{
+	eqBalance: true,
+}
+
+// GetEQ retrieves the full EQ profile.
+func (c *Controller) GetEQ() (map[string]interface{}, error) {
+	return c.client.GetObject(eqProfilePath, eqProfileType)
+}
+
+// SetBassExtension sets the bass extension ("less", "standard" or "extra").
+func (c *Controller) SetBassExtension(extension string) error {
+	return c.setEQField(eqBassExtension, extension)
+}
+
+// SetTreble sets the treble adjustment in dB.
+func (c *Controller) SetTreble(db float64) error {
+	return c.setEQField(eqTrebleAmount, db)
+}
+
+// GetBalance retrieves the left/right balance, from -MaxBalance (left) to
+// MaxBalance (right).
+func (c *Controller) GetBalance() (int, error) {
+	profile, err := c.GetEQ()
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := profile[eqBalance].(float64)
+	if !ok {
+		return 0, fmt.Errorf("balance: %w", ErrUnsupported)
+	}
+	balance := int(value)
+
+	c.mu.Lock()
+	c.state.Balance = balance
+	c.mu.Unlock()
+	c.publish()
+
+	return balance, nil
+}
+
+// SetBalance sets the left/right balance, clamped to ±MaxBalance.
+func (c *Controller) SetBalance(balance int) error {
+	balance = min(max(balance, -MaxBalance), MaxBalance)
+	if err := c.setEQField(eqBalance, balance); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.state.Balance = balance
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
+
+// setEQField changes one EQ field. Writing the profile replaces all of it,
+// so the current profile is re-read first rather than trusting an earlier
+// read, which may be stale if the speaker was changed from another app.
+// eqMu keeps concurrent setters from interleaving their read and write.
+func (c *Controller) setEQField(field string, value interface{}) error {
+	c.eqMu.Lock()
+	defer c.eqMu.Unlock()
+
+	current, err := c.client.GetObject(eqProfilePath, eqProfileType)
+	if err != nil {
+		return fmt.Errorf("failed to read EQ profile: %w", err)
+	}
+	if _, ok := current[field]; !ok && optionalEQFields[field] {
+		return fmt.Errorf("%s: %w", field, ErrUnsupported)
+	}
+
+	profile := withEQField(current, field, value)
+	if err := c.client.SetObject(eqProfilePath, eqProfileType, profile); err != nil {
+		return err
+	}
+
+	slog.Debug("EQ updated", "field", field, "value", value)
+	return nil
+}
+
+// withEQField returns a copy of profile with field set to value, leaving
+// every other field as it was.
+func withEQField(profile map[string]interface{}, field string, value interface{}) map[string]interface{} {
+	updated := maps.Clone(profile)
+	if updated == nil {
+		updated = make(map[string]interface{}, 1)
+	}
+	updated[field] = value
+	return updated
+}
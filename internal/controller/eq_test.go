@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// eqValue wraps an EQ profile as the speaker reports it.
+func eqValue(t *testing.T, profile map[string]interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]interface{}{"type": eqProfileType, eqProfileType: profile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// writtenEQ decodes the last EQ profile written to speaker.
+func writtenEQ(t *testing.T, speaker *fakeSpeaker) map[string]interface{} {
+	t.Helper()
+
+	writes := speaker.writesTo(eqProfilePath)
+	if len(writes) == 0 {
+		t.Fatal("no EQ profile written")
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal([]byte(writes[len(writes)-1]), &value); err != nil {
+		t.Fatal(err)
+	}
+	profile, _ := value[eqProfileType].(map[string]interface{})
+	return profile
+}
+
+func TestSetEQFieldRefetchesStaleProfile(t *testing.T) {
+	speaker := newFakeSpeaker()
+	speaker.set(eqProfilePath, eqValue(t, map[string]interface{}{
+		eqBassExtension: "standard", eqTrebleAmount: 0.0, eqBalance: 0.0,
+	}))
+	c := newTestController(t, speaker)
+
+	if _, err := c.GetEQ(); err != nil {
+		t.Fatal(err)
+	}
+	// Changed from the KEF app since the read above
+	speaker.set(eqProfilePath, eqValue(t, map[string]interface{}{
+		eqBassExtension: "standard", eqTrebleAmount: 2.5, eqBalance: -5.0,
+	}))
+
+	if err := c.SetBassExtension("extra"); err != nil {
+		t.Fatalf("SetBassExtension() error = %v", err)
+	}
+
+	profile := writtenEQ(t, speaker)
+	want := map[string]interface{}{eqBassExtension: "extra", eqTrebleAmount: 2.5, eqBalance: -5.0}
+	for field, value := range want {
+		if profile[field] != value {
+			t.Errorf("written %s = %v, want %v", field, profile[field], value)
+		}
+	}
+}
+
+func TestSetBalanceUnsupported(t *testing.T) {
+	speaker := newFakeSpeaker()
+	speaker.set(eqProfilePath, eqValue(t, map[string]interface{}{eqBassExtension: "standard"}))
+	c := newTestController(t, speaker)
+
+	if err := c.SetBalance(10); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetBalance() on a profile without balance error = %v, want ErrUnsupported", err)
+	}
+	if writes := speaker.writesTo(eqProfilePath); len(writes) != 0 {
+		t.Errorf("SetBalance() wrote %v to a profile without balance", writes)
+	}
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/api"
+)
+
+// streamPollInterval is the poll cadence while an event stream is running.
+// Polling then only detects a lost speaker and catches anything the stream
+// missed, such as the playback position.
+const streamPollInterval = idlePollInterval
+
+// streamEvents follows the changes the speaker pushes while it is
+// connected, so the state updates as soon as the speaker changes rather
+// than at the next poll. On firmware without an event queue it leaves the
+// speaker to the poller.
+func (c *Controller) streamEvents() {
+	delay := time.Duration(0)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	// unsupported is the IP of a speaker known to have no event queue
+	var unsupported string
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		c.mu.RLock()
+		ip := c.state.IPAddress
+		connected := c.state.Connected
+		c.mu.RUnlock()
+
+		if !connected || ip == unsupported {
+			delay = reconnectMinDelay
+			timer.Reset(delay)
+			continue
+		}
+
+		start := time.Now()
+		c.streaming.Store(true)
+		err := c.client.WatchEvents(c.ctx, c.watchedPaths(), c.applyEvent)
+		c.streaming.Store(false)
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case errors.Is(err, api.ErrEventsUnsupported):
+			slog.Info("Speaker doesn't push changes, polling instead", "error", err)
+			unsupported = ip
+			delay = reconnectMinDelay
+		case time.Since(start) > maxPollBackoff:
+			// A stream that ran for a while was healthy; resume promptly
+			slog.Debug("Event stream ended", "error", err)
+			delay = reconnectMinDelay
+		default:
+			delay = min(max(delay*2, reconnectMinDelay), maxPollBackoff)
+			slog.Debug("Event stream failed", "error", err, "retry_in", delay)
+		}
+		timer.Reset(delay)
+	}
+}
+
+// applyEvent stores a pushed change. Values that became unavailable are
+// left for the next poll, as a failed poll read would be.
+func (c *Controller) applyEvent(ev api.Event) {
+	if ev.Value == nil {
+		return
+	}
+	c.applyValues(map[string]map[string]interface{}{ev.Path: ev.Value})
+}
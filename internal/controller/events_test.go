@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+func TestWatchedPathsInStandby(t *testing.T) {
+	c := newTestController(t, newFakeSpeaker())
+
+	c.mu.Lock()
+	c.setSourceLocked(kef.SourceStandby)
+	c.mu.Unlock()
+
+	if got := c.polledPaths(); !slices.Equal(got, []string{sourcePath}) {
+		t.Errorf("polledPaths() in standby = %v, want only the source", got)
+	}
+	// A stream opened now must still deliver changes once the speaker wakes
+	for _, path := range []string{volumePath, mutePath, playerDataPath} {
+		if !slices.Contains(c.watchedPaths(), path) {
+			t.Errorf("watchedPaths() in standby is missing %s", path)
+		}
+	}
+
+	c.mu.Lock()
+	c.setSourceLocked(kef.SourceWifi)
+	c.mu.Unlock()
+
+	if got, want := c.polledPaths(), c.watchedPaths(); !slices.Equal(got, want) {
+		t.Errorf("polledPaths() after waking = %v, want %v", got, want)
+	}
+}
+
+// pushingSpeaker is a fakeSpeaker with an event queue. Long polls wait for
+// a change passed to push; immediate polls report nothing, so batched
+// reads fall back to getData.
+type pushingSpeaker struct {
+	*fakeSpeaker
+	events chan string
+}
+
+func newPushingSpeaker() *pushingSpeaker {
+	return &pushingSpeaker{fakeSpeaker: newFakeSpeaker(), events: make(chan string)}
+}
+
+// push delivers a change of path to value on the next long poll.
+func (s *pushingSpeaker) push(t *testing.T, path, value string) {
+	t.Helper()
+
+	event := `[{"path":"` + path + `","itemType":"itemWithValue","itemValue":` + value + `}]`
+	select {
+	case s.events <- event:
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing polled the speaker's event queue")
+	}
+}
+
+func (s *pushingSpeaker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/event/modifyQueue":
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`"{queue-1}"`))
+	case "/api/event/pollQueue":
+		events := "[]"
+		if r.URL.Query().Get("timeout") != "0" {
+			select {
+			case events = <-s.events:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(events))
+	default:
+		s.fakeSpeaker.ServeHTTP(w, r)
+	}
+}
+
+func TestStreamEventsAppliesPushedChanges(t *testing.T) {
+	speaker := newPushingSpeaker()
+	c := newTestController(t, speaker)
+	// Only a pushed change can update the state within the test
+	c.cfg.PollInterval = time.Hour
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	speaker.push(t, volumePath, volumeValue(55))
+	waitForVolume(t, c, 55)
+	if !c.streaming.Load() {
+		t.Error("event stream not running after a pushed change")
+	}
+}
+
+func TestStreamEventsFallsBackToPolling(t *testing.T) {
+	// fakeSpeaker has no event queue
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+	c.cfg.PollInterval = 20 * time.Millisecond
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A running stream would slow polling to streamPollInterval
+	speaker.set(volumePath, volumeValue(60))
+	waitForVolume(t, c, 60)
+	speaker.set(volumePath, volumeValue(61))
+	waitForVolume(t, c, 61)
+}
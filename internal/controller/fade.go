@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// fadeStepInterval is the time between volume steps of a fade.
+const fadeStepInterval = 100 * time.Millisecond
+
+// ErrFadeCancelled is returned by FadeVolume when another volume command
+// or fade takes over before it finishes.
+var ErrFadeCancelled = errors.New("volume fade cancelled")
+
+// FadeVolume ramps the volume to target, clamped like SetVolume, in steps
+// over the given duration, and blocks until it gets there; a duration of
+// zero or less sets it at once. Setting the volume or starting another
+// fade meanwhile stops it with ErrFadeCancelled.
+func (c *Controller) FadeVolume(target int, over time.Duration) error {
+	return c.FadeVolumeContext(c.ctx, target, over)
+}
+
+// FadeVolumeContext is FadeVolume bounded by ctx.
+func (c *Controller) FadeVolumeContext(ctx context.Context, target int, over time.Duration) error {
+	target = min(max(target, 0), c.MaxVolume())
+
+	ctx, id := c.startFade(ctx)
+	defer c.endFade(id)
+
+	// Nothing to spread the steps over; a ticker would panic
+	if over <= 0 {
+		return c.setVolume(ctx, target)
+	}
+
+	c.mu.RLock()
+	start := c.state.Volume
+	c.mu.RUnlock()
+
+	steps := max(int(over/fadeStepInterval), 1)
+	ticker := time.NewTicker(over / time.Duration(steps))
+	defer ticker.Stop()
+
+	last := start
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return c.fadeErr(ctx)
+		}
+
+		// The last step lands exactly on target
+		level := start + (target-start)*i/steps
+		if level == last && i < steps {
+			continue
+		}
+		if err := c.setVolume(ctx, level); err != nil {
+			if ctx.Err() != nil {
+				return c.fadeErr(ctx)
+			}
+			return err
+		}
+		last = level
+	}
+
+	return nil
+}
+
+// startFade registers a new fade, cancelling the one in progress, and
+// returns its context and ID.
+func (c *Controller) startFade(ctx context.Context) (context.Context, uint64) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.fadeMu.Lock()
+	defer c.fadeMu.Unlock()
+
+	if c.fadeCancel != nil {
+		c.fadeCancel()
+	}
+	c.fadeID++
+	c.fadeCancel = cancel
+	return ctx, c.fadeID
+}
+
+// endFade releases the fade with the given ID, unless another has already
+// replaced it.
+func (c *Controller) endFade(id uint64) {
+	c.fadeMu.Lock()
+	defer c.fadeMu.Unlock()
+
+	if c.fadeID == id && c.fadeCancel != nil {
+		c.fadeCancel()
+		c.fadeCancel = nil
+	}
+}
+
+// cancelFade stops the fade in progress, if any.
+func (c *Controller) cancelFade() {
+	c.fadeMu.Lock()
+	defer c.fadeMu.Unlock()
+
+	if c.fadeCancel != nil {
+		c.fadeCancel()
+		c.fadeCancel = nil
+	}
+}
+
+// fadeErr returns why the fade with context ctx stopped early: the caller's
+// context ending, or ErrFadeCancelled when it was taken over.
+func (c *Controller) fadeErr(ctx context.Context) error {
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return ErrFadeCancelled
+}
+
+// fadePlayPause toggles playback with a fade: playback fades out before
+// pausing, and the volume is put back afterwards so the next play isn't
+// silent; starting playback fades in from zero.
+func (c *Controller) fadePlayPause(ctx context.Context) error {
+	state := c.GetState()
+	level := state.Volume
+	if state.Muted || level == 0 {
+		return c.togglePlayback(ctx)
+	}
+	over := c.cfg.FadeDuration()
+
+	if c.IsPlaying() {
+		if err := c.FadeVolumeContext(ctx, 0, over); err != nil {
+			// The volume was taken over or couldn't be set; pause anyway
+			// and leave the volume alone
+			slog.Debug("Fade out interrupted", "error", err)
+			return c.togglePlayback(ctx)
+		}
+		err := c.togglePlayback(ctx)
+		if restoreErr := c.setVolume(ctx, level); restoreErr != nil {
+			slog.Warn("Could not restore volume after fading out", "volume", level, "error", restoreErr)
+		}
+		return err
+	}
+
+	if err := c.setVolume(ctx, 0); err != nil {
+		return c.togglePlayback(ctx)
+	}
+	if err := c.togglePlayback(ctx); err != nil {
+		_ = c.setVolume(ctx, level)
+		return err
+	}
+	if err := c.FadeVolumeContext(ctx, level, over); err != nil && !errors.Is(err, ErrFadeCancelled) {
+		return err
+	}
+	return nil
+}
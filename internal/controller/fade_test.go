@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFadeVolumeWithoutDuration(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+
+	for _, over := range []time.Duration{0, -time.Second} {
+		if err := c.FadeVolume(10, over); err != nil {
+			t.Fatalf("FadeVolume(10, %v) error = %v", over, err)
+		}
+		if got := c.GetState().Volume; got != 10 {
+			t.Errorf("FadeVolume(10, %v) left volume at %d", over, got)
+		}
+	}
+	if writes := speaker.writesTo(volumePath); len(writes) != 2 {
+		t.Errorf("FadeVolume without a duration wrote %d times, want once per call", len(writes))
+	}
+}
+
+func TestFadeVolumeLandsOnTarget(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+	if _, err := c.GetVolume(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.FadeVolume(17, 3*fadeStepInterval); err != nil {
+		t.Fatalf("FadeVolume() error = %v", err)
+	}
+
+	writes := speaker.writesTo(volumePath)
+	if len(writes) == 0 {
+		t.Fatal("FadeVolume() wrote nothing")
+	}
+	if last, want := writes[len(writes)-1], `{"type":"i32_","i32_":17}`; last != want {
+		t.Errorf("last fade step wrote %s, want %s", last, want)
+	}
+	if got := c.GetState().Volume; got != 17 {
+		t.Errorf("volume after fade = %d, want 17", got)
+	}
+}
+
+func TestFadeVolumeCancelled(t *testing.T) {
+	c := newTestController(t, newFakeSpeaker())
+	if _, err := c.GetVolume(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*fadeStepInterval)
+		defer cancel()
+
+		err := c.FadeVolumeContext(ctx, 0, time.Minute)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("FadeVolumeContext() error = %v, want the context's", err)
+		}
+	})
+
+	t.Run("taken over", func(t *testing.T) {
+		done := make(chan error, 1)
+		go func() { done <- c.FadeVolume(0, time.Minute) }()
+
+		time.Sleep(2 * fadeStepInterval)
+		if err := c.SetVolume(30); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrFadeCancelled) {
+				t.Errorf("FadeVolume() error = %v, want ErrFadeCancelled", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("FadeVolume() kept going after SetVolume")
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// ErrUnsupported is returned when the connected speaker doesn't expose a
+// feature.
+var ErrUnsupported = errors.New("not supported by this speaker")
+
+// Settings paths for optional features.
+const (
+	voiceAssistantPath = "settings:/kef/host/voiceAssistant"
+	headphonesPath     = "settings:/kef/host/headphonesConnected"
+	autoPowerOnPath    = "settings:/kef/host/autoPowerOn"
+)
+
+// featureProbes maps optional features to the boolean setting whose
+// presence indicates support.
+var featureProbes = map[string]string{
+	kef.FeatureVoiceAssistant: voiceAssistantPath,
+	kef.FeatureHeadphones:     headphonesPath,
+	kef.FeatureAutoPowerOn:    autoPowerOnPath,
+}
+
+// polledFeatures are the optional features whose state is refreshed on
+// every poll.
+var polledFeatures = []string{kef.FeatureVoiceAssistant, kef.FeatureHeadphones, kef.FeatureAutoPowerOn}
+
+// probeFeatures checks which optional features the speaker exposes by
+// reading each feature's setting. Most models lack most of them, so a
+// failed read simply marks the feature unsupported.
+func (c *Controller) probeFeatures() {
+	supported := make(map[string]bool, len(featureProbes))
+	for feature, path := range featureProbes {
+		_, err := c.client.GetBool(path)
+		supported[feature] = err == nil
+		slog.Debug("Probed speaker feature", "feature", feature, "supported", err == nil)
+	}
+
+	c.mu.Lock()
+	c.features = supported
+	c.mu.Unlock()
+}
+
+// Supports reports whether the connected speaker supports the feature.
+// Optional features are probed on connect; anything else is looked up in
+// the capability map for the speaker's model (see kef.ModelSupports).
+func (c *Controller) Supports(feature string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, probed := featureProbes[feature]; probed {
+		return c.features[feature]
+	}
+	return kef.ModelSupports(c.state.Model, feature)
+}
+
+// GetVoiceAssistant retrieves whether the voice assistant is enabled.
+func (c *Controller) GetVoiceAssistant() (bool, error) {
+	if !c.Supports(kef.FeatureVoiceAssistant) {
+		return false, ErrUnsupported
+	}
+
+	enabled, err := c.client.GetBool(voiceAssistantPath)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.state.VoiceAssistant = enabled
+	c.mu.Unlock()
+	c.publish()
+
+	return enabled, nil
+}
+
+// SetVoiceAssistant enables or disables the voice assistant.
+func (c *Controller) SetVoiceAssistant(enabled bool) error {
+	if !c.Supports(kef.FeatureVoiceAssistant) {
+		return ErrUnsupported
+	}
+
+	if err := c.client.SetBool(voiceAssistantPath, enabled); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.state.VoiceAssistant = enabled
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
+
+// GetHeadphoneState retrieves whether headphones are plugged in. On models
+// with a jack, this routes audio away from the speakers.
+func (c *Controller) GetHeadphoneState() (bool, error) {
+	if !c.Supports(kef.FeatureHeadphones) {
+		return false, ErrUnsupported
+	}
+
+	connected, err := c.client.GetBool(headphonesPath)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.state.Headphones = connected
+	c.mu.Unlock()
+	c.publish()
+
+	return connected, nil
+}
+
+// GetAutoPowerOn retrieves whether the speaker wakes from standby when it
+// detects a signal on an input.
+func (c *Controller) GetAutoPowerOn() (bool, error) {
+	if !c.Supports(kef.FeatureAutoPowerOn) {
+		return false, ErrUnsupported
+	}
+
+	enabled, err := c.client.GetBool(autoPowerOnPath)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.state.AutoPowerOn = enabled
+	c.mu.Unlock()
+	c.publish()
+
+	return enabled, nil
+}
+
+// SetAutoPowerOn enables or disables waking on signal, e.g. to stop noise on
+// the optical input from turning the speaker on.
+func (c *Controller) SetAutoPowerOn(enabled bool) error {
+	if !c.Supports(kef.FeatureAutoPowerOn) {
+		return ErrUnsupported
+	}
+
+	if err := c.client.SetBool(autoPowerOnPath, enabled); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.state.AutoPowerOn = enabled
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
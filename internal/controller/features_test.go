@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// boolValue is a boolean setting as the speaker reports it.
+func boolValue(b bool) string {
+	return fmt.Sprintf(`{"type":"bool_","bool_":%t}`, b)
+}
+
+func TestAutoPowerOn(t *testing.T) {
+	speaker := newFakeSpeaker()
+	speaker.set(autoPowerOnPath, boolValue(true))
+	c := newTestController(t, speaker)
+	c.probeFeatures()
+
+	if got, err := c.GetAutoPowerOn(); err != nil || !got {
+		t.Fatalf("GetAutoPowerOn() = %t, %v; want true", got, err)
+	}
+	if !c.GetState().AutoPowerOn {
+		t.Error("GetAutoPowerOn() didn't update the state")
+	}
+
+	if err := c.SetAutoPowerOn(false); err != nil {
+		t.Fatalf("SetAutoPowerOn(false) error = %v", err)
+	}
+	if writes, want := speaker.writesTo(autoPowerOnPath), []string{boolValue(false)}; !slices.Equal(writes, want) {
+		t.Errorf("SetAutoPowerOn(false) wrote %v, want %v", writes, want)
+	}
+	if c.GetState().AutoPowerOn {
+		t.Error("SetAutoPowerOn(false) didn't update the state")
+	}
+	if got, err := c.GetAutoPowerOn(); err != nil || got {
+		t.Errorf("GetAutoPowerOn() after disabling = %t, %v; want false", got, err)
+	}
+
+	// A value of the wrong type is an error, not a guess
+	speaker.set(autoPowerOnPath, `{"type":"i32_","i32_":1}`)
+	if _, err := c.GetAutoPowerOn(); err == nil {
+		t.Error("GetAutoPowerOn() of an i32_ value succeeded")
+	}
+}
+
+func TestAutoPowerOnUnsupported(t *testing.T) {
+	speaker := newFakeSpeaker() // No autoPowerOn setting
+	c := newTestController(t, speaker)
+	c.probeFeatures()
+
+	if _, err := c.GetAutoPowerOn(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetAutoPowerOn() error = %v, want ErrUnsupported", err)
+	}
+	if err := c.SetAutoPowerOn(true); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetAutoPowerOn() error = %v, want ErrUnsupported", err)
+	}
+	if writes := speaker.writesTo(autoPowerOnPath); len(writes) != 0 {
+		t.Errorf("SetAutoPowerOn() on an unsupported speaker wrote %v", writes)
+	}
+}
+
+func TestGetHeadphoneState(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{name: "unplugged", value: boolValue(false), want: false},
+		{name: "plugged in", value: boolValue(true), want: true},
+		{name: "wrong type", value: `{"type":"string_","string_":"true"}`, wantErr: true},
+		{name: "unavailable", value: `null`, wantErr: true},
+	}
+
+	speaker := newFakeSpeaker()
+	speaker.set(headphonesPath, boolValue(false))
+	c := newTestController(t, speaker)
+	c.probeFeatures()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speaker.set(headphonesPath, tt.value)
+			before := c.GetState().Headphones
+
+			got, err := c.GetHeadphoneState()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetHeadphoneState() = %t, want an error", got)
+				}
+				if c.GetState().Headphones != before {
+					t.Error("GetHeadphoneState() changed the state on an error")
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("GetHeadphoneState() = %t, %v; want %t", got, err, tt.want)
+			}
+			if c.GetState().Headphones != tt.want {
+				t.Errorf("state headphones = %t, want %t", c.GetState().Headphones, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHeadphoneStateUnsupported(t *testing.T) {
+	c := newTestController(t, newFakeSpeaker())
+	c.probeFeatures()
+
+	if _, err := c.GetHeadphoneState(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetHeadphoneState() error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestVoiceAssistant(t *testing.T) {
+	speaker := newFakeSpeaker()
+	speaker.set(voiceAssistantPath, boolValue(false))
+	c := newTestController(t, speaker)
+	c.probeFeatures()
+
+	if !c.Supports(kef.FeatureVoiceAssistant) {
+		t.Fatal("voice assistant not detected")
+	}
+	if got, err := c.GetVoiceAssistant(); err != nil || got {
+		t.Fatalf("GetVoiceAssistant() = %t, %v; want false", got, err)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		if err := c.SetVoiceAssistant(enabled); err != nil {
+			t.Fatalf("SetVoiceAssistant(%t) error = %v", enabled, err)
+		}
+		if got := c.GetState().VoiceAssistant; got != enabled {
+			t.Errorf("state after SetVoiceAssistant(%t) = %t", enabled, got)
+		}
+	}
+	if writes, want := speaker.writesTo(voiceAssistantPath), []string{boolValue(true), boolValue(false)}; !slices.Equal(writes, want) {
+		t.Errorf("voice assistant writes = %v, want %v", writes, want)
+	}
+}
+
+func TestVoiceAssistantUnsupported(t *testing.T) {
+	speaker := newFakeSpeaker() // No voiceAssistant setting
+	c := newTestController(t, speaker)
+	c.probeFeatures()
+
+	if c.Supports(kef.FeatureVoiceAssistant) {
+		t.Fatal("voice assistant detected on a speaker without it")
+	}
+	if _, err := c.GetVoiceAssistant(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetVoiceAssistant() error = %v, want ErrUnsupported", err)
+	}
+	if err := c.SetVoiceAssistant(true); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetVoiceAssistant() error = %v, want ErrUnsupported", err)
+	}
+	if writes := speaker.writesTo(voiceAssistantPath); len(writes) != 0 {
+		t.Errorf("SetVoiceAssistant() on an unsupported speaker wrote %v", writes)
+	}
+}
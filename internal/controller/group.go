@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/inquire/kefbar-go/internal/api"
+)
+
+// SetVolumeAll sets the volume of every configured speaker profile, and of
+// the current speaker if it has no profile, at once. Speakers are set
+// concurrently, each bounded by its own request timeout, so an unreachable
+// one doesn't hold up the rest. It returns each speaker's result keyed by
+// IP, along with the failures joined.
+func (c *Controller) SetVolumeAll(ctx context.Context, level int) (map[string]error, error) {
+	c.mu.RLock()
+	current := c.state.IPAddress
+	c.mu.RUnlock()
+
+	var ips []string
+	seen := make(map[string]bool)
+	for _, ip := range append([]string{current}, c.profileIPs()...) {
+		if ip != "" && !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no speakers configured")
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(ips))
+	)
+	for _, ip := range ips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+			defer cancel()
+
+			var err error
+			if ip == current {
+				err = c.SetVolumeContext(ctx, level)
+			} else {
+				err = c.setRemoteVolume(ctx, ip, level)
+			}
+
+			mu.Lock()
+			results[ip] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, ip := range ips {
+		if err := results[ip]; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.cfg.SpeakerName(ip), err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// profileIPs returns the IPs of the configured speaker profiles.
+func (c *Controller) profileIPs() []string {
+	ips := make([]string, 0, len(c.cfg.Speakers))
+	for _, profile := range c.cfg.Speakers {
+		ips = append(ips, profile.IP)
+	}
+	return ips
+}
+
+// setRemoteVolume sets the volume of the speaker at ip, other than the
+// current one, through a client of its own. Only the configured limit
+// applies; the speaker enforces its own.
+func (c *Controller) setRemoteVolume(ctx context.Context, ip string, level int) error {
+	client := api.NewClient(ip, c.cfg.Port, c.cfg.Timeout, c.clientOpts...)
+	if c.cfg.Scheme != "" {
+		if err := client.SetScheme(c.cfg.Scheme); err != nil {
+			return err
+		}
+	}
+
+	level = max(0, min(level, c.cfg.VolumeLimit()))
+	return client.SetIntContext(ctx, volumePath, level)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/internal/config"
+)
+
+// hostRouter sends each request to the test server standing in for its
+// host, so several speakers can share the configured port.
+type hostRouter map[string]*httptest.Server
+
+func (r hostRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	server, ok := r[req.URL.Hostname()]
+	if !ok {
+		return nil, errors.New("no speaker at " + req.URL.Hostname())
+	}
+	req = req.Clone(req.Context())
+	req.URL.Host = server.Listener.Addr().String()
+	return server.Client().Transport.RoundTrip(req)
+}
+
+func TestSetVolumeAllWithStalledSpeaker(t *testing.T) {
+	const (
+		healthyIP = "192.0.2.1"
+		stalledIP = "192.0.2.2"
+	)
+
+	var healthyAt atomic.Int64 // when the healthy speaker got the volume, in Unix ns
+	healthy := newFakeSpeaker()
+	healthyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/setData" && r.URL.Query().Get("path") == volumePath {
+			healthyAt.Store(time.Now().UnixNano())
+		}
+		healthy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(healthyServer.Close)
+
+	stalled := newStallingSpeaker()
+	stalled.stall()
+	stalledServer := httptest.NewServer(stalled)
+	t.Cleanup(stalledServer.Close)
+	t.Cleanup(stalled.release)
+
+	cfg := config.New()
+	cfg.SpeakerIP = healthyIP
+	cfg.Speakers = []config.SpeakerProfile{{Name: "Kitchen", IP: stalledIP}}
+	cfg.WriteIntervalMs = 0
+	cfg.Timeout = 500 * time.Millisecond
+
+	c := New(cfg, api.WithTransport(hostRouter{healthyIP: healthyServer, stalledIP: stalledServer}))
+	c.SetIP(healthyIP)
+	t.Cleanup(c.Close)
+
+	start := time.Now()
+	results, err := c.SetVolumeAll(context.Background(), 30)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Error("SetVolumeAll() with a stalled speaker succeeded")
+	}
+	if len(results) != 2 {
+		t.Fatalf("SetVolumeAll() returned %d results, want 2: %v", len(results), results)
+	}
+	if err, ok := results[healthyIP]; !ok || err != nil {
+		t.Errorf("healthy speaker result = %v (present %t), want nil", err, ok)
+	}
+	if err := results[stalledIP]; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("stalled speaker result = %v, want a deadline error", err)
+	}
+
+	// The stalled speaker mustn't hold up the healthy one
+	if at := time.Unix(0, healthyAt.Load()).Sub(start); at < 0 || at > cfg.Timeout/2 {
+		t.Errorf("healthy speaker set after %v, want well within the %v timeout", at, cfg.Timeout)
+	}
+	if got := c.GetState().Volume; got != 30 {
+		t.Errorf("current speaker volume = %d, want 30", got)
+	}
+	// Nor the call as a whole for longer than its own timeout
+	if elapsed > 3*cfg.Timeout {
+		t.Errorf("SetVolumeAll() took %v, want it bounded by the %v timeout", elapsed, cfg.Timeout)
+	}
+}
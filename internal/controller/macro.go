@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/inquire/kefbar-go/internal/config"
+)
+
+// RunMacro runs the named macro's steps in order. Failed steps are collected
+// into the returned error; unless the macro is set to stop on error, the
+// remaining steps still run.
+func (c *Controller) RunMacro(name string) error {
+	macro, ok := c.cfg.Macro(name)
+	if !ok {
+		return fmt.Errorf("unknown macro %q", name)
+	}
+
+	slog.Info("Running macro", "macro", name, "steps", len(macro.Steps))
+	return runSteps(macro, c.runMacroStep)
+}
+
+// runSteps runs each step of macro with run, aggregating errors.
+func runSteps(macro config.Macro, run func(config.MacroStep) error) error {
+	var errs []error
+	for i, step := range macro.Steps {
+		if err := run(step); err != nil {
+			errs = append(errs, fmt.Errorf("step %d (%s): %w", i+1, step.Action, err))
+			if macro.StopOnError {
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runMacroStep performs a single macro action.
+func (c *Controller) runMacroStep(step config.MacroStep) error {
+	switch step.Action {
+	case config.MacroActionVolume:
+		level, err := strconv.Atoi(step.Value)
+		if err != nil {
+			return fmt.Errorf("invalid volume %q", step.Value)
+		}
+		return c.SetVolume(level)
+	case config.MacroActionMute:
+		muted, err := strconv.ParseBool(step.Value)
+		if err != nil {
+			return fmt.Errorf("invalid mute value %q", step.Value)
+		}
+		return c.SetMute(muted)
+	case config.MacroActionSource:
+		return c.SetSource(step.Value)
+	case config.MacroActionPlayPause:
+		return c.PlayPause()
+	case config.MacroActionNext:
+		return c.NextTrack()
+	case config.MacroActionPrevious:
+		return c.PreviousTrack()
+	case config.MacroActionBassExtension:
+		return c.SetBassExtension(step.Value)
+	case config.MacroActionTreble:
+		db, err := strconv.ParseFloat(step.Value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid treble %q", step.Value)
+		}
+		return c.SetTreble(db)
+	case config.MacroActionBalance:
+		balance, err := strconv.Atoi(step.Value)
+		if err != nil {
+			return fmt.Errorf("invalid balance %q", step.Value)
+		}
+		return c.SetBalance(balance)
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/inquire/kefbar-go/internal/config"
+)
+
+func TestRunSteps(t *testing.T) {
+	errBusy := errors.New("busy")
+	errOffline := errors.New("offline")
+	steps := []config.MacroStep{
+		{Action: config.MacroActionSource, Value: "optic"},
+		{Action: config.MacroActionVolume, Value: "35"},
+		{Action: config.MacroActionMute, Value: "false"},
+		{Action: config.MacroActionPlayPause},
+	}
+	failing := map[string]error{
+		config.MacroActionVolume:    errBusy,
+		config.MacroActionPlayPause: errOffline,
+	}
+
+	tests := []struct {
+		name        string
+		stopOnError bool
+		wantRan     []string
+		wantErrs    []error
+	}{
+		{
+			name:     "continue on error",
+			wantRan:  []string{"source", "volume", "mute", "play_pause"},
+			wantErrs: []error{errBusy, errOffline},
+		},
+		{
+			name:        "stop on error",
+			stopOnError: true,
+			wantRan:     []string{"source", "volume"},
+			wantErrs:    []error{errBusy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			err := runSteps(config.Macro{Steps: steps, StopOnError: tt.stopOnError}, func(step config.MacroStep) error {
+				ran = append(ran, step.Action)
+				return failing[step.Action]
+			})
+
+			if !slices.Equal(ran, tt.wantRan) {
+				t.Errorf("ran %v, want %v", ran, tt.wantRan)
+			}
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("runSteps() error = %v, want it to include %v", err, want)
+				}
+			}
+			if errors.Is(err, errOffline) && tt.stopOnError {
+				t.Errorf("runSteps() error = %v, includes a step after the stop", err)
+			}
+		})
+	}
+}
+
+func TestRunStepsErrorNamesStep(t *testing.T) {
+	macro := config.Macro{Steps: []config.MacroStep{
+		{Action: config.MacroActionNext},
+		{Action: config.MacroActionTreble, Value: "loud"},
+	}}
+	err := runSteps(macro, func(step config.MacroStep) error {
+		if step.Action == config.MacroActionTreble {
+			return errors.New("invalid treble")
+		}
+		return nil
+	})
+
+	if err == nil || err.Error() != "step 2 (treble): invalid treble" {
+		t.Errorf("runSteps() error = %v, want it to name step 2", err)
+	}
+}
+
+func TestRunStepsSuccess(t *testing.T) {
+	macro := config.Macro{Steps: []config.MacroStep{{Action: config.MacroActionNext}}}
+	if err := runSteps(macro, func(config.MacroStep) error { return nil }); err != nil {
+		t.Errorf("runSteps() error = %v", err)
+	}
+}
+
+func TestRunMacro(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+	c.cfg.Macros = []config.Macro{{
+		Name: "Quiet",
+		Steps: []config.MacroStep{
+			{Action: config.MacroActionVolume, Value: "loud"},
+			{Action: config.MacroActionVolume, Value: "20"},
+			{Action: "dance"},
+		},
+	}}
+
+	err := c.RunMacro("Quiet")
+	for _, want := range []string{`step 1 (volume): invalid volume "loud"`, `step 3 (dance): unknown action "dance"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("RunMacro() error = %v, want it to include %q", err, want)
+		}
+	}
+	if writes := speaker.writesTo(volumePath); !slices.Equal(writes, []string{volumeValue(20)}) {
+		t.Errorf("volume writes = %v, want the valid step only", writes)
+	}
+
+	if err := c.RunMacro("Loud"); err == nil {
+		t.Error("RunMacro() of an unknown macro succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"encoding/json"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// TestGetStateWhilePlaybackUpdates is meant for -race: GetState's copy of
+// PlaybackInfo must not share memory with the one GetPlaybackInfo replaces.
+func TestGetStateWhilePlaybackUpdates(t *testing.T) {
+	c := newTestController(t, newFakeSpeaker())
+	if _, err := c.GetPlaybackInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if info := c.GetState().PlaybackInfo; info != nil {
+				// Reading and writing the copy must be safe
+				_ = info.Title + info.Artist
+				info.Position++
+			}
+			runtime.Gosched()
+		}
+	}()
+
+	for range 20 {
+		if _, err := c.GetPlaybackInfo(); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if info := c.GetState().PlaybackInfo; info == nil || info.Position != 0 {
+		t.Errorf("controller playback info = %+v, want it untouched by callers", info)
+	}
+}
+
+// playerData decodes a player:player/data value.
+func playerData(t *testing.T, value string) map[string]interface{} {
+	t.Helper()
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParsePlaybackInfo(t *testing.T) {
+	info := parsePlaybackInfo(playerData(t, playbackPayload))
+
+	if info.State != "playing" || info.Title != "Windowlicker" || info.Artist != "Aphex Twin" ||
+		info.Album != "Windowlicker EP" || info.Duration != 254000 ||
+		info.AlbumArt != "http://i.scdn.co/image/ab67616d0000b273" {
+		t.Errorf("parsePlaybackInfo() = %+v", info)
+	}
+	// The speaker's zero-based index 3 is track 4
+	if info.QueueIndex != 4 || info.QueueLength != 12 {
+		t.Errorf("queue position %d of %d, want 4 of 12", info.QueueIndex, info.QueueLength)
+	}
+}
+
+func TestParsePlaybackInfoWithoutQueue(t *testing.T) {
+	// Radio and line inputs report no queue
+	info := parsePlaybackInfo(playerData(t, `{
+		"state": "playing",
+		"status": {"duration": 0},
+		"trackRoles": {"title": "BBC Radio 6 Music"}
+	}`))
+
+	if info.State != "playing" || info.Title != "BBC Radio 6 Music" {
+		t.Errorf("parsePlaybackInfo() = %+v", info)
+	}
+	if info.QueueIndex != 0 || info.QueueLength != 0 {
+		t.Errorf("queue position %d of %d, want none", info.QueueIndex, info.QueueLength)
+	}
+
+	if info := parsePlaybackInfo(map[string]interface{}{}); *info != (kef.PlaybackInfo{}) {
+		t.Errorf("parsePlaybackInfo() of an empty value = %+v, want nothing set", info)
+	}
+}
+
+func TestParseQueuePosition(t *testing.T) {
+	tests := []struct {
+		name                  string
+		data                  string
+		wantIndex, wantLength int
+	}{
+		{"in status", `{"status": {"queueIndex": 0, "queueLength": 3}}`, 1, 3},
+		{"in trackRoles", `{"trackRoles": {"queueIndex": 2, "queueLength": 3}}`, 3, 3},
+		{"status first", `{"status": {"queueIndex": 1, "queueLength": 5}, "trackRoles": {"queueIndex": 4, "queueLength": 9}}`, 2, 5},
+		{"invalid status falls back", `{"status": {"queueIndex": 5, "queueLength": 5}, "trackRoles": {"queueIndex": 4, "queueLength": 9}}`, 5, 9},
+		{"no queue", `{"status": {"duration": 1000}}`, 0, 0},
+		{"empty queue", `{"status": {"queueIndex": 0, "queueLength": 0}}`, 0, 0},
+		{"index past the end", `{"status": {"queueIndex": 3, "queueLength": 3}}`, 0, 0},
+		{"negative index", `{"status": {"queueIndex": -1, "queueLength": 3}}`, 0, 0},
+		{"length missing", `{"status": {"queueIndex": 1}}`, 0, 0},
+		{"not numbers", `{"status": {"queueIndex": "1", "queueLength": "3"}}`, 0, 0},
+	}
+
+	for _, tt := range tests {
+		index, length := parseQueuePosition(playerData(t, tt.data))
+		if index != tt.wantIndex || length != tt.wantLength {
+			t.Errorf("%s: parseQueuePosition() = %d, %d; want %d, %d", tt.name, index, length, tt.wantIndex, tt.wantLength)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// Play mode setting. The speaker combines shuffle and repeat into one
+// value, see playModes.
+const (
+	playModePath = "settings:/mediaPlayer/playMode"
+	playModeType = "playerPlayMode"
+)
+
+// playMode is shuffle and repeat as set together.
+type playMode struct {
+	shuffle bool
+	repeat  string
+}
+
+// playModes maps the speaker's play mode values to shuffle and repeat.
+var playModes = map[string]playMode{
+	"normal":           {false, kef.RepeatOff},
+	"repeatOne":        {false, kef.RepeatOne},
+	"repeatAll":        {false, kef.RepeatAll},
+	"shuffle":          {true, kef.RepeatOff},
+	"shuffleRepeatOne": {true, kef.RepeatOne},
+	"shuffleRepeatAll": {true, kef.RepeatAll},
+}
+
+// GetPlayMode reads whether shuffle is on and the repeat mode.
+func (c *Controller) GetPlayMode() (shuffle bool, repeat string, err error) {
+	value, err := c.client.GetTypedString(playModePath, playModeType)
+	if err != nil {
+		return false, "", err
+	}
+
+	mode, err := parsePlayMode(value)
+	if err != nil {
+		return false, "", err
+	}
+
+	c.mu.Lock()
+	c.setPlayModeLocked(mode)
+	c.mu.Unlock()
+	c.publish()
+
+	return mode.shuffle, mode.repeat, nil
+}
+
+// SetShuffle turns shuffle on or off, keeping the repeat mode.
+func (c *Controller) SetShuffle(shuffle bool) error {
+	_, repeat, err := c.GetPlayMode()
+	if err != nil {
+		return err
+	}
+	return c.setPlayMode(playMode{shuffle: shuffle, repeat: repeat})
+}
+
+// SetRepeat sets the repeat mode (one of the kef.Repeat* modes), keeping
+// shuffle.
+func (c *Controller) SetRepeat(repeat string) error {
+	if !slices.Contains(kef.RepeatModes, repeat) {
+		return fmt.Errorf("unknown repeat mode %q", repeat)
+	}
+
+	shuffle, _, err := c.GetPlayMode()
+	if err != nil {
+		return err
+	}
+	return c.setPlayMode(playMode{shuffle: shuffle, repeat: repeat})
+}
+
+// setPlayMode writes mode to the speaker.
+func (c *Controller) setPlayMode(mode playMode) error {
+	var value string
+	for v, m := range playModes {
+		if m == mode {
+			value = v
+		}
+	}
+
+	if err := c.client.SetTypedString(playModePath, playModeType, value); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.setPlayModeLocked(mode)
+	c.mu.Unlock()
+	c.publish()
+
+	return nil
+}
+
+// setPlayModeLocked records mode and applies it to the current playback
+// info. c.mu must be held.
+func (c *Controller) setPlayModeLocked(mode playMode) {
+	c.playMode = mode
+	if c.state.PlaybackInfo != nil {
+		// The stored info may have been handed out; replace rather than edit
+		info := *c.state.PlaybackInfo
+		info.Shuffle, info.Repeat = mode.shuffle, mode.repeat
+		c.state.PlaybackInfo = &info
+	}
+}
+
+// parsePlayMode converts a speaker play mode value.
+func parsePlayMode(value string) (playMode, error) {
+	mode, ok := playModes[value]
+	if !ok {
+		return playMode{}, fmt.Errorf("unknown play mode %q", value)
+	}
+	return mode, nil
+}
+
+// applyPlayModeLocked stores a polled play mode value. c.mu must be held.
+func (c *Controller) applyPlayModeLocked(v map[string]interface{}) {
+	value, err := api.TypedStringValue(v, playModeType)
+	if err == nil {
+		var mode playMode
+		if mode, err = parsePlayMode(value); err == nil {
+			c.setPlayModeLocked(mode)
+		}
+	}
+	logPollError("play mode", err)
+}
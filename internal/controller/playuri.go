@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// PlayURI plays the audio stream at uri, e.g. an internet radio station,
+// switching to the WiFi input first if needed. Only http and https URIs are
+// accepted; if the speaker can't play one, its error is returned.
+func (c *Controller) PlayURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid stream URI %q: want an http or https URL", uri)
+	}
+
+	if c.GetState().Source != kef.SourceWifi {
+		if err := c.SetSource(kef.SourceWifi); err != nil {
+			return fmt.Errorf("switch to wifi: %w", err)
+		}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"control": "play",
+		"mediaRoles": map[string]interface{}{
+			"type":      "audio",
+			"mediaData": map[string]interface{}{"resources": []map[string]string{{"uri": uri}}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.SetData("player:player/control", "activate", string(payload)); err != nil {
+		return fmt.Errorf("play %s: %w", uri, err)
+	}
+	slog.Info("Playing stream", "uri", uri)
+
+	// Refresh playback info after a delay
+	safe.Go("playback refresh", func() {
+		time.Sleep(500 * time.Millisecond)
+		_, _ = c.GetPlaybackInfo()
+	})
+
+	return nil
+}
+
+// PlayPreset plays the configured play preset with the given name.
+func (c *Controller) PlayPreset(name string) error {
+	preset, ok := c.cfg.PlayPreset(name)
+	if !ok {
+		return fmt.Errorf("unknown preset %q", name)
+	}
+	return c.PlayURI(preset.URI)
+}
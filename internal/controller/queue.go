@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// queuePath lists the tracks in the play queue.
+const queuePath = "playlists:pq/getitems"
+
+// maxQueueRows bounds how much of a long queue GetQueue reads.
+const maxQueueRows = 100
+
+// GetQueue returns the tracks in the play queue, at most maxQueueRows of
+// them. Sources without a queue, such as TV or radio, give an empty list.
+func (c *Controller) GetQueue() ([]kef.QueueItem, error) {
+	state := c.GetState()
+	if !kef.IsStreamingSource(state.Source) || state.PlaybackInfo == nil || state.PlaybackInfo.QueueLength == 0 {
+		return []kef.QueueItem{}, nil
+	}
+
+	rows, err := c.client.GetRows(queuePath, 0, min(state.PlaybackInfo.QueueLength, maxQueueRows))
+	if err != nil {
+		return nil, fmt.Errorf("queue: %w", err)
+	}
+
+	items := make([]kef.QueueItem, 0, len(rows.Rows))
+	for _, row := range rows.Rows {
+		items = append(items, parseQueueItem(row))
+	}
+	return items, nil
+}
+
+// parseQueueItem extracts a track from a row of the queue, which carries
+// its metadata as trackRoles does in player:player/data.
+func parseQueueItem(row map[string]interface{}) kef.QueueItem {
+	var item kef.QueueItem
+	if title, ok := row["title"].(string); ok {
+		item.Title = title
+	}
+
+	mediaData, _ := row["mediaData"].(map[string]interface{})
+	if metaData, ok := mediaData["metaData"].(map[string]interface{}); ok {
+		if artist, ok := metaData["artist"].(string); ok {
+			item.Artist = artist
+		}
+		if album, ok := metaData["album"].(string); ok {
+			item.Album = album
+		}
+	}
+
+	// The duration is on the track's first resource
+	if resources, ok := mediaData["resources"].([]interface{}); ok && len(resources) > 0 {
+		if resource, ok := resources[0].(map[string]interface{}); ok {
+			if duration, ok := resource["duration"].(float64); ok {
+				item.Duration = int(duration)
+			}
+		}
+	}
+
+	return item
+}
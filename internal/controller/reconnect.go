@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/safe"
+)
+
+// reconnectMinDelay is the first retry delay after losing the speaker. It
+// doubles on each failed attempt, up to maxPollBackoff.
+const reconnectMinDelay = 2 * time.Second
+
+// SetAutoReconnect enables or disables reconnecting in the background while
+// an IP is set but the speaker isn't connected, e.g. after it went to
+// standby or the Mac slept. Only one reconnect loop ever runs; it stops
+// when the controller is closed.
+func (c *Controller) SetAutoReconnect(enabled bool) {
+	c.autoReconnect.Store(enabled)
+	if enabled {
+		c.reconnectOnce.Do(func() {
+			safe.GoRestart("auto reconnect", c.reconnectLoop)
+		})
+	}
+}
+
+// reconnectLoop retries Connect with backoff whenever auto reconnect is
+// enabled and the speaker is disconnected.
+func (c *Controller) reconnectLoop() {
+	delay := reconnectMinDelay
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		c.mu.RLock()
+		needed := c.state.IPAddress != "" && !c.state.Connected
+		c.mu.RUnlock()
+
+		if !needed || !c.autoReconnect.Load() {
+			delay = reconnectMinDelay
+			timer.Reset(delay)
+			continue
+		}
+
+		attempted, err := c.reconnect()
+		switch {
+		case !attempted:
+			// Another connect got there first; see how it went next time
+			delay = reconnectMinDelay
+		case err == nil:
+			slog.Info("Reconnected to speaker")
+			delay = reconnectMinDelay
+		default:
+			slog.Debug("Reconnect attempt failed", "retry_in", delay)
+			delay = min(delay*2, maxPollBackoff)
+		}
+		timer.Reset(delay)
+	}
+}
+
+// reconnect connects unless a connect is already under way or has just
+// succeeded, and reports whether it tried.
+func (c *Controller) reconnect() (attempted bool, err error) {
+	if !c.connectMu.TryLock() {
+		return false, nil
+	}
+	defer c.connectMu.Unlock()
+
+	c.mu.RLock()
+	connected := c.state.Connected
+	c.mu.RUnlock()
+	if connected {
+		return false, nil
+	}
+
+	return true, c.connect()
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"log/slog"
+
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/internal/schedule"
+)
+
+// StartSchedules starts running the configured scheduled macros. Invalid
+// entries are logged and skipped. The scheduler stops when the controller
+// is closed; calling StartSchedules again has no effect.
+func (c *Controller) StartSchedules() {
+	c.scheduleOnce.Do(func() {
+		var entries []schedule.Entry
+		for _, sa := range c.cfg.Schedules {
+			cron, err := schedule.Parse(sa.Cron)
+			if err != nil {
+				slog.Warn("Skipping invalid schedule", "macro", sa.Macro, "error", err)
+				continue
+			}
+			if _, ok := c.cfg.Macro(sa.Macro); !ok {
+				slog.Warn("Skipping schedule for unknown macro", "macro", sa.Macro, "cron", sa.Cron)
+				continue
+			}
+			entries = append(entries, schedule.Entry{Cron: cron, Action: sa.Macro})
+		}
+
+		if len(entries) == 0 {
+			return
+		}
+
+		s := &schedule.Scheduler{
+			Entries: entries,
+			Run:     c.RunMacro,
+			Policy:  c.cfg.MissedSchedulePolicy,
+		}
+		slog.Info("Starting scheduler", "entries", len(entries))
+		safe.GoRestart("scheduler", func() { s.Start(c.ctx) })
+	})
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits for settings tree browsing.
+const (
+	maxBrowseDepth = 4
+	maxBrowseRows  = 200
+)
+
+// BrowseSettings returns a structured view of the settings subtree at prefix
+// (e.g., "settings:/kef/dsp"). Listable nodes become nested maps keyed by
+// child name and leaves map to their decoded value. Nodes the firmware
+// can't list are read as plain values instead.
+func (c *Controller) BrowseSettings(prefix string) (map[string]interface{}, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	node, err := c.browse(prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tree, ok := node.(map[string]interface{}); ok {
+		return tree, nil
+	}
+
+	// The prefix itself is a leaf
+	return map[string]interface{}{lastSegment(prefix): node}, nil
+}
+
+// browse lists path's children, recursing into nodes without an inline
+// value. Leaves and unlistable nodes are read with getData.
+func (c *Controller) browse(path string, depth int) (interface{}, error) {
+	rows, err := c.client.GetRows(path, 0, maxBrowseRows)
+	if err != nil || rows.Count == 0 || len(rows.Rows) == 0 {
+		return c.readSetting(path)
+	}
+
+	tree := make(map[string]interface{}, len(rows.Rows))
+	for _, row := range rows.Rows {
+		childPath, _ := row["path"].(string)
+		if childPath == "" {
+			continue
+		}
+		name := lastSegment(childPath)
+
+		if value, ok := row["value"].(map[string]interface{}); ok {
+			tree[name] = decodeValue(value)
+			continue
+		}
+
+		if depth+1 >= maxBrowseDepth {
+			tree[name] = nil
+			continue
+		}
+
+		child, err := c.browse(childPath, depth+1)
+		if err != nil {
+			// Keep browsing siblings; record the failure in place
+			tree[name] = fmt.Sprintf("<error: %v>", err)
+			continue
+		}
+		tree[name] = child
+	}
+
+	return tree, nil
+}
+
+// readSetting reads a single value with getData.
+func (c *Controller) readSetting(path string) (interface{}, error) {
+	result, err := c.client.GetData(path, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("empty response for %s", path)
+	}
+
+	if value, ok := result[0].(map[string]interface{}); ok {
+		return decodeValue(value), nil
+	}
+	return result[0], nil
+}
+
+// decodeValue unwraps a typed KEF value such as {"type":"i32_","i32_":5}.
+// Values without a matching type key are returned as-is.
+func decodeValue(value map[string]interface{}) interface{} {
+	if t, ok := value["type"].(string); ok {
+		if v, ok := value[t]; ok {
+			return v
+		}
+	}
+	return value
+}
+
+// lastSegment returns the last component of a settings path.
+func lastSegment(path string) string {
+	if i := strings.LastIndexAny(path, "/:"); i >= 0 && i < len(path)-1 {
+		return path[i+1:]
+	}
+	return path
+}
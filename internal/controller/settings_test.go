@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// dspRows are getRows responses for a settings:/kef/dsp subtree, keyed by
+// path. Paths not listed can't be listed.
+var dspRows = map[string]string{
+	"settings:/kef/dsp": `{"rowsCount": 7, "rows": [
+		{"path": "settings:/kef/dsp/bassExtension", "value": {"type": "string_", "string_": "standard"}},
+		{"path": "settings:/kef/dsp/deskMode", "value": {"type": "bool_", "bool_": false}},
+		{"path": "settings:/kef/dsp/v2"},
+		{"path": "settings:/kef/dsp/trebleAmount"},
+		{"path": "settings:/kef/dsp/phaseCorrection"},
+		{"path": "settings:/kef/dsp/broken"},
+		{"title": "no path"}
+	]}`,
+	"settings:/kef/dsp/v2": `{"rowsCount": 1, "rows": [
+		{"path": "settings:/kef/dsp/v2/subwooferGain", "value": {"type": "i32_", "i32_": -2}}
+	]}`,
+	// Listable but empty
+	"settings:/kef/dsp/phaseCorrection": `{"rowsCount": 0, "rows": []}`,
+}
+
+// newSettingsSpeaker returns a fakeSpeaker that also answers getRows from
+// dspRows, holding the values of the leaves that can't be listed.
+func newSettingsSpeaker() http.Handler {
+	speaker := newFakeSpeaker()
+	speaker.set("settings:/kef/dsp/trebleAmount", `{"type":"double_","double_":-1.5}`)
+	speaker.set("settings:/kef/dsp/phaseCorrection", `{"type":"bool_","bool_":true}`)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/getRows" {
+			speaker.ServeHTTP(w, r)
+			return
+		}
+		body, ok := dspRows[r.URL.Query().Get("path")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+func TestBrowseSettings(t *testing.T) {
+	c := newTestController(t, newSettingsSpeaker())
+
+	tree, err := c.BrowseSettings("settings:/kef/dsp/")
+	if err != nil {
+		t.Fatalf("BrowseSettings() error = %v", err)
+	}
+
+	// A leaf that can't be read is recorded in place
+	broken, _ := tree["broken"].(string)
+	if !strings.HasPrefix(broken, "<error: ") {
+		t.Errorf("broken = %v, want an error in place", tree["broken"])
+	}
+	delete(tree, "broken")
+
+	want := map[string]interface{}{
+		"bassExtension":   "standard",
+		"deskMode":        false,
+		"v2":              map[string]interface{}{"subwooferGain": -2.0},
+		"trebleAmount":    -1.5, // Not listable, so read as a value
+		"phaseCorrection": true, // Listed no children, so read as a value
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("BrowseSettings() = %v, want %v", tree, want)
+	}
+}
+
+func TestBrowseSettingsLeaf(t *testing.T) {
+	c := newTestController(t, newSettingsSpeaker())
+
+	tree, err := c.BrowseSettings("settings:/kef/dsp/trebleAmount")
+	if err != nil {
+		t.Fatalf("BrowseSettings() error = %v", err)
+	}
+	if want := map[string]interface{}{"trebleAmount": -1.5}; !reflect.DeepEqual(tree, want) {
+		t.Errorf("BrowseSettings() of a leaf = %v, want %v", tree, want)
+	}
+
+	if _, err := c.BrowseSettings("settings:/kef/missing"); err == nil {
+		t.Error("BrowseSettings() of a missing path succeeded")
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		value map[string]interface{}
+		want  interface{}
+	}{
+		{map[string]interface{}{"type": "i32_", "i32_": 5.0}, 5.0},
+		{map[string]interface{}{"type": "kefPhysicalSource", "kefPhysicalSource": "optic"}, "optic"},
+		{map[string]interface{}{"state": "playing"}, map[string]interface{}{"state": "playing"}},
+		{map[string]interface{}{"type": "i32_"}, map[string]interface{}{"type": "i32_"}},
+	}
+
+	for _, tt := range tests {
+		if got := decodeValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("decodeValue(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
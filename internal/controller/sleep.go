@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// Sleep timer actions, see config.Config.SleepTimerAction.
+const (
+	SleepActionPause   = "pause"   // Pause playback (the default)
+	SleepActionStandby = "standby" // Put the speaker in standby
+)
+
+// sleepTimer is a pending sleep timer. stop is closed when it is cancelled
+// or replaced.
+type sleepTimer struct {
+	at   time.Time
+	stop chan struct{}
+}
+
+// SetSleepTimer pauses playback, or puts the speaker in standby, after d.
+// It replaces any timer already set; zero or less cancels it.
+func (c *Controller) SetSleepTimer(d time.Duration) {
+	if d <= 0 {
+		c.CancelSleepTimer()
+		return
+	}
+
+	t := &sleepTimer{at: time.Now().Add(d), stop: make(chan struct{})}
+
+	c.sleepMu.Lock()
+	if c.sleep != nil {
+		close(c.sleep.stop)
+	}
+	c.sleep = t
+	c.sleepMu.Unlock()
+
+	slog.Info("Sleep timer set", "duration", d, "action", c.sleepAction())
+	safe.Go("sleep timer", func() { c.runSleepTimer(t, d) })
+}
+
+// CancelSleepTimer cancels the sleep timer, if one is set.
+func (c *Controller) CancelSleepTimer() {
+	c.sleepMu.Lock()
+	defer c.sleepMu.Unlock()
+
+	if c.sleep == nil {
+		return
+	}
+	close(c.sleep.stop)
+	c.sleep = nil
+	slog.Info("Sleep timer cancelled")
+}
+
+// SleepTimerRemaining returns the time left on the sleep timer, or zero if
+// none is set.
+func (c *Controller) SleepTimerRemaining() time.Duration {
+	c.sleepMu.Lock()
+	defer c.sleepMu.Unlock()
+
+	if c.sleep == nil {
+		return 0
+	}
+	return max(time.Until(c.sleep.at), 0)
+}
+
+// runSleepTimer waits out t and then runs the sleep action, unless t is
+// cancelled or replaced first.
+func (c *Controller) runSleepTimer(t *sleepTimer, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-t.stop:
+		return
+	case <-c.ctx.Done():
+		return
+	}
+
+	c.sleepMu.Lock()
+	current := c.sleep == t
+	if current {
+		c.sleep = nil
+	}
+	c.sleepMu.Unlock()
+
+	if current {
+		c.sleepNow()
+	}
+}
+
+// sleepNow runs the configured sleep action.
+func (c *Controller) sleepNow() {
+	action := c.sleepAction()
+	slog.Info("Sleep timer ended", "action", action)
+
+	if action == SleepActionStandby {
+		if err := c.SetSource(kef.SourceStandby); err != nil {
+			slog.Warn("Sleep timer could not put the speaker in standby", "error", err)
+		}
+		return
+	}
+
+	// PlayPause toggles, so only pause what is actually playing
+	if _, err := c.GetPlaybackInfo(); err != nil {
+		slog.Warn("Sleep timer could not read playback state", "error", err)
+		return
+	}
+	if !c.IsPlaying() {
+		return
+	}
+	if err := c.PlayPause(); err != nil {
+		slog.Warn("Sleep timer could not pause playback", "error", err)
+	}
+}
+
+// sleepAction returns the configured sleep action, defaulting to pause.
+func (c *Controller) sleepAction() string {
+	if c.cfg.SleepTimerAction == SleepActionStandby {
+		return SleepActionStandby
+	}
+	return SleepActionPause
+}
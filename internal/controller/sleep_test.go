@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+const playerControlPath = "player:player/control"
+
+// newSleepController returns a controller whose sleep timer puts speaker
+// in standby.
+func newSleepController(t *testing.T, speaker *fakeSpeaker) *Controller {
+	t.Helper()
+
+	c := newTestController(t, speaker)
+	c.cfg.SleepTimerAction = SleepActionStandby
+	return c
+}
+
+// waitForWrites waits until path has been written n times.
+func waitForWrites(t *testing.T, speaker *fakeSpeaker, path string, n int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		writes := speaker.writesTo(path)
+		if len(writes) >= n {
+			return writes
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d writes to %s, want %d", len(writes), path, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSleepTimerFires(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newSleepController(t, speaker)
+
+	c.SetSleepTimer(20 * time.Millisecond)
+
+	writes := waitForWrites(t, speaker, sourcePath, 1)
+	if want := []string{sourceValue(kef.SourceStandby)}; !slices.Equal(writes, want) {
+		t.Errorf("source writes = %v, want %v", writes, want)
+	}
+	if got := c.SleepTimerRemaining(); got != 0 {
+		t.Errorf("SleepTimerRemaining() after firing = %v, want 0", got)
+	}
+}
+
+func TestSleepTimerReplaced(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newSleepController(t, speaker)
+
+	c.SetSleepTimer(20 * time.Millisecond)
+	c.SetSleepTimer(time.Hour)
+	t.Cleanup(c.CancelSleepTimer)
+
+	time.Sleep(100 * time.Millisecond)
+	if writes := speaker.writesTo(sourcePath); len(writes) != 0 {
+		t.Errorf("replaced timer fired: source writes = %v", writes)
+	}
+	if got := c.SleepTimerRemaining(); got < 59*time.Minute {
+		t.Errorf("SleepTimerRemaining() = %v, want the replacement's hour", got)
+	}
+}
+
+func TestCancelSleepTimer(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newSleepController(t, speaker)
+
+	c.SetSleepTimer(20 * time.Millisecond)
+	c.CancelSleepTimer()
+
+	time.Sleep(100 * time.Millisecond)
+	if writes := speaker.writesTo(sourcePath); len(writes) != 0 {
+		t.Errorf("cancelled timer fired: source writes = %v", writes)
+	}
+	if got := c.SleepTimerRemaining(); got != 0 {
+		t.Errorf("SleepTimerRemaining() after cancel = %v, want 0", got)
+	}
+
+	// Zero cancels too
+	c.SetSleepTimer(time.Hour)
+	c.SetSleepTimer(0)
+	if got := c.SleepTimerRemaining(); got != 0 {
+		t.Errorf("SleepTimerRemaining() after SetSleepTimer(0) = %v, want 0", got)
+	}
+}
+
+func TestSleepTimerRemainingCountsDown(t *testing.T) {
+	c := newSleepController(t, newFakeSpeaker())
+
+	c.SetSleepTimer(time.Hour)
+	t.Cleanup(c.CancelSleepTimer)
+
+	first := c.SleepTimerRemaining()
+	time.Sleep(20 * time.Millisecond)
+	second := c.SleepTimerRemaining()
+
+	if first > time.Hour || first < 59*time.Minute {
+		t.Errorf("SleepTimerRemaining() = %v, want just under an hour", first)
+	}
+	if second >= first {
+		t.Errorf("SleepTimerRemaining() went from %v to %v, want it counting down", first, second)
+	}
+}
+
+func TestSleepNow(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		state     string
+		source    []string // writes to the source
+		transport []string // writes to the player control
+	}{
+		{"standby", SleepActionStandby, "playing", []string{sourceValue(kef.SourceStandby)}, nil},
+		{"pause while playing", SleepActionPause, "playing", nil, []string{`{"control":"pause"}`}},
+		// The pause control toggles, so it would start paused playback
+		{"pause while paused", SleepActionPause, "paused", nil, nil},
+		{"pause while stopped", SleepActionPause, "stopped", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speaker := newFakeSpeaker()
+			speaker.set(playerDataPath, `{"state":"`+tt.state+`"}`)
+			c := newTestController(t, speaker)
+			c.cfg.SleepTimerAction = tt.action
+
+			c.sleepNow()
+
+			if got := speaker.writesTo(sourcePath); !slices.Equal(got, tt.source) {
+				t.Errorf("source writes = %v, want %v", got, tt.source)
+			}
+			if got := speaker.writesTo(playerControlPath); !slices.Equal(got, tt.transport) {
+				t.Errorf("player control writes = %v, want %v", got, tt.transport)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// sourceValue is a source as the controller writes it.
+func sourceValue(source string) string {
+	return `{"kefPhysicalSource":"` + source + `","type":"kefPhysicalSource"}`
+}
+
+func TestToggleTarget(t *testing.T) {
+	tests := []struct {
+		current, want string
+	}{
+		{current: "optic", want: "wifi"},      // On A
+		{current: "wifi", want: "optic"},      // On B
+		{current: "bluetooth", want: "optic"}, // On neither
+		{current: "standby", want: "optic"},
+		{current: "", want: "optic"},
+	}
+
+	for _, tt := range tests {
+		if got := toggleTarget(tt.current, "optic", "wifi"); got != tt.want {
+			t.Errorf("toggleTarget(%q, optic, wifi) = %q, want %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestToggleBetweenSources(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+
+	// Wi-Fi, then optical, then back
+	for _, want := range []string{"optic", "wifi", "optic"} {
+		if err := c.ToggleBetweenSources("optic", "wifi"); err != nil {
+			t.Fatalf("ToggleBetweenSources() error = %v", err)
+		}
+		if got := c.GetState().Source; got != want {
+			t.Errorf("source after toggling = %q, want %q", got, want)
+		}
+	}
+
+	want := []string{sourceValue("optic"), sourceValue("wifi"), sourceValue("optic")}
+	if writes := speaker.writesTo(sourcePath); !slices.Equal(writes, want) {
+		t.Errorf("source writes = %v, want %v", writes, want)
+	}
+}
+
+func TestTransportSource(t *testing.T) {
+	tests := []struct {
+		current, lastStreaming string
+		want                   string
+		switchFirst            bool
+	}{
+		{kef.SourceOptical, kef.SourceBluetooth, kef.SourceBluetooth, true},
+		{kef.SourceTV, kef.SourceWifi, kef.SourceWifi, true},
+		{kef.SourceCoaxial, "", kef.SourceWifi, true}, // Nothing streamed yet
+		{kef.SourceAnalog, "", kef.SourceWifi, true},
+		{kef.SourceUSB, kef.SourceBluetooth, kef.SourceBluetooth, true},
+		{kef.SourceWifi, kef.SourceBluetooth, "", false}, // Already streaming
+		{kef.SourceBluetooth, "", "", false},
+		{kef.SourceStandby, kef.SourceWifi, "", false}, // Not woken
+		{"", kef.SourceWifi, "", false},                // Unknown
+		{"hdmi2", kef.SourceWifi, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := transportSource(tt.current, tt.lastStreaming)
+		if got != tt.want || ok != tt.switchFirst {
+			t.Errorf("transportSource(%q, %q) = %q, %t; want %q, %t", tt.current, tt.lastStreaming, got, ok, tt.want, tt.switchFirst)
+		}
+	}
+}
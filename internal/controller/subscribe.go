@@ -0,0 +1,73 @@
+package controller
+
+import "github.com/inquire/kefbar-go/pkg/kef"
+
+// Subscribe returns a channel that receives a snapshot of the speaker state
+// whenever it changes. The channel holds only the latest snapshot: a slow
+// reader skips intermediate states rather than blocking the controller.
+// It is closed by Close.
+func (c *Controller) Subscribe() <-chan kef.SpeakerState {
+	ch := make(chan kef.SpeakerState, 1)
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if c.closed {
+		close(ch)
+		return ch
+	}
+	c.subs = append(c.subs, ch)
+	return ch
+}
+
+// publish sends the current state to subscribers if it differs from the
+// last state sent. Call it after changing c.state, without holding c.mu.
+func (c *Controller) publish() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	// Snapshot under subMu, so concurrent publishers can't send an older
+	// state after a newer one
+	state := c.GetState()
+	if c.closed || (c.published != nil && statesEqual(*c.published, state)) {
+		return
+	}
+	c.published = &state
+
+	for _, ch := range c.subs {
+		// Replace an unread snapshot with the newer one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- state
+	}
+}
+
+// closeSubscriptions closes all subscriber channels.
+func (c *Controller) closeSubscriptions() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, ch := range c.subs {
+		close(ch)
+	}
+	c.subs = nil
+}
+
+// statesEqual compares two states, including playback info by value.
+func statesEqual(a, b kef.SpeakerState) bool {
+	pa, pb := a.PlaybackInfo, b.PlaybackInfo
+	a.PlaybackInfo, b.PlaybackInfo = nil, nil
+	if a != b {
+		return false
+	}
+	if pa == nil || pb == nil {
+		return pa == pb
+	}
+	return *pa == *pb
+}
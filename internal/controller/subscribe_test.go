@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPublishSendsLatestState(t *testing.T) {
+	c := newTestController(t, newFakeSpeaker())
+	updates := c.Subscribe()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.mu.Lock()
+			c.state.Volume = i
+			c.mu.Unlock()
+			c.publish()
+		}()
+	}
+	wg.Wait()
+
+	want := c.GetState()
+	c.subMu.Lock()
+	published := *c.published
+	c.subMu.Unlock()
+	if !statesEqual(published, want) {
+		t.Errorf("last published volume = %d, want the current %d", published.Volume, want.Volume)
+	}
+	if got := <-updates; !statesEqual(got, want) {
+		t.Errorf("subscriber got volume %d, want the current %d", got.Volume, want.Volume)
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// volumeValue is a volume reading as the speaker reports it.
+func volumeValue(level int) string {
+	return fmt.Sprintf(`{"type":"i32_","i32_":%d}`, level)
+}
+
+func TestMutedZeroKeepsVolume(t *testing.T) {
+	speaker := newFakeSpeaker()
+	speaker.set(volumePath, volumeValue(35))
+	c := newTestController(t, speaker)
+
+	if got, err := c.GetVolume(); err != nil || got != 35 {
+		t.Fatalf("GetVolume() = %d, %v; want 35", got, err)
+	}
+
+	// Muted, the speaker reports a volume of 0
+	speaker.set(volumePath, volumeValue(0))
+	speaker.set(mutePath, boolValue(true))
+
+	got, err := c.GetVolume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 35 {
+		t.Errorf("GetVolume() while muted = %d, want the remembered 35", got)
+	}
+	if state := c.GetState(); !state.Muted || state.Volume != 35 {
+		t.Errorf("state while muted = volume %d muted %t, want 35 and muted", state.Volume, state.Muted)
+	}
+
+	// Polling sees the same thing, once the speaker is known to be on
+	if _, err := c.GetSource(); err != nil {
+		t.Fatal(err)
+	}
+	c.pollState()
+	if got := c.GetState().Volume; got != 35 {
+		t.Errorf("volume after a muted poll = %d, want 35", got)
+	}
+
+	// A real zero while unmuted is kept
+	speaker.set(mutePath, boolValue(false))
+	if got, err := c.GetVolume(); err != nil || got != 0 {
+		t.Errorf("GetVolume() unmuted at 0 = %d, %v; want 0", got, err)
+	}
+}
+
+func TestVolumeSettleWindow(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+	// Known to be on, so polls read the volume
+	if _, err := c.GetSource(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A burst of steps, each followed by a poll that read the speaker
+	// before the write landed
+	for level := 43; level <= 47; level++ {
+		if err := c.SetVolume(level); err != nil {
+			t.Fatal(err)
+		}
+		speaker.set(volumePath, volumeValue(level-1))
+		c.pollState()
+		if got := c.GetState().Volume; got != level {
+			t.Fatalf("volume after a stale poll = %d, want the %d just set", got, level)
+		}
+	}
+
+	// A change pushed for an earlier step is ignored too, but the rest of
+	// the update isn't
+	c.applyValues(map[string]map[string]interface{}{
+		volumePath: {"type": "i32_", "i32_": 44.0},
+		mutePath:   {"type": "bool_", "bool_": true},
+	})
+	if state := c.GetState(); state.Volume != 47 || !state.Muted {
+		t.Errorf("state after a stale push = volume %d muted %t, want 47 and muted", state.Volume, state.Muted)
+	}
+
+	// Once the window has passed, the speaker's reading is trusted again,
+	// e.g. after the volume knob was turned
+	c.mu.Lock()
+	c.volumeSetAt = c.volumeSetAt.Add(-volumeSettleWindow)
+	c.mu.Unlock()
+	speaker.set(volumePath, volumeValue(20))
+	speaker.set(mutePath, boolValue(false))
+	c.pollState()
+	if got := c.GetState().Volume; got != 20 {
+		t.Errorf("volume after the window = %d, want the polled 20", got)
+	}
+}
+
+func TestVolumeStepWhileMuted(t *testing.T) {
+	tests := []struct {
+		name       string
+		sticky     bool
+		delta      int
+		wantVolume int
+		wantMuted  bool
+		wantMutes  []string // Mute writes after muting
+	}{
+		{name: "up unmutes", delta: 5, wantVolume: 47, wantMutes: []string{boolValue(false)}},
+		{name: "down unmutes", delta: -5, wantVolume: 37, wantMutes: []string{boolValue(false)}},
+		{name: "up with sticky mute", sticky: true, delta: 5, wantVolume: 47, wantMuted: true},
+		{name: "down with sticky mute", sticky: true, delta: -5, wantVolume: 37, wantMuted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speaker := newFakeSpeaker()
+			c := newTestController(t, speaker)
+			c.cfg.StickyMute = tt.sticky
+			if _, err := c.GetVolume(); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SetMute(true); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.AdjustVolume(tt.delta); err != nil {
+				t.Fatalf("AdjustVolume(%d) error = %v", tt.delta, err)
+			}
+
+			state := c.GetState()
+			if state.Volume != tt.wantVolume || state.Muted != tt.wantMuted {
+				t.Errorf("state = volume %d muted %t, want %d and %t", state.Volume, state.Muted, tt.wantVolume, tt.wantMuted)
+			}
+			if writes, want := speaker.writesTo(volumePath), []string{volumeValue(tt.wantVolume)}; !slices.Equal(writes, want) {
+				t.Errorf("volume writes = %v, want %v", writes, want)
+			}
+			if writes, want := speaker.writesTo(mutePath)[1:], tt.wantMutes; !slices.Equal(writes, want) {
+				t.Errorf("mute writes after muting = %v, want %v", writes, want)
+			}
+		})
+	}
+}
+
+// TestVolumeStepUnmutesFromPremuteLevel covers speakers that report a volume
+// of 0 while muted: stepping starts from the level before muting.
+func TestVolumeStepUnmutesFromPremuteLevel(t *testing.T) {
+	speaker := newFakeSpeaker()
+	c := newTestController(t, speaker)
+	if _, err := c.GetVolume(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMute(true); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	c.state.Volume = 0
+	c.mu.Unlock()
+
+	if err := c.AdjustVolume(5); err != nil {
+		t.Fatal(err)
+	}
+	if state := c.GetState(); state.Volume != 47 || state.Muted {
+		t.Errorf("state = volume %d muted %t, want 47 and unmuted", state.Volume, state.Muted)
+	}
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"log/slog"
+
+	"github.com/inquire/kefbar-go/internal/safe"
+)
+
+// volumeQueueSize bounds the volume steps waiting to be sent. Presses
+// beyond it are dropped rather than piling up behind a slow speaker.
+const volumeQueueSize = 16
+
+// QueueVolumeStep changes the volume by delta without waiting for the
+// speaker, for callers such as hotkeys that must never block. Steps queued
+// while another is being sent are combined into one change. It reports
+// false if the queue was full and the step was dropped.
+func (c *Controller) QueueVolumeStep(delta int) bool {
+	c.volumeQueueOnce.Do(func() {
+		safe.GoRestart("volume queue", c.runVolumeQueue)
+	})
+
+	select {
+	case c.volumeSteps <- delta:
+		return true
+	default:
+		slog.Debug("Volume queue full, dropping step", "delta", delta)
+		return false
+	}
+}
+
+// runVolumeQueue sends queued volume steps until the controller is closed.
+func (c *Controller) runVolumeQueue() {
+	for {
+		var delta int
+		select {
+		case <-c.ctx.Done():
+			return
+		case delta = <-c.volumeSteps:
+		}
+
+		// Fold in whatever arrived while the last change was being sent
+	drain:
+		for {
+			select {
+			case more := <-c.volumeSteps:
+				delta += more
+			default:
+				break drain
+			}
+		}
+		if delta == 0 {
+			continue
+		}
+
+		oldVol := c.GetState().Volume
+		if err := c.AdjustVolume(delta); err != nil {
+			slog.Error("Failed to change volume", "delta", delta, "error", err)
+			continue
+		}
+		slog.Info("Volume changed", "old", oldVol, "new", c.GetState().Volume)
+	}
+}
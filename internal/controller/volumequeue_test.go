@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stallingSpeaker is a fakeSpeaker whose volume writes can be held until
+// released, like a speaker that stops answering while a dialog has the
+// app tied up.
+type stallingSpeaker struct {
+	*fakeSpeaker
+
+	mu      sync.Mutex
+	gate    chan struct{} // Closed to let volume writes through
+	entered chan struct{} // Signalled as each volume write arrives
+}
+
+func newStallingSpeaker() *stallingSpeaker {
+	s := &stallingSpeaker{
+		fakeSpeaker: newFakeSpeaker(),
+		gate:        make(chan struct{}),
+		entered:     make(chan struct{}, 64),
+	}
+	close(s.gate)
+	return s
+}
+
+// stall holds volume writes from now on.
+func (s *stallingSpeaker) stall() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate = make(chan struct{})
+}
+
+// release lets held and future volume writes through.
+func (s *stallingSpeaker) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.gate)
+}
+
+func (s *stallingSpeaker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/setData" && r.URL.Query().Get("path") == volumePath {
+		s.mu.Lock()
+		gate := s.gate
+		s.mu.Unlock()
+
+		select {
+		case s.entered <- struct{}{}:
+		default:
+		}
+		select {
+		case <-gate:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	s.fakeSpeaker.ServeHTTP(w, r)
+}
+
+// waitForVolume waits for c's volume to reach want.
+func waitForVolume(t *testing.T, c *Controller, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.GetState().Volume != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("volume = %d, want %d", c.GetState().Volume, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueVolumeStepFoldsAndDrops(t *testing.T) {
+	speaker := newStallingSpeaker()
+	c := newTestController(t, speaker)
+	if _, err := c.GetVolume(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first step is sent and held by the speaker
+	speaker.stall()
+	if !c.QueueVolumeStep(1) {
+		t.Fatal("QueueVolumeStep() dropped the first step")
+	}
+	select {
+	case <-speaker.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first step was never sent")
+	}
+
+	// Steps pile up behind it until the queue is full
+	for i := range volumeQueueSize {
+		if !c.QueueVolumeStep(1) {
+			t.Fatalf("QueueVolumeStep() dropped step %d of %d", i+1, volumeQueueSize)
+		}
+	}
+	if c.QueueVolumeStep(1) {
+		t.Error("QueueVolumeStep() accepted a step with the queue full")
+	}
+
+	// Once the speaker answers, the queued steps go out as one change
+	speaker.release()
+	want := 42 + 1 + volumeQueueSize
+	waitForVolume(t, c, want)
+
+	if writes, wantWrites := speaker.writesTo(volumePath), []string{volumeValue(43), volumeValue(want)}; !slices.Equal(writes, wantWrites) {
+		t.Errorf("volume writes = %v, want %v", writes, wantWrites)
+	}
+}
+
+func TestQueueVolumeStepWhileStalled(t *testing.T) {
+	speaker := newStallingSpeaker()
+	c := newTestController(t, speaker)
+	if _, err := c.GetVolume(); err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		rounds   = 5
+		pressers = 4
+		presses  = 50
+	)
+	var accepted atomic.Int64 // Sum of the steps QueueVolumeStep took
+
+	for round := range rounds {
+		// Hotkeys keep firing while the speaker is held up. None of them
+		// may wait for it.
+		speaker.stall()
+
+		var wg sync.WaitGroup
+		for range pressers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range presses {
+					delta := 1 - 2*(i%2) // Up, down, up, ...
+					if c.QueueVolumeStep(delta) {
+						accepted.Add(int64(delta))
+					}
+				}
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			speaker.release()
+			t.Fatalf("round %d: QueueVolumeStep() blocked on a stalled speaker", round+1)
+		}
+
+		// Every accepted step lands once the speaker answers
+		speaker.release()
+		waitForVolume(t, c, 42+int(accepted.Load()))
+	}
+}
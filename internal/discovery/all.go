@@ -0,0 +1,177 @@
+package discovery
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/api"
+)
+
+// Settings read from each speaker found by DiscoverAll.
+const (
+	deviceNamePath  = "settings:/deviceName"
+	macAddressPath  = "settings:/system/primaryMacAddress"
+	releaseTextPath = "settings:/releasetext"
+)
+
+// detailsTimeout bounds each request for a discovered speaker's details.
+const detailsTimeout = 2 * time.Second
+
+// DiscoveredSpeaker describes a speaker found by DiscoverAll.
+type DiscoveredSpeaker struct {
+	Name  string `json:"name,omitempty"`
+	Model string `json:"model,omitempty"`
+	IP    string `json:"ip"`
+	MAC   string `json:"mac,omitempty"`
+}
+
+// DiscoverAll finds every speaker that answers within timeout. SSDP and a
+// full network scan, whichever of them opts.Methods enables, run side by
+// side, then each speaker is asked for its name, model and MAC address.
+// Details that can't be read are left empty. Speakers are returned in
+// address order. Only the Methods, Scheme, Port and Transport of opts are
+// used.
+func DiscoverAll(ctx context.Context, timeout time.Duration, opts Options) ([]DiscoveredSpeaker, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		speakers = make(map[string]*DiscoveredSpeaker)
+	)
+	add := func(ip, name, model string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		sp, ok := speakers[ip]
+		if !ok {
+			sp = &DiscoveredSpeaker{IP: ip}
+			speakers[ip] = sp
+		}
+		if sp.Name == "" {
+			sp.Name = name
+		}
+		if sp.Model == "" {
+			sp.Model = model
+		}
+	}
+
+	methods := opts.methods()
+	var wg sync.WaitGroup
+
+	if slices.Contains(methods, MethodSSDP) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var received atomic.Bool
+			responses, err := ssdpSearch(ctx, timeout, &received)
+			if err != nil {
+				return
+			}
+
+			confirm := opts.newScanner().Probe
+			described := make(map[string]bool)
+			for res := range responses {
+				if described[res.ip] {
+					continue
+				}
+				described[res.ip] = true
+
+				if !confirm(ctx, res.ip) {
+					continue
+				}
+				name, model := describeDevice(ctx, res.location)
+				add(res.ip, name, model)
+			}
+		}()
+	}
+
+	var scanErr error
+	if slices.Contains(methods, MethodScan) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ips, err := opts.newScanner().ScanAll(ctx, timeout)
+			for _, ip := range ips {
+				add(ip, "", "")
+			}
+			scanErr = err
+		}()
+	}
+
+	wg.Wait()
+
+	// The timeout is the normal way for the search to end; only fail if
+	// nothing at all was found
+	if len(speakers) == 0 && scanErr != nil {
+		return nil, scanErr
+	}
+
+	list := make([]DiscoveredSpeaker, 0, len(speakers))
+	for _, sp := range speakers {
+		list = append(list, *sp)
+	}
+	slices.SortFunc(list, func(a, b DiscoveredSpeaker) int { return compareIPs(a.IP, b.IP) })
+
+	// The search context has expired by now, so details get their own
+	for i := range list {
+		readDetails(context.WithoutCancel(ctx), &list[i], opts)
+	}
+
+	return list, nil
+}
+
+// readDetails fills in a speaker's missing name, model and MAC address from
+// its settings.
+func readDetails(ctx context.Context, sp *DiscoveredSpeaker, opts Options) {
+	ctx, cancel := context.WithTimeout(ctx, detailsTimeout*3)
+	defer cancel()
+
+	port := opts.Port
+	if port <= 0 {
+		port = defaultPort
+	}
+	client := api.NewClient(sp.IP, port, detailsTimeout)
+	client.SetContext(ctx)
+	if opts.Scheme != "" {
+		_ = client.SetScheme(opts.Scheme)
+	}
+	if opts.Transport != nil {
+		client.SetTransport(opts.Transport)
+	}
+
+	if sp.Name == "" {
+		if name, err := client.GetString(deviceNamePath); err == nil {
+			sp.Name = name
+		}
+	}
+	if sp.Model == "" {
+		// Model is the first part of the release text (e.g., "LSXII_4.0.1")
+		if release, err := client.GetString(releaseTextPath); err == nil {
+			sp.Model, _, _ = strings.Cut(release, "_")
+		}
+	}
+	if mac, err := client.GetString(macAddressPath); err == nil {
+		sp.MAC = mac
+	}
+}
+
+// compareIPs orders dotted IPv4 addresses numerically.
+func compareIPs(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if len(pa[i]) != len(pb[i]) {
+			return len(pa[i]) - len(pb[i])
+		}
+		if c := strings.Compare(pa[i], pb[i]); c != 0 {
+			return c
+		}
+	}
+	return len(pa) - len(pb)
+}
@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiscoveredSpeakerJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		speakers []DiscoveredSpeaker
+		want     string
+	}{
+		{
+			name: "full and partial details",
+			speakers: []DiscoveredSpeaker{
+				{Name: "Office", Model: "LSXII", IP: "192.168.1.20", MAC: "00:1a:2b:3c:4d:5e"},
+				{Model: "LS60", IP: "192.168.1.21"},
+				{IP: "192.168.1.22"},
+			},
+			want: `[` +
+				`{"name":"Office","model":"LSXII","ip":"192.168.1.20","mac":"00:1a:2b:3c:4d:5e"},` +
+				`{"model":"LS60","ip":"192.168.1.21"},` +
+				`{"ip":"192.168.1.22"}` +
+				`]`,
+		},
+		// DiscoverAll returns an empty list, not nil, when nothing answers
+		{name: "none found", speakers: []DiscoveredSpeaker{}, want: `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.speakers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", data, tt.want)
+			}
+
+			var decoded []DiscoveredSpeaker
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.speakers {
+				if decoded[i] != tt.speakers[i] {
+					t.Errorf("speaker %d round-tripped to %+v, want %+v", i, decoded[i], tt.speakers[i])
+				}
+			}
+		})
+	}
+}
@@ -3,23 +3,168 @@ package discovery
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
 	"time"
 )
 
+// Discovery methods, for Options.Methods.
+const (
+	MethodSSDP = "ssdp" // SSDP multicast search
+	MethodScan = "scan" // Probe every host on the local networks
+)
+
+// DefaultMethods are the discovery methods tried, in order, when
+// Options.Methods names none.
+var DefaultMethods = []string{MethodSSDP, MethodScan}
+
+// Default discovery tuning.
+const (
+	// DefaultSSDPBudgetPercent is the share of the discovery timeout given
+	// to SSDP before falling back to the network scan.
+	DefaultSSDPBudgetPercent = 50
+
+	// DefaultSSDPSilenceTimeout ends SSDP early when no packets at all have
+	// arrived, which usually means multicast is filtered on this network.
+	DefaultSSDPSilenceTimeout = 1500 * time.Millisecond
+)
+
 // Discoverer defines the interface for speaker discovery.
 type Discoverer interface {
 	Discover(ctx context.Context, timeout time.Duration) (string, error)
 }
 
+// Speaker is a discovered speaker.
+type Speaker struct {
+	IP    string
+	Model string // Model advertised over SSDP; empty if not known
+}
+
+// Options tunes how the discovery time budget is spent.
+type Options struct {
+	// Methods are the discovery methods to try, in order. Unknown names
+	// are skipped; if none are left DefaultMethods are used. Leaving out
+	// MethodScan avoids the slow network scan where SSDP works.
+	Methods []string
+
+	// SSDPBudgetPercent is the share (1-100) of the timeout given to SSDP.
+	// Out-of-range values use DefaultSSDPBudgetPercent.
+	SSDPBudgetPercent int
+
+	// SSDPSilenceTimeout ends SSDP early if nothing has been received by
+	// then. Zero uses DefaultSSDPSilenceTimeout.
+	SSDPSilenceTimeout time.Duration
+
+	// Scanner, if set, is used for the network scan so that an interrupted
+	// scan resumes where it stopped on the next discovery. Its own Scheme
+	// and Port apply.
+	Scanner *Scanner
+
+	// Scheme and Port locate the speaker API for the network scan and
+	// speaker details. Empty and zero mean http and port 80.
+	Scheme string
+	Port   int
+
+	// Transport is the connection pool for the network scan and speaker
+	// details. Nil means api.DefaultTransport.
+	Transport http.RoundTripper
+}
+
 // Discover attempts to find a KEF speaker on the network.
 // It tries SSDP first, then falls back to network scanning.
 func Discover(ctx context.Context, timeout time.Duration) (string, error) {
-	// Try SSDP discovery first
-	if ip, err := DiscoverViaSSDP(ctx, timeout/2); err == nil {
-		return ip, nil
+	return DiscoverWithOptions(ctx, timeout, Options{})
+}
+
+// DiscoverWithOptions is like Discover but with a tunable budget split.
+// Any time SSDP doesn't use (e.g., because multicast looks blocked) is
+// handed to the network scan.
+func DiscoverWithOptions(ctx context.Context, timeout time.Duration, opts Options) (string, error) {
+	speaker, err := DiscoverSpeaker(ctx, timeout, opts)
+	return speaker.IP, err
+}
+
+// DiscoverSpeaker is like DiscoverWithOptions but also reports the model when
+// the speaker was found via SSDP, so callers can skip fetching it.
+func DiscoverSpeaker(ctx context.Context, timeout time.Duration, opts Options) (Speaker, error) {
+	deadline := time.Now().Add(timeout)
+
+	silence := opts.SSDPSilenceTimeout
+	if silence <= 0 {
+		silence = DefaultSSDPSilenceTimeout
+	}
+
+	scanner := opts.Scanner
+	if scanner == nil {
+		scanner = opts.newScanner()
+	}
+
+	// Each method gets what the ones before it left over. SSDP is capped
+	// at its budget unless it is the last method.
+	methods := opts.methods()
+	err := fmt.Errorf("speaker not found")
+	for i, method := range methods {
+		remaining := time.Until(deadline)
+		if remaining <= 0 || ctx.Err() != nil {
+			break
+		}
+
+		var speaker Speaker
+		switch method {
+		case MethodSSDP:
+			budget := remaining
+			if i < len(methods)-1 {
+				budget = min(ssdpBudget(timeout, opts.SSDPBudgetPercent), remaining)
+			}
+			// Confirm responders the way the scan probes hosts
+			speaker, err = discoverViaSSDP(ctx, budget, silence, scanner.Probe)
+		case MethodScan:
+			speaker.IP, err = scanner.Scan(ctx, remaining)
+		}
+		if err == nil {
+			return speaker, nil
+		}
+	}
+	return Speaker{}, err
+}
+
+// methods returns the known methods in opts.Methods, without repeats, or
+// DefaultMethods if there are none. Unknown methods are logged and
+// skipped.
+func (opts Options) methods() []string {
+	var methods []string
+	for _, method := range opts.Methods {
+		switch {
+		case !slices.Contains(DefaultMethods, method):
+			slog.Warn("Ignoring unknown discovery method", "method", method, "known", DefaultMethods)
+		case !slices.Contains(methods, method):
+			methods = append(methods, method)
+		}
 	}
+	if len(methods) == 0 {
+		if len(opts.Methods) > 0 {
+			slog.Warn("No known discovery methods given, using the defaults", "methods", DefaultMethods)
+		}
+		return DefaultMethods
+	}
+	return methods
+}
 
-	// Fallback to network scanning
-	return DiscoverViaNetworkScan(ctx, timeout/2)
+// ssdpBudget returns the part of the timeout allotted to SSDP.
+func ssdpBudget(timeout time.Duration, percent int) time.Duration {
+	if percent <= 0 || percent > 100 {
+		percent = DefaultSSDPBudgetPercent
+	}
+	return timeout * time.Duration(percent) / 100
 }
 
+// newScanner creates a Scanner for the API location in opts.
+func (opts Options) newScanner() *Scanner {
+	scanner := NewScanner()
+	scanner.Scheme = opts.Scheme
+	scanner.Port = opts.Port
+	scanner.Transport = opts.Transport
+	return scanner
+}
@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLogs sends log output to the returned buffer until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []string
+		want    []string
+		warns   []string // Log output expected
+	}{
+		{name: "none", want: DefaultMethods},
+		{name: "reordered", methods: []string{MethodScan, MethodSSDP}, want: []string{MethodScan, MethodSSDP}},
+		{name: "repeated", methods: []string{MethodScan, MethodScan}, want: []string{MethodScan}},
+		{
+			name:    "unknown skipped",
+			methods: []string{"mdns", MethodScan},
+			want:    []string{MethodScan},
+			warns:   []string{"unknown discovery method", "method=mdns"},
+		},
+		{
+			name:    "only unknown",
+			methods: []string{"mdns", "bonjour"},
+			want:    DefaultMethods,
+			warns:   []string{"method=mdns", "method=bonjour", "using the defaults"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			if got := (Options{Methods: tt.methods}).methods(); !slices.Equal(got, tt.want) {
+				t.Errorf("methods() = %v, want %v", got, tt.want)
+			}
+			for _, warn := range tt.warns {
+				if !strings.Contains(logs.String(), warn) {
+					t.Errorf("log %q doesn't mention %q", logs.String(), warn)
+				}
+			}
+			if len(tt.warns) == 0 && logs.Len() > 0 {
+				t.Errorf("unexpected log output %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestSSDPBudget(t *testing.T) {
+	const timeout = 10 * time.Second
+	defaultBudget := timeout * DefaultSSDPBudgetPercent / 100
+
+	tests := []struct {
+		percent int
+		want    time.Duration
+	}{
+		{percent: 0, want: defaultBudget}, // Unset
+		{percent: -20, want: defaultBudget},
+		{percent: 101, want: defaultBudget},
+		{percent: 250, want: defaultBudget},
+		{percent: 1, want: 100 * time.Millisecond},
+		{percent: 30, want: 3 * time.Second},
+		{percent: 100, want: timeout},
+	}
+
+	for _, tt := range tests {
+		if got := ssdpBudget(timeout, tt.percent); got != tt.want {
+			t.Errorf("ssdpBudget(%v, %d) = %v, want %v", timeout, tt.percent, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrLocalNetworkDenied is returned by CheckLocalNetwork when macOS blocks
+// local network access, which is granted in System Settings > Privacy &
+// Security > Local Network.
+var ErrLocalNetworkDenied = errors.New("local network access denied")
+
+// CheckLocalNetwork sends a single SSDP search to see whether the app may
+// reach the local network. macOS refuses sends to local addresses with
+// "no route to host" when the permission is missing; any other failure,
+// such as having no network at all, says nothing about it and is returned
+// as is.
+func CheckLocalNetwork() error {
+	addr, err := net.ResolveUDPAddr("udp4", ssdpMulticastAddr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		return classifyLocalNetworkError(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Write([]byte(buildMSearchRequest("upnp:rootdevice"))); err != nil {
+		return classifyLocalNetworkError(err)
+	}
+	return nil
+}
+
+// classifyLocalNetworkError maps the error macOS reports for a missing
+// Local Network permission to ErrLocalNetworkDenied.
+func classifyLocalNetworkError(err error) error {
+	if errors.Is(err, syscall.EHOSTUNREACH) {
+		return fmt.Errorf("%w: %v", ErrLocalNetworkDenied, err)
+	}
+	return err
+}
@@ -6,93 +6,267 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/pkg/kef"
 )
 
+// defaultScanWorkers is the number of hosts probed concurrently.
+const defaultScanWorkers = 64
+
+// probeTimeout bounds the check of a single host.
+const probeTimeout = 1 * time.Second
+
+// defaultPort is the speaker API port unless configured otherwise.
+const defaultPort = 80
+
+// ProbeFunc reports whether the given IP hosts a KEF speaker.
+type ProbeFunc func(ctx context.Context, ip string) bool
+
+// ScanProgress reports how far a network scan has got.
+type ScanProgress struct {
+	Scanned int
+	Total   int
+}
+
+// Scanner scans the local network for KEF speakers. A scan that is cancelled
+// or times out can be resumed by calling Scan again; hosts already probed
+// are not probed again until the scan completes or Reset is called.
+type Scanner struct {
+	// Probe checks a single host. Defaults to the KEF HTTP API check.
+	Probe ProbeFunc
+	// Workers is the number of hosts probed concurrently.
+	Workers int
+	// OnProgress, if set, is called after each batch of hosts is probed.
+	OnProgress func(ScanProgress)
+	// Scheme and Port locate the API the default probe checks; set them
+	// before scanning. Empty and zero mean http and port 80.
+	Scheme string
+	Port   int
+	// Transport is the connection pool the default probe uses. Nil means
+	// api.DefaultTransport, so speakers found are already connected.
+	Transport http.RoundTripper
+
+	mu         sync.Mutex
+	candidates []string
+	cursor     int // index of the next candidate to probe
+}
+
+// NewScanner creates a Scanner that probes hosts via the KEF HTTP API. The
+// secondary speaker of a stereo pair doesn't count, as only the primary
+// can be controlled.
+func NewScanner() *Scanner {
+	s := &Scanner{Workers: defaultScanWorkers}
+	s.Probe = func(ctx context.Context, ip string) bool {
+		client, base := s.probeClient(), apiBase(s.Scheme, ip, s.Port)
+		return isKEFSpeaker(ctx, client, base) && !isSecondarySpeaker(ctx, client, base)
+	}
+	return s
+}
+
+// probeClient returns an HTTP client for the default probe.
+func (s *Scanner) probeClient() *http.Client {
+	transport := s.Transport
+	if transport == nil {
+		transport = api.DefaultTransport
+	}
+	return &http.Client{
+		Timeout:   probeTimeout,
+		Transport: transport,
+	}
+}
+
 // DiscoverViaNetworkScan scans the local network for KEF speakers.
 func DiscoverViaNetworkScan(ctx context.Context, timeout time.Duration) (string, error) {
-	localIPs, err := getLocalIPs()
+	return NewScanner().Scan(ctx, timeout)
+}
+
+// Scan probes candidate hosts on the local networks, resuming from where a
+// previous interrupted scan stopped, and returns the first speaker found.
+func (s *Scanner) Scan(ctx context.Context, timeout time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidates, err := scanCandidates()
 	if err != nil {
 		return "", err
 	}
 
-	if len(localIPs) == 0 {
-		return "", fmt.Errorf("no local network interfaces found")
+	// Start over if the local networks changed since the last scan
+	if !slices.Equal(candidates, s.candidates) {
+		s.candidates = candidates
+		s.cursor = 0
 	}
 
 	scanCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	type result struct {
-		ip  string
-		err error
+	return s.scanFrom(scanCtx)
+}
+
+// ScanAll probes every candidate host and returns all speakers found, in
+// address order. It doesn't use or change the progress saved by Scan. If
+// the timeout expires, the speakers found so far are returned with the
+// error.
+func (s *Scanner) ScanAll(ctx context.Context, timeout time.Duration) ([]string, error) {
+	candidates, err := scanCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = defaultScanWorkers
 	}
 
-	resultChan := make(chan result, 1)
+	scanCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	found := make([]bool, len(candidates))
+	sem := make(chan struct{}, workers)
 	var wg sync.WaitGroup
+	for i, ip := range candidates {
+		select {
+		case sem <- struct{}{}:
+		case <-scanCtx.Done():
+		}
+		if scanCtx.Err() != nil {
+			break
+		}
 
-	client := &http.Client{
-		Timeout: 1 * time.Second,
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found[i] = s.Probe(scanCtx, ip)
+		}()
 	}
+	wg.Wait()
 
-	// Scan each local network
-	for _, localIP := range localIPs {
-		ip := localIP.To4()
-		if ip == nil {
-			continue
+	var ips []string
+	for i, ok := range found {
+		if ok {
+			ips = append(ips, candidates[i])
 		}
+	}
 
-		networkPrefix := fmt.Sprintf("%d.%d.%d", ip[0], ip[1], ip[2])
+	if err := scanCtx.Err(); err != nil {
+		return ips, scanError(err)
+	}
+	return ips, nil
+}
 
-		// Scan IPs 1-254
-		for i := 1; i <= 254; i++ {
-			select {
-			case <-scanCtx.Done():
-				goto waitForResults
-			default:
-			}
+// Reset discards any saved progress so the next Scan starts from scratch.
+func (s *Scanner) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-			testIP := fmt.Sprintf("%s.%d", networkPrefix, i)
-			wg.Add(1)
+	s.candidates = nil
+	s.cursor = 0
+}
+
+// scanFrom probes candidates in batches starting at the cursor. The cursor
+// only advances past a batch once every host in it has been probed, so an
+// interruption never skips a host. Callers must hold s.mu.
+func (s *Scanner) scanFrom(ctx context.Context) (string, error) {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = defaultScanWorkers
+	}
+
+	for s.cursor < len(s.candidates) {
+		if err := ctx.Err(); err != nil {
+			return "", scanError(err)
+		}
 
-			go func(ipAddr string) {
+		end := min(s.cursor+workers, len(s.candidates))
+		batch := s.candidates[s.cursor:end]
+
+		found := make([]bool, len(batch))
+		var wg sync.WaitGroup
+		for i, ip := range batch {
+			wg.Add(1)
+			go func() {
 				defer wg.Done()
+				found[i] = s.Probe(ctx, ip)
+			}()
+		}
+		wg.Wait()
+
+		// Take the lowest matching address so results are deterministic.
+		// A speaker that answered counts even if the scan ran out of time
+		// while the rest of its batch was probed.
+		if i := slices.Index(found, true); i >= 0 {
+			if s.OnProgress != nil {
+				s.OnProgress(ScanProgress{Scanned: end, Total: len(s.candidates)})
+			}
+			s.candidates = nil
+			s.cursor = 0
+			return batch[i], nil
+		}
+
+		// A cancelled batch may have probes that gave up early, so it
+		// doesn't count as scanned
+		if err := ctx.Err(); err != nil {
+			return "", scanError(err)
+		}
 
-				select {
-				case <-scanCtx.Done():
-					return
-				default:
-				}
-
-				if isKEFSpeaker(scanCtx, client, ipAddr) {
-					select {
-					case resultChan <- result{ip: ipAddr, err: nil}:
-					case <-scanCtx.Done():
-					}
-				}
-			}(testIP)
+		s.cursor = end
+		if s.OnProgress != nil {
+			s.OnProgress(ScanProgress{Scanned: s.cursor, Total: len(s.candidates)})
 		}
 	}
 
-waitForResults:
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case res := <-resultChan:
-		return res.ip, res.err
-	case <-scanCtx.Done():
-		if scanCtx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("network scan timeout")
+	// The whole range was scanned; the next scan starts over
+	s.candidates = nil
+	s.cursor = 0
+	return "", fmt.Errorf("speaker not found on network")
+}
+
+// scanError converts a context error into a scan error.
+func scanError(err error) error {
+	if err == context.DeadlineExceeded {
+		return fmt.Errorf("network scan timeout")
+	}
+	return err
+}
+
+// scanCandidates returns the hosts to probe: addresses 1-254 of each local
+// IPv4 /24 network.
+func scanCandidates() ([]string, error) {
+	localIPs, err := getLocalIPs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(localIPs) == 0 {
+		return nil, fmt.Errorf("no local network interfaces found")
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, localIP := range localIPs {
+		ip := localIP.To4()
+		if ip == nil {
+			continue
+		}
+
+		networkPrefix := fmt.Sprintf("%d.%d.%d", ip[0], ip[1], ip[2])
+		if seen[networkPrefix] {
+			continue
+		}
+		seen[networkPrefix] = true
+
+		for i := 1; i <= 254; i++ {
+			candidates = append(candidates, fmt.Sprintf("%s.%d", networkPrefix, i))
 		}
-		return "", scanCtx.Err()
-	case <-done:
-		return "", fmt.Errorf("speaker not found on network")
 	}
+
+	return candidates, nil
 }
 
 // getLocalIPs returns all local IPv4 addresses.
@@ -123,9 +297,51 @@ func getLocalIPs() ([]net.IP, error) {
 	return localIPs, nil
 }
 
-// isKEFSpeaker checks if the given IP hosts a KEF speaker.
-func isKEFSpeaker(ctx context.Context, client *http.Client, ipAddr string) bool {
-	apiURL := fmt.Sprintf("http://%s:80/api/getData?path=settings:/deviceName&roles=value", ipAddr)
+// apiBase returns the base URL of a speaker's API, defaulting to http on
+// port 80.
+func apiBase(scheme, ip string, port int) string {
+	if scheme == "" {
+		scheme = "http"
+	}
+	if port <= 0 {
+		port = defaultPort
+	}
+	return scheme + "://" + net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+// isSecondarySpeaker checks if the speaker whose API is at base reports
+// being the secondary of a stereo pair. Speakers that don't report a role
+// are taken to be controllable.
+func isSecondarySpeaker(ctx context.Context, client *http.Client, base string) bool {
+	apiURL := base + "/api/getData?path=settings:/kef/host/speakerRole&roles=value"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		return false
+	}
+
+	var data []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil || len(data) == 0 {
+		return false
+	}
+	role, _ := data[0]["string_"].(string)
+	return kef.SpeakerRole(role) == kef.RoleSecondary
+}
+
+// isKEFSpeaker checks if the API at base (e.g., "http://192.168.1.20:80")
+// is a KEF speaker's.
+func isKEFSpeaker(ctx context.Context, client *http.Client, base string) bool {
+	apiURL := base + "/api/getData?path=settings:/deviceName&roles=value"
 
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
@@ -155,4 +371,3 @@ func isKEFSpeaker(ctx context.Context, client *http.Client, ipAddr string) bool
 
 	return false
 }
-
@@ -0,0 +1,140 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCandidates returns n addresses on 10.0.0.0/24, from 10.0.0.1 up.
+func testCandidates(n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("10.0.0.%d", i+1)
+	}
+	return ips
+}
+
+func TestScanFromLowestMatchOnTimeout(t *testing.T) {
+	// Two speakers in the same batch. The higher one answers at once; the
+	// lower one only as the scan runs out of time, and the other hosts
+	// never answer.
+	s := &Scanner{
+		Workers:    8,
+		candidates: testCandidates(16),
+		Probe: func(ctx context.Context, ip string) bool {
+			switch ip {
+			case "10.0.0.6":
+				return true
+			case "10.0.0.3":
+				<-ctx.Done()
+				return true
+			}
+			<-ctx.Done()
+			return false
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	ip, err := s.scanFrom(ctx)
+	if err != nil || ip != "10.0.0.3" {
+		t.Fatalf("scanFrom() = %q, %v; want the lowest speaker, 10.0.0.3", ip, err)
+	}
+	if s.candidates != nil || s.cursor != 0 {
+		t.Errorf("progress kept after a speaker was found: cursor %d of %d", s.cursor, len(s.candidates))
+	}
+}
+
+func TestScanFromLowestMatchInBatch(t *testing.T) {
+	speakers := map[string]bool{"10.0.0.12": true, "10.0.0.10": true, "10.0.0.15": true}
+	var progress []ScanProgress
+	s := &Scanner{
+		Workers:    8,
+		candidates: testCandidates(16),
+		Probe: func(_ context.Context, ip string) bool {
+			return speakers[ip]
+		},
+		OnProgress: func(p ScanProgress) { progress = append(progress, p) },
+	}
+
+	ip, err := s.scanFrom(context.Background())
+	if err != nil || ip != "10.0.0.10" {
+		t.Fatalf("scanFrom() = %q, %v; want 10.0.0.10", ip, err)
+	}
+	if want := []ScanProgress{{8, 16}, {16, 16}}; !slices.Equal(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+}
+
+func TestScanFromResumes(t *testing.T) {
+	const speaker = "10.0.0.30"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var probed []string
+	s := &Scanner{
+		Workers:    8,
+		candidates: testCandidates(32),
+		Probe: func(probeCtx context.Context, ip string) bool {
+			mu.Lock()
+			probed = append(probed, ip)
+			mu.Unlock()
+
+			// The user cancels during the third batch
+			if ip == "10.0.0.20" {
+				cancel()
+			}
+			return probeCtx.Err() == nil && ip == speaker
+		},
+	}
+
+	if _, err := s.scanFrom(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("scanFrom() error = %v, want it cancelled", err)
+	}
+	// The interrupted batch doesn't count as scanned
+	if s.cursor != 16 {
+		t.Fatalf("cursor after cancelling = %d, want 16", s.cursor)
+	}
+
+	probed = nil
+	ip, err := s.scanFrom(context.Background())
+	if err != nil || ip != speaker {
+		t.Fatalf("resumed scanFrom() = %q, %v; want %s", ip, err, speaker)
+	}
+	slices.SortFunc(probed, compareIPs)
+	if want := testCandidates(32)[16:]; !slices.Equal(probed, want) {
+		t.Errorf("resumed scan probed %v, want %v", probed, want)
+	}
+}
+
+func TestScanFromNotFound(t *testing.T) {
+	s := &Scanner{
+		Workers:    8,
+		candidates: testCandidates(16),
+		cursor:     8, // Resuming
+		Probe:      func(context.Context, string) bool { return false },
+	}
+
+	if _, err := s.scanFrom(context.Background()); err == nil {
+		t.Fatal("scanFrom() found a speaker")
+	}
+	// A completed scan starts over next time
+	if s.candidates != nil || s.cursor != 0 {
+		t.Errorf("progress kept after a full scan: cursor %d of %d", s.cursor, len(s.candidates))
+	}
+}
+
+func TestScannerReset(t *testing.T) {
+	s := &Scanner{candidates: testCandidates(16), cursor: 8}
+	s.Reset()
+	if s.candidates != nil || s.cursor != 0 {
+		t.Errorf("progress kept after Reset: cursor %d of %d", s.cursor, len(s.candidates))
+	}
+}
@@ -2,36 +2,120 @@ package discovery
 
 import (
 	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/inquire/kefbar-go/internal/api"
 )
 
 // SSDP constants.
 const (
 	ssdpMulticastAddr = "239.255.255.250:1900"
+
+	// descriptionTimeout bounds the device description fetch used to
+	// read the model, so a slow speaker doesn't delay discovery
+	descriptionTimeout = time.Second
 )
 
+// errMulticastSilent is returned when SSDP receives no packets at all, which
+// usually means multicast is filtered (e.g., on corporate networks or VPNs).
+var errMulticastSilent = errors.New("SSDP discovery received no packets - multicast may be blocked")
+
 // DiscoverViaSSDP attempts to find a KEF speaker using SSDP multicast.
 func DiscoverViaSSDP(ctx context.Context, timeout time.Duration) (string, error) {
-	multicastAddr, err := net.ResolveUDPAddr("udp4", ssdpMulticastAddr)
+	speaker, err := discoverViaSSDP(ctx, timeout, DefaultSSDPSilenceTimeout, NewScanner().Probe)
+	return speaker.IP, err
+}
+
+// discoverViaSSDP is DiscoverViaSSDP with an early exit when nothing has been
+// received within silence. Each responder must also pass confirm, which
+// checks its API so that other devices mentioning a model name are skipped.
+// The model is read from the device description advertised in the response,
+// when there is one.
+func discoverViaSSDP(ctx context.Context, timeout, silence time.Duration, confirm ProbeFunc) (Speaker, error) {
+	// Stop the per-interface listeners as soon as we return, including on
+	// an early silence exit
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Set once any packet other than our own (looped-back) M-SEARCH arrives
+	var received atomic.Bool
+
+	responses, err := ssdpSearch(ctx, timeout, &received)
 	if err != nil {
-		return "", err
+		return Speaker{}, err
 	}
 
-	interfaces, err := net.Interfaces()
+	timeoutTimer := time.NewTimer(timeout)
+	defer timeoutTimer.Stop()
+
+	var silenceC <-chan time.Time
+	if silence > 0 && silence < timeout {
+		silenceTimer := time.NewTimer(silence)
+		defer silenceTimer.Stop()
+		silenceC = silenceTimer.C
+	}
+
+	rejected := make(map[string]bool)
+	for {
+		select {
+		case res, ok := <-responses:
+			if !ok {
+				return Speaker{}, fmt.Errorf("SSDP discovery failed - no KEF device found")
+			}
+			if rejected[res.ip] {
+				continue
+			}
+			if !confirm(ctx, res.ip) {
+				slog.Debug("Ignoring SSDP responder without a KEF API", "ip", res.ip)
+				rejected[res.ip] = true
+				continue
+			}
+			_, model := describeDevice(ctx, res.location)
+			return Speaker{IP: res.ip, Model: model}, nil
+		case <-ctx.Done():
+			return Speaker{}, ctx.Err()
+		case <-silenceC:
+			if !received.Load() {
+				return Speaker{}, errMulticastSilent
+			}
+		case <-timeoutTimer.C:
+			return Speaker{}, fmt.Errorf("SSDP discovery timeout")
+		}
+	}
+}
+
+// ssdpResponse is a KEF device's answer to an M-SEARCH.
+type ssdpResponse struct {
+	ip       string
+	location string // Device description URL, if advertised
+}
+
+// ssdpSearch sends M-SEARCH requests on every interface and reports KEF
+// responses until ctx is done or timeout elapses. A device may be reported
+// more than once. The channel is closed once every listener has stopped.
+// received is set when any packet other than our own M-SEARCH arrives.
+func ssdpSearch(ctx context.Context, timeout time.Duration, received *atomic.Bool) (<-chan ssdpResponse, error) {
+	multicastAddr, err := net.ResolveUDPAddr("udp4", ssdpMulticastAddr)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	type result struct {
-		ip  string
-		err error
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
 	}
 
-	resultChan := make(chan result, 1)
+	responses := make(chan ssdpResponse)
 	var wg sync.WaitGroup
 
 	// Try each interface
@@ -88,36 +172,30 @@ func DiscoverViaSSDP(ctx context.Context, timeout time.Duration) (string, error)
 						continue
 					}
 
-					response := strings.ToUpper(string(buffer[:n]))
-					if isKEFDevice(response) {
+					raw := string(buffer[:n])
+					if strings.HasPrefix(strings.ToUpper(raw), "M-SEARCH") {
+						continue
+					}
+					received.Store(true)
+
+					if isKEFDevice(raw) {
 						select {
-						case resultChan <- result{ip: addr.IP.String(), err: nil}:
+						case responses <- ssdpResponse{ip: addr.IP.String(), location: headerValue(raw, "LOCATION")}:
 						case <-ctx.Done():
+							return
 						}
-						return
 					}
 				}
 			}
 		}(iface)
 	}
 
-	// Wait for first result or timeout
-	done := make(chan struct{})
 	go func() {
 		wg.Wait()
-		close(done)
+		close(responses)
 	}()
 
-	select {
-	case res := <-resultChan:
-		return res.ip, res.err
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case <-time.After(timeout):
-		return "", fmt.Errorf("SSDP discovery timeout")
-	case <-done:
-		return "", fmt.Errorf("SSDP discovery failed - no KEF device found")
-	}
+	return responses, nil
 }
 
 // buildMSearchRequest creates an SSDP M-SEARCH request.
@@ -130,9 +208,92 @@ func buildMSearchRequest(searchTarget string) string {
 		"\r\n"
 }
 
-// isKEFDevice checks if the SSDP response indicates a KEF device.
+// kefModelTokens are the brand and model names KEF speakers put in their
+// SSDP headers. Newer models may only name themselves, not the brand.
+var kefModelTokens = []string{"KEF", "LSX", "LS50", "LS60", "XIO", "CODA"}
+
+// kefHeaders are the SSDP headers that identify the device.
+var kefHeaders = []string{"SERVER", "USN", "NT", "ST", "X-USER-AGENT"}
+
+// isKEFDevice checks if the SSDP response may be from a KEF device: the
+// brand anywhere in it, or a model name in one of its identifying headers.
+// Matches are only a hint; see confirmSpeaker.
 func isKEFDevice(response string) bool {
-	return strings.Contains(response, "KEF") ||
-		strings.Contains(response, "LSX") ||
-		strings.Contains(response, "LS50")
+	if strings.Contains(strings.ToUpper(response), "KEF") {
+		return true
+	}
+	for _, header := range kefHeaders {
+		value := strings.ToUpper(headerValue(response, header))
+		for _, token := range kefModelTokens {
+			if strings.Contains(value, token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headerValue returns the value of an HTTP-style header in an SSDP
+// response, matching the name case-insensitively.
+func headerValue(response, name string) string {
+	for _, line := range strings.Split(response, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// descriptionClient fetches device descriptions over the shared connection
+// pool; each fetch has its own deadline.
+var descriptionClient = &http.Client{Transport: api.DefaultTransport}
+
+// describeDevice fetches the UPnP device description at location and returns
+// the name and model it gives, or empty strings if it can't be read.
+func describeDevice(ctx context.Context, location string) (name, model string) {
+	if location == "" {
+		return "", ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, descriptionTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return "", ""
+	}
+
+	resp, err := descriptionClient.Do(req)
+	if err != nil {
+		return "", ""
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", ""
+	}
+	return parseDescription(data)
+}
+
+// parseDescription extracts the friendly name and model from a UPnP device
+// description. Spaces are dropped from the model so it matches the
+// firmware's naming (e.g., "LSX II" -> "LSXII").
+func parseDescription(data []byte) (name, model string) {
+	var desc struct {
+		Device struct {
+			FriendlyName string `xml:"friendlyName"`
+			ModelName    string `xml:"modelName"`
+		} `xml:"device"`
+	}
+	if err := xml.Unmarshal(data, &desc); err != nil {
+		return "", ""
+	}
+	return strings.TrimSpace(desc.Device.FriendlyName),
+		strings.ReplaceAll(strings.TrimSpace(desc.Device.ModelName), " ", "")
 }
@@ -0,0 +1,102 @@
+package discovery
+
+import (
+	"strings"
+	"testing"
+)
+
+// searchReply joins header lines into an SSDP response as received.
+func searchReply(lines ...string) string {
+	return "HTTP/1.1 200 OK\r\n" + strings.Join(lines, "\r\n") + "\r\n\r\n"
+}
+
+func TestIsKEFDevice(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     bool
+	}{
+		{
+			name: "LSX II naming the brand",
+			response: searchReply(
+				"CACHE-CONTROL: max-age=1800",
+				"LOCATION: http://192.168.1.20:8080/description.xml",
+				"SERVER: Linux/4.9 UPnP/1.0 KEF LSXII/4.0.1",
+				"ST: urn:schemas-upnp-org:device:MediaRenderer:1",
+				"USN: uuid:7d2a7e0c-2f1b-4a0e-9c38-2c1f5e9a0b11::urn:schemas-upnp-org:device:MediaRenderer:1",
+			),
+			want: true,
+		},
+		{
+			name: "LS60 naming only the model",
+			response: searchReply(
+				"CACHE-CONTROL: max-age=1800",
+				"LOCATION: http://192.168.1.21:8080/description.xml",
+				"SERVER: Linux UPnP/1.0 LS60Wireless/3.2",
+				"ST: upnp:rootdevice",
+				"USN: uuid:0a9c5d4e-61f3-4b7e-8d2a-5e6f7a8b9c0d::upnp:rootdevice",
+			),
+			want: true,
+		},
+		{
+			name: "model in USN only",
+			response: searchReply(
+				"LOCATION: http://192.168.1.22:8080/description.xml",
+				"SERVER: Linux UPnP/1.0 StreamSDK/2.1",
+				"ST: upnp:rootdevice",
+				"USN: uuid:lsx2-1c2d3e4f5a6b::upnp:rootdevice",
+			),
+			want: true,
+		},
+		{
+			name: "model in SERVER only, lower case",
+			response: searchReply(
+				"SERVER: linux upnp/1.0 ls50w2/2.9",
+			),
+			want: true,
+		},
+		{
+			name: "Sonos",
+			response: searchReply(
+				"CACHE-CONTROL: max-age = 1800",
+				"LOCATION: http://192.168.1.40:1400/xml/device_description.xml",
+				"SERVER: Linux UPnP/1.0 Sonos/70.3-35220 (ZPS23)",
+				"ST: urn:schemas-upnp-org:device:ZonePlayer:1",
+				"USN: uuid:RINCON_48A6B8E1F2C301400::urn:schemas-upnp-org:device:ZonePlayer:1",
+			),
+			want: false,
+		},
+		{
+			name: "model name outside the identifying headers",
+			response: searchReply(
+				"LOCATION: http://192.168.1.41/coda/description.xml",
+				"SERVER: Linux UPnP/1.0 Hue/1.0",
+				"ST: upnp:rootdevice",
+				"USN: uuid:2f402f80-da50-11e1-9b23-001788255acc::upnp:rootdevice",
+			),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isKEFDevice(tt.response); got != tt.want {
+				t.Errorf("isKEFDevice() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderValue(t *testing.T) {
+	response := searchReply("Location: http://192.168.1.20:8080/description.xml", "SERVER:  KEF LSXII ")
+
+	if got := headerValue(response, "LOCATION"); got != "http://192.168.1.20:8080/description.xml" {
+		t.Errorf("headerValue(LOCATION) = %q", got)
+	}
+	if got := headerValue(response, "server"); got != "KEF LSXII" {
+		t.Errorf("headerValue(server) = %q", got)
+	}
+	if got := headerValue(response, "USN"); got != "" {
+		t.Errorf("headerValue(USN) = %q, want empty", got)
+	}
+}
@@ -2,26 +2,57 @@
 package hotkeys
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 
-	"github/com/inquire/kefbar-go/internal/config"
-	"github/com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/safe"
 	"golang.design/x/hotkey"
 )
 
 // Manager handles global hotkey registration.
 type Manager struct {
-	ctrl          *controller.Controller
-	cfg           *config.Config
-	hkUp          *hotkey.Hotkey
-	hkDown        *hotkey.Hotkey
-	hkPlayPause   *hotkey.Hotkey
-	mu            sync.Mutex
-	stopUp        chan struct{}
-	stopDown      chan struct{}
-	stopPlayPause chan struct{}
+	ctrl       *controller.Controller
+	cfg        *config.Config
+	mu         sync.Mutex
+	registered []*hotkey.Hotkey
+	stop       chan struct{}
+
+	// onAllFailed is called, once, when no hotkey could be registered
+	onAllFailed     func()
+	allFailedNotify sync.Once
+}
+
+// hotkeyAction is something a hotkey in config.Config.Hotkeys can do.
+type hotkeyAction struct {
+	name  string // config.Config.Hotkeys key
+	label string // for logs
+	run   func(m *Manager)
+
+	// check, if set, reports why the action can't be used with the config
+	check func(cfg *config.Config) error
+}
+
+// hotkeyActions lists what hotkeys can be bound to; a new hotkey is an
+// entry here.
+var hotkeyActions = []hotkeyAction{
+	{name: config.HotkeyVolumeUp, label: "volume up", run: (*Manager).volumeUp},
+	{name: config.HotkeyVolumeDown, label: "volume down", run: (*Manager).volumeDown},
+	{name: config.HotkeyPlayPause, label: "play/pause", run: (*Manager).playPause},
+	{name: config.HotkeySourceToggle, label: "source toggle", run: (*Manager).toggleSource, check: checkSourceToggle},
+	{name: config.HotkeyCycleSource, label: "cycle source", run: (*Manager).cycleSource, check: checkCycleSource},
+}
+
+// binding is a hotkey to register and what it runs.
+type binding struct {
+	label   string
+	binding config.HotkeyBinding
+	run     func()
 }
 
 // NewManager creates a new hotkey manager.
@@ -32,146 +63,247 @@ func NewManager(ctrl *controller.Controller, cfg *config.Config) *Manager {
 	}
 }
 
-// Register registers global hotkeys for playback control.
+// SetRegistrationFailedCallback sets a callback for when every hotkey fails
+// to register, which usually means macOS hasn't granted the permissions
+// hotkeys need. It is called at most once, however often hotkeys are
+// re-registered.
+func (m *Manager) SetRegistrationFailedCallback(cb func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onAllFailed = cb
+}
+
+// Register registers the global hotkeys in the config and those of macros.
 func (m *Manager) Register() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.stopUp = make(chan struct{})
-	m.stopDown = make(chan struct{})
-	m.stopPlayPause = make(chan struct{})
+	stop := make(chan struct{})
+	m.stop = stop
 
-	go m.registerVolumeUp()
-	go m.registerVolumeDown()
-	go m.registerPlayPause()
+	bindings := m.bindings()
+	tally := newRegistrationTally(len(bindings))
+	for _, b := range bindings {
+		safe.Go(b.label+" hotkey", func() { m.register(b, stop, tally) })
+	}
 }
 
-// Reregister unregisters and re-registers hotkeys with new config.
-func (m *Manager) Reregister() {
-	m.Unregister()
-	m.Register()
-}
+// bindings returns the hotkeys to register: the bound actions, the macros
+// that have a hotkey, then volume presets. Invalid bindings, and any that
+// reuse the combination of one before them, are left out.
+func (m *Manager) bindings() []binding {
+	known := make(map[string]bool, len(hotkeyActions))
+	var bindings []binding
+	add := func(b binding) {
+		if err := b.binding.Validate(); err != nil {
+			slog.Warn("Invalid hotkey", "hotkey", b.label, "error", err)
+			return
+		}
+		for _, other := range bindings {
+			if other.binding.SameCombo(b.binding) {
+				slog.Warn("Hotkey already bound", "hotkey", b.label, "binding", b.binding.String(), "bound_to", other.label)
+				return
+			}
+		}
+		bindings = append(bindings, b)
+	}
 
-// registerVolumeUp sets up the volume up hotkey.
-func (m *Manager) registerVolumeUp() {
-	modifiers := parseModifiers(m.cfg.VolumeUpHotkey.Modifiers)
-	key := parseKey(m.cfg.VolumeUpHotkey.Key)
+	for _, action := range hotkeyActions {
+		known[action.name] = true
 
-	if key == 0 {
-		slog.Warn("Invalid volume up key", "key", m.cfg.VolumeUpHotkey.Key)
-		return
+		hk, ok := m.cfg.Hotkeys[action.name]
+		if !ok || hk.Key == "" {
+			continue
+		}
+		if action.check != nil {
+			if err := action.check(m.cfg); err != nil {
+				slog.Warn("Skipping hotkey", "action", action.name, "error", err)
+				continue
+			}
+		}
+		add(binding{
+			label:   action.label,
+			binding: hk,
+			run:     func() { action.run(m) },
+		})
+	}
+
+	for name := range m.cfg.Hotkeys {
+		if !known[name] {
+			slog.Warn("Unknown hotkey action", "action", name)
+		}
 	}
 
-	m.hkUp = hotkey.New(modifiers, key)
+	for _, macro := range m.cfg.Macros {
+		if macro.Hotkey == nil {
+			continue
+		}
+		add(binding{
+			label:   "macro " + macro.Name,
+			binding: *macro.Hotkey,
+			run:     func() { m.runMacro(macro.Name) },
+		})
+	}
 
-	if err := m.hkUp.Register(); err != nil {
-		slog.Warn("Failed to register volume up hotkey", "error", err, "binding", m.cfg.VolumeUpHotkey.String())
+	for _, preset := range m.cfg.VolumePresets {
+		if preset.Level < 0 || preset.Level > 100 {
+			slog.Warn("Invalid volume preset level", "level", preset.Level)
+			continue
+		}
+		add(binding{
+			label:   fmt.Sprintf("volume preset %d", preset.Level),
+			binding: preset.Hotkey,
+			run:     func() { m.setVolume(preset.Level) },
+		})
+	}
+
+	return bindings
+}
+
+// report records a registration outcome in tally, calling the
+// registration failed callback if it shows that no hotkey works.
+func (m *Manager) report(tally *registrationTally, result registrationResult) {
+	if !tally.report(result) {
 		return
 	}
 
-	slog.Info("Registered volume up hotkey", "binding", m.cfg.VolumeUpHotkey.String())
+	slog.Warn("No hotkeys could be registered; check the Accessibility and Input Monitoring permissions")
 
-	for {
-		select {
-		case <-m.stopUp:
-			return
-		case <-m.hkUp.Keydown():
-			state := m.ctrl.GetState()
-			if !state.Connected {
-				continue
-			}
+	m.mu.Lock()
+	cb := m.onAllFailed
+	m.mu.Unlock()
 
-			oldVol := state.Volume
-			if err := m.ctrl.VolumeUp(); err != nil {
-				slog.Error("Failed to increase volume via hotkey", "error", err)
-			} else {
-				newState := m.ctrl.GetState()
-				slog.Info("Volume changed via hotkey", "old", oldVol, "new", newState.Volume)
-			}
-		}
+	if cb != nil {
+		m.allFailedNotify.Do(cb)
 	}
 }
 
-// registerVolumeDown sets up the volume down hotkey.
-func (m *Manager) registerVolumeDown() {
-	modifiers := parseModifiers(m.cfg.VolumeDownHotkey.Modifiers)
-	key := parseKey(m.cfg.VolumeDownHotkey.Key)
+// Reregister unregisters and re-registers hotkeys with new config.
+func (m *Manager) Reregister() {
+	m.Unregister()
+	m.Register()
+}
 
-	if key == 0 {
-		slog.Warn("Invalid volume down key", "key", m.cfg.VolumeDownHotkey.Key)
+// register sets up one hotkey and runs it on each press while a speaker is
+// connected, until stop is closed.
+func (m *Manager) register(b binding, stop chan struct{}, tally *registrationTally) {
+	code, ok := parseKey(b.binding.Key)
+	if !ok {
+		slog.Warn("Invalid hotkey key", "hotkey", b.label, "key", b.binding.Key)
+		m.report(tally, registrationSkipped)
 		return
 	}
 
-	m.hkDown = hotkey.New(modifiers, key)
+	modifiers := parseModifiers(b.binding.Modifiers)
+	if code.shift && !slices.Contains(modifiers, hotkey.ModShift) {
+		modifiers = append(modifiers, hotkey.ModShift)
+	}
+
+	hk := hotkey.New(modifiers, code.key)
+
+	if err := hk.Register(); err != nil {
+		slog.Warn("Failed to register hotkey", "hotkey", b.label, "error", err, "binding", b.binding.String())
+		m.report(tally, registrationFailed)
+		return
+	}
 
-	if err := m.hkDown.Register(); err != nil {
-		slog.Warn("Failed to register volume down hotkey", "error", err, "binding", m.cfg.VolumeDownHotkey.String())
+	// Unregister may have run while this was registering
+	m.mu.Lock()
+	select {
+	case <-stop:
+		m.mu.Unlock()
+		_ = hk.Unregister()
 		return
+	default:
 	}
+	m.registered = append(m.registered, hk)
+	m.mu.Unlock()
 
-	slog.Info("Registered volume down hotkey", "binding", m.cfg.VolumeDownHotkey.String())
+	slog.Info("Registered hotkey", "hotkey", b.label, "binding", b.binding.String())
+	m.report(tally, registrationSucceeded)
 
 	for {
 		select {
-		case <-m.stopDown:
+		case <-stop:
 			return
-		case <-m.hkDown.Keydown():
-			state := m.ctrl.GetState()
-			if !state.Connected {
+		case <-hk.Keydown():
+			if !m.ctrl.GetState().Connected {
 				continue
 			}
-
-			oldVol := state.Volume
-			if err := m.ctrl.VolumeDown(); err != nil {
-				slog.Error("Failed to decrease volume via hotkey", "error", err)
-			} else {
-				newState := m.ctrl.GetState()
-				slog.Info("Volume changed via hotkey", "old", oldVol, "new", newState.Volume)
-			}
+			b.run()
 		}
 	}
 }
 
-// registerPlayPause sets up the play/pause hotkey.
-func (m *Manager) registerPlayPause() {
-	modifiers := parseModifiers(m.cfg.PlayPauseHotkey.Modifiers)
-	key := parseKey(m.cfg.PlayPauseHotkey.Key)
+// volumeUp steps the volume up. The step is queued, so a slow speaker
+// can't hold up the next key press.
+func (m *Manager) volumeUp() {
+	m.ctrl.QueueVolumeStep(m.cfg.VolumeStep)
+}
+
+// volumeDown steps the volume down, queued like volumeUp.
+func (m *Manager) volumeDown() {
+	m.ctrl.QueueVolumeStep(-m.cfg.VolumeStep)
+}
 
-	if key == 0 {
-		slog.Warn("Invalid play/pause key", "key", m.cfg.PlayPauseHotkey.Key)
+// playPause toggles playback.
+func (m *Manager) playPause() {
+	wasPlaying := m.ctrl.IsPlaying()
+	if err := m.ctrl.PlayPause(); err != nil {
+		slog.Error("Failed to toggle play/pause via hotkey", "error", err)
 		return
 	}
+	if wasPlaying {
+		slog.Info("Paused via hotkey")
+	} else {
+		slog.Info("Playing via hotkey")
+	}
+}
 
-	m.hkPlayPause = hotkey.New(modifiers, key)
+// toggleSource flips between the two favorite sources.
+func (m *Manager) toggleSource() {
+	if err := m.ctrl.ToggleBetweenSources(m.cfg.SourceToggleA, m.cfg.SourceToggleB); err != nil {
+		slog.Error("Failed to toggle source via hotkey", "error", err)
+	}
+}
 
-	if err := m.hkPlayPause.Register(); err != nil {
-		slog.Warn("Failed to register play/pause hotkey", "error", err, "binding", m.cfg.PlayPauseHotkey.String())
-		return
+// checkSourceToggle requires both favorite sources for the source toggle.
+func checkSourceToggle(cfg *config.Config) error {
+	if cfg.SourceToggleA == "" || cfg.SourceToggleB == "" {
+		return errors.New("source toggle hotkey needs both source_toggle_a and source_toggle_b")
+	}
+	return nil
+}
+
+// cycleSource steps to the next source in the cycle.
+func (m *Manager) cycleSource() {
+	if err := m.ctrl.CycleSource(m.cfg.CycleSources); err != nil {
+		slog.Error("Failed to cycle source via hotkey", "error", err)
 	}
+}
 
-	slog.Info("Registered play/pause hotkey", "binding", m.cfg.PlayPauseHotkey.String())
+// checkCycleSource requires sources to cycle through.
+func checkCycleSource(cfg *config.Config) error {
+	if len(cfg.CycleSources) < 2 {
+		return errors.New("cycle source hotkey needs at least two cycle_sources")
+	}
+	return nil
+}
 
-	for {
-		select {
-		case <-m.stopPlayPause:
-			return
-		case <-m.hkPlayPause.Keydown():
-			state := m.ctrl.GetState()
-			if !state.Connected {
-				continue
-			}
+// setVolume jumps to a volume preset.
+func (m *Manager) setVolume(level int) {
+	if err := m.ctrl.SetVolume(level); err != nil {
+		slog.Error("Failed to set volume preset via hotkey", "level", level, "error", err)
+		return
+	}
+	slog.Info("Volume preset set via hotkey", "level", m.ctrl.GetState().Volume)
+}
 
-			wasPlaying := m.ctrl.IsPlaying()
-			if err := m.ctrl.PlayPause(); err != nil {
-				slog.Error("Failed to toggle play/pause via hotkey", "error", err)
-			} else {
-				if wasPlaying {
-					slog.Info("Paused via hotkey")
-				} else {
-					slog.Info("Playing via hotkey")
-				}
-			}
-		}
+// runMacro runs the named macro.
+func (m *Manager) runMacro(name string) {
+	if err := m.ctrl.RunMacro(name); err != nil {
+		slog.Error("Macro failed via hotkey", "macro", name, "error", err)
 	}
 }
 
@@ -180,28 +312,15 @@ func (m *Manager) Unregister() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.stopUp != nil {
-		close(m.stopUp)
-	}
-	if m.stopDown != nil {
-		close(m.stopDown)
-	}
-	if m.stopPlayPause != nil {
-		close(m.stopPlayPause)
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
 	}
 
-	if m.hkUp != nil {
-		_ = m.hkUp.Unregister()
-		m.hkUp = nil
-	}
-	if m.hkDown != nil {
-		_ = m.hkDown.Unregister()
-		m.hkDown = nil
-	}
-	if m.hkPlayPause != nil {
-		_ = m.hkPlayPause.Unregister()
-		m.hkPlayPause = nil
+	for _, hk := range m.registered {
+		_ = hk.Unregister()
 	}
+	m.registered = nil
 }
 
 // parseModifiers converts a modifier string to hotkey modifiers.
@@ -224,65 +343,3 @@ func parseModifiers(s string) []hotkey.Modifier {
 
 	return mods
 }
-
-// parseKey converts a key string to a hotkey key.
-func parseKey(s string) hotkey.Key {
-	switch strings.ToLower(s) {
-	case "up":
-		return hotkey.KeyUp
-	case "down":
-		return hotkey.KeyDown
-	case "left":
-		return hotkey.KeyLeft
-	case "right":
-		return hotkey.KeyRight
-	case "p":
-		return hotkey.Key('P')
-	case "s":
-		return hotkey.Key('S')
-	case ">":
-		return hotkey.Key('>')
-	case "<":
-		return hotkey.Key('<')
-	case ".":
-		return hotkey.Key('.')
-	case ",":
-		return hotkey.Key(',')
-	case "space":
-		return hotkey.KeySpace
-	case "f1":
-		return hotkey.KeyF1
-	case "f2":
-		return hotkey.KeyF2
-	case "f3":
-		return hotkey.KeyF3
-	case "f4":
-		return hotkey.KeyF4
-	case "f5":
-		return hotkey.KeyF5
-	case "f6":
-		return hotkey.KeyF6
-	case "f7":
-		return hotkey.KeyF7
-	case "f8":
-		return hotkey.KeyF8
-	case "f9":
-		return hotkey.KeyF9
-	case "f10":
-		return hotkey.KeyF10
-	case "f11":
-		return hotkey.KeyF11
-	case "f12":
-		return hotkey.KeyF12
-	case "[":
-		return hotkey.Key('[')
-	case "]":
-		return hotkey.Key(']')
-	case "=":
-		return hotkey.Key('=')
-	case "-":
-		return hotkey.Key('-')
-	default:
-		return 0
-	}
-}
@@ -0,0 +1,112 @@
+package hotkeys
+
+import (
+	"strings"
+
+	"golang.design/x/hotkey"
+)
+
+// keyCode is the key a key name stands for. shift is set for names like
+// ">" that are typed with Shift.
+type keyCode struct {
+	key   hotkey.Key
+	shift bool
+}
+
+// keyCodes maps the lower-cased config.AvailableKeys names to keys.
+// hotkey.Key values are macOS virtual key codes rather than characters, so
+// punctuation and the keypad, which the hotkey package has no constants
+// for, use the kVK_* codes from Carbon's Events.h.
+var keyCodes = map[string]keyCode{
+	"up":    {key: hotkey.KeyUp},
+	"down":  {key: hotkey.KeyDown},
+	"left":  {key: hotkey.KeyLeft},
+	"right": {key: hotkey.KeyRight},
+	"space": {key: hotkey.KeySpace},
+
+	"a": {key: hotkey.KeyA},
+	"b": {key: hotkey.KeyB},
+	"c": {key: hotkey.KeyC},
+	"d": {key: hotkey.KeyD},
+	"e": {key: hotkey.KeyE},
+	"f": {key: hotkey.KeyF},
+	"g": {key: hotkey.KeyG},
+	"h": {key: hotkey.KeyH},
+	"i": {key: hotkey.KeyI},
+	"j": {key: hotkey.KeyJ},
+	"k": {key: hotkey.KeyK},
+	"l": {key: hotkey.KeyL},
+	"m": {key: hotkey.KeyM},
+	"n": {key: hotkey.KeyN},
+	"o": {key: hotkey.KeyO},
+	"p": {key: hotkey.KeyP},
+	"q": {key: hotkey.KeyQ},
+	"r": {key: hotkey.KeyR},
+	"s": {key: hotkey.KeyS},
+	"t": {key: hotkey.KeyT},
+	"u": {key: hotkey.KeyU},
+	"v": {key: hotkey.KeyV},
+	"w": {key: hotkey.KeyW},
+	"x": {key: hotkey.KeyX},
+	"y": {key: hotkey.KeyY},
+	"z": {key: hotkey.KeyZ},
+
+	"0": {key: hotkey.Key0},
+	"1": {key: hotkey.Key1},
+	"2": {key: hotkey.Key2},
+	"3": {key: hotkey.Key3},
+	"4": {key: hotkey.Key4},
+	"5": {key: hotkey.Key5},
+	"6": {key: hotkey.Key6},
+	"7": {key: hotkey.Key7},
+	"8": {key: hotkey.Key8},
+	"9": {key: hotkey.Key9},
+
+	"num0": {key: 0x52},
+	"num1": {key: 0x53},
+	"num2": {key: 0x54},
+	"num3": {key: 0x55},
+	"num4": {key: 0x56},
+	"num5": {key: 0x57},
+	"num6": {key: 0x58},
+	"num7": {key: 0x59},
+	"num8": {key: 0x5B},
+	"num9": {key: 0x5C},
+	"num+": {key: 0x45},
+	"num-": {key: 0x4E},
+	"num*": {key: 0x43},
+	"num/": {key: 0x4B},
+
+	".":  {key: 0x2F},
+	",":  {key: 0x2B},
+	">":  {key: 0x2F, shift: true},
+	"<":  {key: 0x2B, shift: true},
+	"[":  {key: 0x21},
+	"]":  {key: 0x1E},
+	"=":  {key: 0x18},
+	"-":  {key: 0x1B},
+	";":  {key: 0x29},
+	"'":  {key: 0x27},
+	"/":  {key: 0x2C},
+	"\\": {key: 0x2A},
+	"`":  {key: 0x32},
+
+	"f1":  {key: hotkey.KeyF1},
+	"f2":  {key: hotkey.KeyF2},
+	"f3":  {key: hotkey.KeyF3},
+	"f4":  {key: hotkey.KeyF4},
+	"f5":  {key: hotkey.KeyF5},
+	"f6":  {key: hotkey.KeyF6},
+	"f7":  {key: hotkey.KeyF7},
+	"f8":  {key: hotkey.KeyF8},
+	"f9":  {key: hotkey.KeyF9},
+	"f10": {key: hotkey.KeyF10},
+	"f11": {key: hotkey.KeyF11},
+	"f12": {key: hotkey.KeyF12},
+}
+
+// parseKey looks up the key for a key name, ignoring case.
+func parseKey(s string) (keyCode, bool) {
+	code, ok := keyCodes[strings.ToLower(strings.TrimSpace(s))]
+	return code, ok
+}
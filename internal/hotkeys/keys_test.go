@@ -0,0 +1,30 @@
+//go:build darwin
+
+package hotkeys
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/inquire/kefbar-go/internal/config"
+)
+
+func TestAvailableKeysMapped(t *testing.T) {
+	seen := make(map[keyCode]string)
+	for _, name := range config.AvailableKeys {
+		code, ok := parseKey(name)
+		if !ok {
+			t.Errorf("key %q has no key code", name)
+			continue
+		}
+		// A is the only key whose code is 0, so a zero anywhere else is
+		// an entry that was never filled in
+		if code.key == 0 && !strings.EqualFold(name, "a") {
+			t.Errorf("key %q maps to key code 0", name)
+		}
+		if other, dup := seen[code]; dup {
+			t.Errorf("keys %q and %q map to the same key", other, name)
+		}
+		seen[code] = name
+	}
+}
@@ -0,0 +1,46 @@
+package hotkeys
+
+import "sync"
+
+// registrationResult is the outcome of setting up one hotkey.
+type registrationResult int
+
+const (
+	registrationSkipped registrationResult = iota // invalid binding, never tried
+	registrationFailed
+	registrationSucceeded
+)
+
+// registrationTally collects the outcomes of one Register call, to tell a
+// few bad bindings apart from the system refusing hotkeys altogether.
+type registrationTally struct {
+	mu        sync.Mutex
+	pending   int
+	failed    int
+	succeeded int
+}
+
+// newRegistrationTally expects n outcomes.
+func newRegistrationTally(n int) *registrationTally {
+	return &registrationTally{pending: n}
+}
+
+// report records an outcome. It returns true for the last outcome if every
+// hotkey that was tried failed to register.
+func (t *registrationTally) report(result registrationResult) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == 0 {
+		return false
+	}
+	t.pending--
+
+	switch result {
+	case registrationFailed:
+		t.failed++
+	case registrationSucceeded:
+		t.succeeded++
+	}
+	return t.pending == 0 && t.failed > 0 && t.succeeded == 0
+}
@@ -0,0 +1,76 @@
+//go:build darwin
+
+package hotkeys
+
+import "testing"
+
+func TestRegistrationTally(t *testing.T) {
+	const (
+		skipped   = registrationSkipped
+		failed    = registrationFailed
+		succeeded = registrationSucceeded
+	)
+	tests := []struct {
+		name    string
+		results []registrationResult
+		want    bool // All tried failed
+	}{
+		{"all failed", []registrationResult{failed, failed, failed}, true},
+		{"failed and skipped", []registrationResult{skipped, failed, skipped}, true},
+		{"skipped only", []registrationResult{skipped, skipped}, false},
+		{"mixed", []registrationResult{failed, succeeded, failed}, false},
+		{"all succeeded", []registrationResult{succeeded, succeeded}, false},
+		{"one failed", []registrationResult{failed}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tally := newRegistrationTally(len(tt.results))
+			for i, result := range tt.results {
+				got := tally.report(result)
+				last := i == len(tt.results)-1
+				if last && got != tt.want {
+					t.Errorf("last report() = %t, want %t", got, tt.want)
+				}
+				if !last && got {
+					t.Errorf("report() %d of %d = true before every outcome was in", i+1, len(tt.results))
+				}
+			}
+
+			// Reports past the expected count are ignored
+			if tally.report(failed) {
+				t.Error("report() after the last outcome = true")
+			}
+		})
+	}
+}
+
+func TestAllFailedAlertOnce(t *testing.T) {
+	m := &Manager{}
+	calls := 0
+	m.SetRegistrationFailedCallback(func() { calls++ })
+
+	// Hotkeys are re-registered whenever the config changes; the alert
+	// only comes up the first time they all fail
+	for range 3 {
+		tally := newRegistrationTally(2)
+		m.report(tally, registrationFailed)
+		m.report(tally, registrationFailed)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times, want once", calls)
+	}
+}
+
+func TestAllFailedAlertNotForPartialFailure(t *testing.T) {
+	m := &Manager{}
+	calls := 0
+	m.SetRegistrationFailedCallback(func() { calls++ })
+
+	tally := newRegistrationTally(2)
+	m.report(tally, registrationFailed)
+	m.report(tally, registrationSucceeded)
+	if calls != 0 {
+		t.Errorf("callback called %d times with a hotkey registered, want never", calls)
+	}
+}
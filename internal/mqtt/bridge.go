@@ -0,0 +1,309 @@
+// Package mqtt bridges the speaker to an MQTT broker, so home automation
+// such as Home Assistant can show its state and control it.
+//
+// Under the topic prefix (default "kefbar") the bridge publishes retained
+// state topics:
+//
+//	<prefix>/status     "online" or "offline"
+//	<prefix>/connected  "true" or "false"
+//	<prefix>/volume     0-100
+//	<prefix>/muted      "true" or "false"
+//	<prefix>/source     e.g., "wifi"
+//	<prefix>/playback   PlaybackInfo as JSON, or null
+//
+// and accepts commands on:
+//
+//	<prefix>/volume/set  0-100, or +N/-N to step
+//	<prefix>/muted/set   "true", "false" or "toggle"
+//	<prefix>/source/set  a source name
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// Connection timing.
+const (
+	keepAlive       = 30 * time.Second
+	retryMinDelay   = 2 * time.Second
+	retryMaxDelay   = 2 * time.Minute
+	shutdownTimeout = 2 * time.Second
+)
+
+// Availability payloads for the status topic.
+const (
+	statusOnline  = "online"
+	statusOffline = "offline"
+)
+
+// Bridge publishes speaker state to an MQTT broker and applies commands
+// received from it. A Bridge without a broker URL does nothing.
+type Bridge struct {
+	ctrl     *controller.Controller
+	url      string
+	prefix   string
+	username string
+	password string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// Last payload published per topic, so only changes are sent
+	published map[string]string
+}
+
+// New creates a bridge from the MQTT settings in cfg.
+func New(ctrl *controller.Controller, cfg *config.Config) *Bridge {
+	prefix := strings.Trim(cfg.MQTTTopicPrefix, "/")
+	if prefix == "" {
+		prefix = config.DefaultMQTTPrefix
+	}
+
+	return &Bridge{
+		ctrl:     ctrl,
+		url:      cfg.MQTTBrokerURL,
+		prefix:   prefix,
+		username: cfg.MQTTUsername,
+		password: cfg.MQTTPassword,
+	}
+}
+
+// Start connects to the broker in the background, reconnecting with
+// backoff whenever the connection drops. It does nothing if no broker is
+// configured.
+func (b *Bridge) Start() {
+	if b.url == "" || b.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	updates := b.ctrl.Subscribe()
+	safe.Go("mqtt bridge", func() {
+		defer close(b.done)
+		b.run(ctx, updates)
+	})
+}
+
+// Stop marks the bridge offline and disconnects, waiting briefly for the
+// broker to be told.
+func (b *Bridge) Stop() {
+	if b.cancel == nil {
+		return
+	}
+
+	b.cancel()
+	select {
+	case <-b.done:
+	case <-time.After(shutdownTimeout):
+		slog.Warn("MQTT bridge did not stop in time")
+	}
+}
+
+// run keeps a broker session going until ctx is done.
+func (b *Bridge) run(ctx context.Context, updates <-chan kef.SpeakerState) {
+	delay := retryMinDelay
+	for {
+		start := time.Now()
+		err := b.session(ctx, updates)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A session that lasted a while was healthy; retry promptly
+		if time.Since(start) > retryMaxDelay {
+			delay = retryMinDelay
+		}
+		slog.Warn("MQTT bridge disconnected", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// session connects, publishes the current state and then serves commands
+// and state changes until the connection fails or ctx is done.
+func (b *Bridge) session(ctx context.Context, updates <-chan kef.SpeakerState) error {
+	c, err := dial(ctx, b.url, connectOptions{
+		clientID:    clientID(),
+		username:    b.username,
+		password:    b.password,
+		keepAlive:   keepAlive,
+		willTopic:   b.topic("status"),
+		willPayload: statusOffline,
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("MQTT bridge connected", "broker", b.url, "prefix", b.prefix)
+
+	sessCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Closing the connection ends Serve. On shutdown, say goodbye first so
+	// the status doesn't wait for the broker to notice.
+	go func() {
+		<-sessCtx.Done()
+		if ctx.Err() != nil {
+			_ = c.Publish(b.topic("status"), []byte(statusOffline), true)
+		}
+		_ = c.Close()
+	}()
+
+	// State may have changed while disconnected, so republish all of it
+	b.published = make(map[string]string)
+	if err := c.Publish(b.topic("status"), []byte(statusOnline), true); err != nil {
+		return err
+	}
+	if err := b.publishState(c, b.ctrl.GetState()); err != nil {
+		return err
+	}
+	if err := c.Subscribe(b.topic("volume/set"), b.topic("muted/set"), b.topic("source/set")); err != nil {
+		return err
+	}
+
+	publisherDone := make(chan struct{})
+	go func() {
+		defer close(publisherDone)
+		for {
+			select {
+			case <-sessCtx.Done():
+				return
+			case state, ok := <-updates:
+				if !ok {
+					return
+				}
+				if err := b.publishState(c, state); err != nil {
+					// Serve notices the broken connection and ends the session
+					slog.Debug("MQTT publish failed", "error", err)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = c.Serve(b.handleCommand)
+
+	// Don't let the next session's publisher overlap this one's
+	cancel()
+	<-publisherDone
+	return err
+}
+
+// publishState publishes each state topic whose value changed.
+func (b *Bridge) publishState(c *client, state kef.SpeakerState) error {
+	playback, err := json.Marshal(state.PlaybackInfo)
+	if err != nil {
+		return fmt.Errorf("failed to encode playback info: %v", err)
+	}
+
+	values := []struct{ name, payload string }{
+		{"connected", strconv.FormatBool(state.Connected)},
+		{"volume", strconv.Itoa(state.Volume)},
+		{"muted", strconv.FormatBool(state.Muted)},
+		{"source", state.Source},
+		{"playback", string(playback)},
+	}
+	for _, v := range values {
+		topic := b.topic(v.name)
+		if last, ok := b.published[topic]; ok && last == v.payload {
+			continue
+		}
+		if err := c.Publish(topic, []byte(v.payload), true); err != nil {
+			return err
+		}
+		b.published[topic] = v.payload
+	}
+	return nil
+}
+
+// handleCommand applies a message received on a command topic.
+func (b *Bridge) handleCommand(topic string, payload []byte) {
+	value := strings.TrimSpace(string(payload))
+	slog.Info("MQTT command received", "topic", topic, "value", value)
+
+	var err error
+	switch topic {
+	case b.topic("volume/set"):
+		err = b.setVolume(value)
+	case b.topic("muted/set"):
+		err = b.setMute(value)
+	case b.topic("source/set"):
+		source := strings.ToLower(value)
+		if !slices.Contains(kef.Sources, source) {
+			err = fmt.Errorf("unknown source %q", value)
+		} else {
+			err = b.ctrl.SetSource(source)
+		}
+	default:
+		return
+	}
+
+	if err != nil {
+		slog.Warn("MQTT command failed", "topic", topic, "value", value, "error", err)
+	}
+}
+
+// setVolume sets the volume, or steps it when value has a leading sign.
+func (b *Bridge) setVolume(value string) error {
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid volume %q", value)
+	}
+
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		return b.ctrl.AdjustVolume(level)
+	}
+	if level < 0 || level > 100 {
+		return fmt.Errorf("volume %d out of range", level)
+	}
+	return b.ctrl.SetVolume(level)
+}
+
+// setMute sets or toggles mute.
+func (b *Bridge) setMute(value string) error {
+	switch strings.ToLower(value) {
+	case "toggle":
+		return b.ctrl.ToggleMute()
+	case "true", "on", "1":
+		return b.ctrl.SetMute(true)
+	case "false", "off", "0":
+		return b.ctrl.SetMute(false)
+	default:
+		return fmt.Errorf("invalid mute value %q", value)
+	}
+}
+
+// topic returns the full topic name for a path under the prefix.
+func (b *Bridge) topic(path string) string {
+	return b.prefix + "/" + path
+}
+
+// clientID identifies this Mac to the broker.
+func clientID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "kefbar"
+	}
+	return "kefbar-" + host
+}
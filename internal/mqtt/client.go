@@ -0,0 +1,218 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// connectTimeout bounds dialling the broker and waiting for CONNACK.
+const connectTimeout = 10 * time.Second
+
+// connAckReasons explains non-zero CONNACK return codes.
+var connAckReasons = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client ID rejected",
+	3: "server unavailable",
+	4: "bad username or password",
+	5: "not authorized",
+}
+
+// connectOptions configures a broker connection.
+type connectOptions struct {
+	clientID    string
+	username    string
+	password    string
+	keepAlive   time.Duration
+	willTopic   string // Retained message the broker publishes if we drop off
+	willPayload string
+}
+
+// client is a minimal MQTT 3.1.1 client supporting QoS 0 only, which is all
+// the bridge needs.
+type client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	keepAlive time.Duration
+
+	writeMu sync.Mutex
+}
+
+// dial connects to the broker at rawURL (tcp://, mqtt://, ssl://, tls:// or
+// mqtts://) and completes the MQTT handshake.
+func dial(ctx context.Context, rawURL string, opts connectOptions) (*client, error) {
+	addr, useTLS, err := brokerAddress(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	if useTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: hostOnly(addr)}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %v", err)
+	}
+
+	c := &client{conn: conn, reader: bufio.NewReader(conn), keepAlive: opts.keepAlive}
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if err := c.handshake(opts); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return c, nil
+}
+
+// handshake sends CONNECT and checks the broker's CONNACK.
+func (c *client) handshake(opts connectOptions) error {
+	if err := c.write(connectPacket(opts)); err != nil {
+		return fmt.Errorf("failed to send connect: %v", err)
+	}
+
+	p, err := readPacket(c.reader)
+	if err != nil {
+		return fmt.Errorf("failed to read connack: %v", err)
+	}
+	if p.kind != packetConnAck || len(p.body) < 2 {
+		return fmt.Errorf("unexpected packet %d during connect", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		reason, ok := connAckReasons[code]
+		if !ok {
+			reason = fmt.Sprintf("code %d", code)
+		}
+		return fmt.Errorf("broker refused connection: %s", reason)
+	}
+	return nil
+}
+
+// brokerAddress turns a broker URL into a host:port and whether to use TLS.
+func brokerAddress(rawURL string) (addr string, useTLS bool, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid broker URL: %v", err)
+	}
+
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported broker URL scheme %q", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return "", false, fmt.Errorf("broker URL has no host")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// hostOnly strips the port from addr.
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// write sends a packet. It is safe for concurrent use.
+func (c *client) write(p packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(connectTimeout))
+	_, err := c.conn.Write(p.encode())
+	return err
+}
+
+// Publish sends a QoS 0 message.
+func (c *client) Publish(topic string, payload []byte, retain bool) error {
+	return c.write(publishPacket(topic, payload, retain))
+}
+
+// Subscribe asks the broker for messages on the given topics.
+func (c *client) Subscribe(topics ...string) error {
+	return c.write(subscribePacket(1, topics))
+}
+
+// Serve reads from the broker, passing published messages to onMessage and
+// pinging to keep the connection alive. It returns when the connection
+// fails or is closed.
+func (c *client) Serve(onMessage func(topic string, payload []byte)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if c.keepAlive > 0 {
+		go c.pingLoop(ctx)
+	}
+
+	for {
+		if c.keepAlive > 0 {
+			// The broker answers pings, so silence this long means it's gone
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		}
+
+		p, err := readPacket(c.reader)
+		if err != nil {
+			return fmt.Errorf("connection to broker lost: %v", err)
+		}
+
+		switch p.kind {
+		case packetPublish:
+			topic, payload, err := parsePublish(p)
+			if err != nil {
+				return err
+			}
+			onMessage(topic, payload)
+		case packetSubAck, packetPingResp:
+		default:
+			return fmt.Errorf("unexpected packet %d from broker", p.kind)
+		}
+	}
+}
+
+// pingLoop sends a PINGREQ twice per keep-alive period until ctx is done.
+func (c *client) pingLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.write(packet{kind: packetPingReq}); err != nil {
+				_ = c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// Close disconnects cleanly, so the broker doesn't publish the will.
+func (c *client) Close() error {
+	_ = c.write(packet{kind: packetDisconnect})
+	return c.conn.Close()
+}
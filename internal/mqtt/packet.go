@@ -0,0 +1,179 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types, in the high nibble of the first byte.
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetSubscribe  = 8
+	packetSubAck     = 9
+	packetPingReq    = 12
+	packetPingResp   = 13
+	packetDisconnect = 14
+)
+
+// CONNECT flags.
+const (
+	connectCleanSession = 0x02
+	connectWill         = 0x04
+	connectWillRetain   = 0x20
+	connectPassword     = 0x40
+	connectUsername     = 0x80
+)
+
+// maxPacketSize caps incoming packets; the bridge only receives short
+// command payloads.
+const maxPacketSize = 64 << 10
+
+// packet is a raw control packet.
+type packet struct {
+	kind  byte // packet type
+	flags byte // low nibble of the first byte
+	body  []byte
+}
+
+// encode serializes the packet with its fixed header.
+func (p packet) encode() []byte {
+	buf := []byte{p.kind<<4 | p.flags&0x0f}
+	buf = appendLength(buf, len(p.body))
+	return append(buf, p.body...)
+}
+
+// appendLength appends the variable-length remaining-length encoding of n.
+func appendLength(buf []byte, n int) []byte {
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			return buf
+		}
+	}
+}
+
+// appendString appends a length-prefixed UTF-8 string.
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// readPacket reads one control packet.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, fmt.Errorf("malformed packet length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("packet too large (%d bytes)", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// connectPacket builds a CONNECT packet. A zero keepAlive disables pings.
+func connectPacket(opts connectOptions) packet {
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+
+	flags := byte(connectCleanSession)
+	if opts.willTopic != "" {
+		flags |= connectWill | connectWillRetain
+	}
+	if opts.username != "" {
+		flags |= connectUsername
+		if opts.password != "" {
+			flags |= connectPassword
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.keepAlive.Seconds()))
+
+	body = appendString(body, opts.clientID)
+	if opts.willTopic != "" {
+		body = appendString(body, opts.willTopic)
+		body = appendString(body, opts.willPayload)
+	}
+	if opts.username != "" {
+		body = appendString(body, opts.username)
+		if opts.password != "" {
+			body = appendString(body, opts.password)
+		}
+	}
+	return packet{kind: packetConnect, body: body}
+}
+
+// publishPacket builds a QoS 0 PUBLISH packet.
+func publishPacket(topic string, payload []byte, retain bool) packet {
+	var flags byte
+	if retain {
+		flags = 0x01
+	}
+	body := appendString(nil, topic)
+	return packet{kind: packetPublish, flags: flags, body: append(body, payload...)}
+}
+
+// subscribePacket builds a SUBSCRIBE packet requesting QoS 0 for each topic.
+func subscribePacket(id uint16, topics []string) packet {
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, topic := range topics {
+		body = appendString(body, topic)
+		body = append(body, 0) // QoS 0
+	}
+	// SUBSCRIBE requires the reserved flags to be 0010
+	return packet{kind: packetSubscribe, flags: 0x02, body: body}
+}
+
+// parsePublish extracts the topic and payload of an incoming PUBLISH. The
+// bridge subscribes at QoS 0, so the broker never sends anything needing an
+// acknowledgement.
+func parsePublish(p packet) (topic string, payload []byte, err error) {
+	body := p.body
+	if len(body) < 2 {
+		return "", nil, fmt.Errorf("malformed publish")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	body = body[2:]
+	if len(body) < n {
+		return "", nil, fmt.Errorf("malformed publish topic")
+	}
+	topic, body = string(body[:n]), body[n:]
+
+	// Skip the packet ID the broker would add for a higher QoS
+	if qos := (p.flags >> 1) & 0x03; qos > 0 {
+		if len(body) < 2 {
+			return "", nil, fmt.Errorf("malformed publish packet ID")
+		}
+		body = body[2:]
+	}
+	return topic, body, nil
+}
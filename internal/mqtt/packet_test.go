@@ -0,0 +1,234 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTrip encodes p and reads it back.
+func roundTrip(t *testing.T, p packet) packet {
+	t.Helper()
+
+	got, err := readPacket(bufio.NewReader(bytes.NewReader(p.encode())))
+	if err != nil {
+		t.Fatalf("readPacket() error = %v", err)
+	}
+	return got
+}
+
+func TestRemainingLength(t *testing.T) {
+	tests := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+	}
+
+	for _, tt := range tests {
+		if got := appendLength(nil, tt.length); !bytes.Equal(got, tt.want) {
+			t.Errorf("appendLength(%d) = % x, want % x", tt.length, got, tt.want)
+		}
+
+		p := packet{kind: packetPublish, body: bytes.Repeat([]byte{'x'}, tt.length)}
+		got := roundTrip(t, p)
+		if got.kind != p.kind || len(got.body) != tt.length {
+			t.Errorf("round trip of a %d byte body gave type %d with %d bytes", tt.length, got.kind, len(got.body))
+		}
+	}
+}
+
+func TestReadPacketRejects(t *testing.T) {
+	tests := map[string][]byte{
+		"five length bytes": {packetPublish << 4, 0x80, 0x80, 0x80, 0x80, 0x01},
+		"too large":         append([]byte{packetPublish << 4}, appendLength(nil, maxPacketSize+1)...),
+		"short body":        {packetPublish << 4, 0x05, 'a', 'b'},
+		"no length":         {packetPublish << 4},
+	}
+
+	for name, data := range tests {
+		if _, err := readPacket(bufio.NewReader(bytes.NewReader(data))); err == nil {
+			t.Errorf("readPacket(%s) succeeded", name)
+		}
+	}
+}
+
+// connectFields is a decoded CONNECT packet.
+type connectFields struct {
+	protocol  string
+	level     byte
+	flags     byte
+	keepAlive uint16
+	payload   []string // client ID, will topic and payload, username, password
+}
+
+// decodeConnect decodes a CONNECT body as a broker would.
+func decodeConnect(t *testing.T, body []byte) connectFields {
+	t.Helper()
+
+	readString := func() string {
+		if len(body) < 2 {
+			t.Fatalf("CONNECT truncated")
+		}
+		n := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+n {
+			t.Fatalf("CONNECT string truncated")
+		}
+		s := string(body[2 : 2+n])
+		body = body[2+n:]
+		return s
+	}
+
+	var f connectFields
+	f.protocol = readString()
+	if len(body) < 4 {
+		t.Fatalf("CONNECT variable header truncated")
+	}
+	f.level, f.flags = body[0], body[1]
+	f.keepAlive = binary.BigEndian.Uint16(body[2:])
+	body = body[4:]
+	for len(body) > 0 {
+		f.payload = append(f.payload, readString())
+	}
+	return f
+}
+
+func TestConnectPacket(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      connectOptions
+		wantFlags byte
+		want      []string
+	}{
+		{
+			name:      "plain",
+			opts:      connectOptions{clientID: "kefbar", keepAlive: 30 * time.Second},
+			wantFlags: connectCleanSession,
+			want:      []string{"kefbar"},
+		},
+		{
+			name:      "will",
+			opts:      connectOptions{clientID: "kefbar", willTopic: "kefbar/status", willPayload: "offline"},
+			wantFlags: connectCleanSession | connectWill | connectWillRetain,
+			want:      []string{"kefbar", "kefbar/status", "offline"},
+		},
+		{
+			name:      "credentials",
+			opts:      connectOptions{clientID: "kefbar", username: "user", password: "secret"},
+			wantFlags: connectCleanSession | connectUsername | connectPassword,
+			want:      []string{"kefbar", "user", "secret"},
+		},
+		{
+			name:      "username only",
+			opts:      connectOptions{clientID: "kefbar", username: "user"},
+			wantFlags: connectCleanSession | connectUsername,
+			want:      []string{"kefbar", "user"},
+		},
+		{
+			name: "will and credentials",
+			opts: connectOptions{
+				clientID: "kefbar", username: "user", password: "secret",
+				willTopic: "kefbar/status", willPayload: "offline",
+			},
+			wantFlags: connectCleanSession | connectWill | connectWillRetain | connectUsername | connectPassword,
+			want:      []string{"kefbar", "kefbar/status", "offline", "user", "secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := roundTrip(t, connectPacket(tt.opts))
+			if p.kind != packetConnect || p.flags != 0 {
+				t.Fatalf("packet type %d flags %#x, want CONNECT with no flags", p.kind, p.flags)
+			}
+
+			f := decodeConnect(t, p.body)
+			if f.protocol != "MQTT" || f.level != 4 {
+				t.Errorf("protocol %q level %d, want MQTT 4", f.protocol, f.level)
+			}
+			if f.flags != tt.wantFlags {
+				t.Errorf("connect flags = %#08b, want %#08b", f.flags, tt.wantFlags)
+			}
+			if want := uint16(tt.opts.keepAlive.Seconds()); f.keepAlive != want {
+				t.Errorf("keep alive = %d, want %d", f.keepAlive, want)
+			}
+			if strings.Join(f.payload, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("payload = %q, want %q", f.payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishRoundTrip(t *testing.T) {
+	for _, retain := range []bool{false, true} {
+		p := roundTrip(t, publishPacket("kefbar/volume", []byte("42"), retain))
+		if p.kind != packetPublish {
+			t.Fatalf("packet type = %d, want PUBLISH", p.kind)
+		}
+		if got := p.flags&0x01 == 1; got != retain {
+			t.Errorf("retain = %t, want %t", got, retain)
+		}
+
+		topic, payload, err := parsePublish(p)
+		if err != nil {
+			t.Fatalf("parsePublish() error = %v", err)
+		}
+		if topic != "kefbar/volume" || string(payload) != "42" {
+			t.Errorf("parsePublish() = %q %q, want kefbar/volume 42", topic, payload)
+		}
+	}
+}
+
+func TestParsePublishQoS(t *testing.T) {
+	// A broker may deliver at a higher QoS than subscribed; the packet ID
+	// sits between the topic and the payload
+	for qos := byte(1); qos <= 2; qos++ {
+		body := appendString(nil, "kefbar/set/volume")
+		body = binary.BigEndian.AppendUint16(body, 7)
+		body = append(body, "30"...)
+		p := roundTrip(t, packet{kind: packetPublish, flags: qos << 1, body: body})
+
+		topic, payload, err := parsePublish(p)
+		if err != nil {
+			t.Fatalf("QoS %d: parsePublish() error = %v", qos, err)
+		}
+		if topic != "kefbar/set/volume" || string(payload) != "30" {
+			t.Errorf("QoS %d: parsePublish() = %q %q, want kefbar/set/volume 30", qos, topic, payload)
+		}
+	}
+}
+
+func TestParsePublishMalformed(t *testing.T) {
+	tests := map[string]packet{
+		"empty":        {kind: packetPublish},
+		"short topic":  {kind: packetPublish, body: []byte{0x00, 0x05, 'a'}},
+		"no packet ID": {kind: packetPublish, flags: 1 << 1, body: appendString(nil, "a")},
+	}
+
+	for name, p := range tests {
+		if _, _, err := parsePublish(p); err == nil {
+			t.Errorf("parsePublish(%s) succeeded", name)
+		}
+	}
+}
+
+func TestSubscribePacket(t *testing.T) {
+	p := roundTrip(t, subscribePacket(1, []string{"kefbar/set/+", "kefbar/cmd"}))
+	if p.kind != packetSubscribe || p.flags != 0x02 {
+		t.Fatalf("packet type %d flags %#x, want SUBSCRIBE with flags 0010", p.kind, p.flags)
+	}
+
+	want := binary.BigEndian.AppendUint16(nil, 1)
+	want = append(appendString(want, "kefbar/set/+"), 0)
+	want = append(appendString(want, "kefbar/cmd"), 0)
+	if !bytes.Equal(p.body, want) {
+		t.Errorf("SUBSCRIBE body = % x, want % x", p.body, want)
+	}
+}
@@ -0,0 +1,47 @@
+// Package safe provides panic-safe goroutine helpers.
+package safe
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// restartDelay is how long GoRestart waits before restarting a loop that
+// panicked, so a loop that panics immediately doesn't spin.
+const restartDelay = time.Second
+
+// Go runs fn in a new goroutine, recovering and logging any panic instead
+// of crashing the process.
+func Go(name string, fn func()) {
+	go func() {
+		_ = run(name, fn)
+	}()
+}
+
+// GoRestart runs a long-lived loop in a new goroutine. If fn panics, the
+// panic is logged and fn is restarted; if fn returns normally, it is not.
+func GoRestart(name string, fn func()) {
+	go func() {
+		for run(name, fn) {
+			time.Sleep(restartDelay)
+			slog.Info("Restarting goroutine after panic", "name", name)
+		}
+	}()
+}
+
+// run calls fn and reports whether it panicked.
+func run(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Recovered from panic in goroutine",
+				"name", name,
+				"panic", r,
+				"stack", string(debug.Stack()))
+			panicked = true
+		}
+	}()
+
+	fn()
+	return false
+}
@@ -0,0 +1,119 @@
+package safe
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBuffer collects log output written from any goroutine.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs sends log output to the returned buffer until the test ends.
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+
+	var buf logBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// waitForLog waits for the log to contain all of want.
+func waitForLog(t *testing.T, logs *logBuffer, want ...string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		out := logs.String()
+		missing := false
+		for _, w := range want {
+			if !strings.Contains(out, w) {
+				missing = true
+			}
+		}
+		if !missing {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("log %q doesn't mention all of %q", out, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRun(t *testing.T) {
+	logs := captureLogs(t)
+
+	if run("fine", func() {}) {
+		t.Error("run() of a normal return reported a panic")
+	}
+	if logs.String() != "" {
+		t.Errorf("run() of a normal return logged %q", logs.String())
+	}
+
+	if !run("broken", func() { panic("boom") }) {
+		t.Error("run() of a panic didn't report it")
+	}
+	out := logs.String()
+	for _, want := range []string{"Recovered from panic in goroutine", "name=broken", "panic=boom", "stack="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log %q doesn't mention %q", out, want)
+		}
+	}
+}
+
+func TestGoRecovers(t *testing.T) {
+	logs := captureLogs(t)
+
+	Go("exploding", func() {
+		var m map[string]int
+		m["x"] = 1 // Panics: assignment to a nil map
+	})
+	waitForLog(t, logs, "Recovered from panic in goroutine", "name=exploding", "nil map")
+}
+
+func TestGoRestartRestartsAfterPanic(t *testing.T) {
+	logs := captureLogs(t)
+
+	var mu sync.Mutex
+	calls := 0
+	done := make(chan struct{})
+	GoRestart("loop", func() {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if n == 1 {
+			panic("first run")
+		}
+		close(done) // Returns normally, so isn't restarted again
+	})
+
+	select {
+	case <-done:
+	case <-time.After(restartDelay + 5*time.Second):
+		t.Fatal("GoRestart didn't restart the loop after a panic")
+	}
+	waitForLog(t, logs, "panic=\"first run\"", "Restarting goroutine after panic")
+}
@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// at returns the given local wall time in UTC, to keep tests free of DST.
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",     // Too few fields
+		"* * * * * *", // Too many
+		"60 * * * *",  // Minute out of range
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *", // Backwards range
+		"*/0 * * * *", // Zero step
+		"*/x * * * *",
+		"a * * * *",
+		"@yearly", // Unsupported alias
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"every minute", "* * * * *", at(2026, 10, 14, 10, 7), at(2026, 10, 14, 10, 8)},
+		{"strictly after", "7 10 * * *", at(2026, 10, 14, 10, 7), at(2026, 10, 15, 10, 7)},
+		{"seconds ignored", "8 10 * * *", at(2026, 10, 14, 10, 7).Add(59 * time.Second), at(2026, 10, 14, 10, 8)},
+		{"step", "*/15 * * * *", at(2026, 10, 14, 10, 7), at(2026, 10, 14, 10, 15)},
+		{"step wraps the hour", "*/15 * * * *", at(2026, 10, 14, 10, 50), at(2026, 10, 14, 11, 0)},
+		{"stepped range", "10-40/10 * * * *", at(2026, 10, 14, 10, 31), at(2026, 10, 14, 10, 40)},
+		{"step from value", "5/20 * * * *", at(2026, 10, 14, 10, 30), at(2026, 10, 14, 10, 45)},
+		{"list", "0,30 9 * * *", at(2026, 10, 14, 9, 10), at(2026, 10, 14, 9, 30)},
+		{"list of ranges", "0 8-9,17-18 * * *", at(2026, 10, 14, 10, 0), at(2026, 10, 14, 17, 0)},
+		// Friday 23:00 to Monday 22:00
+		{"weekdays", "0 22 * * 1-5", at(2026, 10, 16, 23, 0), at(2026, 10, 19, 22, 0)},
+		{"Sunday as 7", "0 9 * * 7", at(2026, 10, 14, 0, 0), at(2026, 10, 18, 9, 0)},
+		{"Sunday as 0", "0 9 * * 0", at(2026, 10, 14, 0, 0), at(2026, 10, 18, 9, 0)},
+		// Both day fields restricted: either matches. The 20th comes
+		// before the next Friday the 23rd.
+		{"day of month or week", "0 0 20 * 5", at(2026, 10, 17, 0, 0), at(2026, 10, 20, 0, 0)},
+		{"day of week or month", "0 0 31 * 5", at(2026, 10, 14, 0, 0), at(2026, 10, 16, 0, 0)},
+		{"month", "0 0 1 3 *", at(2026, 10, 14, 0, 0), at(2027, 3, 1, 0, 0)},
+		{"end of year", "0 0 1 1 *", at(2026, 12, 31, 23, 59), at(2027, 1, 1, 0, 0)},
+		{"leap day", "0 12 29 2 *", at(2026, 10, 14, 0, 0), at(2028, 2, 29, 12, 0)},
+		{"@hourly", "@hourly", at(2026, 10, 14, 10, 7), at(2026, 10, 14, 11, 0)},
+		{"@daily", "@daily", at(2026, 10, 14, 23, 59), at(2026, 10, 15, 0, 0)},
+		{"@midnight", "@midnight", at(2026, 10, 14, 0, 0), at(2026, 10, 15, 0, 0)},
+		{"@weekly", "@weekly", at(2026, 10, 14, 10, 0), at(2026, 10, 18, 0, 0)},
+		{"@monthly", "@monthly", at(2026, 10, 14, 10, 0), at(2026, 11, 1, 0, 0)},
+		{"never", "0 0 30 2 *", at(2026, 10, 14, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+			if !tt.want.IsZero() && !c.Matches(tt.want) {
+				t.Errorf("Matches(%v) = false for the time Next returned", tt.want)
+			}
+		})
+	}
+}
+
+func TestLatest(t *testing.T) {
+	c, err := Parse("*/10 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		after, until time.Time
+		want         time.Time
+		ok           bool
+	}{
+		{"several due", at(2026, 10, 14, 10, 0), at(2026, 10, 14, 10, 35), at(2026, 10, 14, 10, 30), true},
+		{"until is inclusive", at(2026, 10, 14, 10, 5), at(2026, 10, 14, 10, 10), at(2026, 10, 14, 10, 10), true},
+		{"after is exclusive", at(2026, 10, 14, 10, 10), at(2026, 10, 14, 10, 15), time.Time{}, false},
+		{"none due", at(2026, 10, 14, 10, 1), at(2026, 10, 14, 10, 9), time.Time{}, false},
+		{"overnight", at(2026, 10, 14, 23, 0), at(2026, 10, 15, 7, 3), at(2026, 10, 15, 7, 0), true},
+	}
+
+	for _, tt := range tests {
+		got, ok := c.Latest(tt.after, tt.until)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%s: Latest() = %v, %t; want %v, %t", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package schedule
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Missed schedule policies, for runs that were due while the Mac was asleep.
+const (
+	MissedSkip      = "skip"       // Drop missed runs
+	MissedRunLatest = "run_latest" // Run the most recent missed run on wake
+)
+
+// Scheduler timing.
+const (
+	// checkInterval is how often the scheduler looks for due entries.
+	checkInterval = 20 * time.Second
+
+	// missedAfter is how late a run may be and still count as on time
+	// rather than missed.
+	missedAfter = 2 * time.Minute
+)
+
+// Entry is a cron expression and the name of the action it triggers.
+type Entry struct {
+	Cron   *Cron
+	Action string
+}
+
+// Scheduler runs entries when their cron expressions come due.
+type Scheduler struct {
+	Entries []Entry
+	Run     func(action string) error // Called with the entry's action
+	Policy  string                    // MissedSkip (default) or MissedRunLatest
+	Now     func() time.Time          // Clock; nil means time.Now
+}
+
+// Start checks for due entries until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	last := s.now()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := s.now()
+			s.check(last, now)
+			last = now
+		}
+	}
+}
+
+// check runs the entries that came due in (last, now].
+func (s *Scheduler) check(last, now time.Time) {
+	for _, entry := range s.Due(last, now) {
+		slog.Info("Running scheduled action", "action", entry.Action)
+		if err := s.Run(entry.Action); err != nil {
+			slog.Error("Scheduled action failed", "action", entry.Action, "error", err)
+		}
+	}
+}
+
+// Due returns the entries to run for the interval (last, now]. Each entry
+// runs at most once per check. A run that is more than missedAfter late,
+// e.g. because the Mac was asleep, is missed and only runs under
+// MissedRunLatest.
+func (s *Scheduler) Due(last, now time.Time) []Entry {
+	var due []Entry
+	for _, entry := range s.Entries {
+		at, ok := entry.Cron.Latest(last, now)
+		if !ok {
+			continue
+		}
+
+		if now.Sub(at) > missedAfter && s.Policy != MissedRunLatest {
+			slog.Info("Skipping missed scheduled action", "action", entry.Action, "due", at)
+			continue
+		}
+		due = append(due, entry)
+	}
+	return due
+}
+
+// now returns the current time from the scheduler's clock.
+func (s *Scheduler) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,126 @@
+package schedule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// mustParse parses expr or fails the test.
+func mustParse(t *testing.T, expr string) *Cron {
+	t.Helper()
+
+	c, err := Parse(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// dueActions returns the actions of the entries due in (last, now].
+func dueActions(s *Scheduler, last, now time.Time) []string {
+	var actions []string
+	for _, entry := range s.Due(last, now) {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+func TestDue(t *testing.T) {
+	entries := []Entry{
+		{Cron: mustParse(t, "0 22 * * *"), Action: "standby"},
+		{Cron: mustParse(t, "30 7 * * *"), Action: "wake"},
+	}
+	s := &Scheduler{Entries: entries}
+
+	// An ordinary check just after 22:00
+	last, now := at(2026, 10, 14, 21, 59), at(2026, 10, 14, 22, 0).Add(20*time.Second)
+	if got := dueActions(s, last, now); !slices.Equal(got, []string{"standby"}) {
+		t.Errorf("Due() at 22:00 = %v, want [standby]", got)
+	}
+
+	// Nothing due
+	last, now = at(2026, 10, 14, 12, 0), at(2026, 10, 14, 12, 0).Add(20*time.Second)
+	if got := dueActions(s, last, now); len(got) != 0 {
+		t.Errorf("Due() at noon = %v, want nothing", got)
+	}
+
+	// A late check still counts as on time within missedAfter
+	last, now = at(2026, 10, 14, 21, 59), at(2026, 10, 14, 22, 0).Add(missedAfter)
+	if got := dueActions(s, last, now); !slices.Equal(got, []string{"standby"}) {
+		t.Errorf("Due() %v late = %v, want [standby]", missedAfter, got)
+	}
+}
+
+func TestDueMissedPolicy(t *testing.T) {
+	// Asleep from 21:00 to 08:00; standby (22:00) and wake (07:30) were
+	// both missed
+	last, now := at(2026, 10, 14, 21, 0), at(2026, 10, 15, 8, 0)
+	entries := []Entry{
+		{Cron: mustParse(t, "0 22 * * *"), Action: "standby"},
+		{Cron: mustParse(t, "30 7 * * *"), Action: "wake"},
+		{Cron: mustParse(t, "0 8 * * *"), Action: "on time"},
+	}
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{"", []string{"on time"}},
+		{MissedSkip, []string{"on time"}},
+		{MissedRunLatest, []string{"standby", "wake", "on time"}},
+	}
+
+	for _, tt := range tests {
+		s := &Scheduler{Entries: entries, Policy: tt.policy}
+		if got := dueActions(s, last, now); !slices.Equal(got, tt.want) {
+			t.Errorf("policy %q: Due() = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestDueRunsOncePerCheck(t *testing.T) {
+	// Every minute, but a check covering ten minutes runs it once
+	s := &Scheduler{
+		Entries: []Entry{{Cron: mustParse(t, "* * * * *"), Action: "tick"}},
+		Policy:  MissedRunLatest,
+	}
+	if got := dueActions(s, at(2026, 10, 14, 10, 0), at(2026, 10, 14, 10, 10)); len(got) != 1 {
+		t.Errorf("Due() = %v, want one run", got)
+	}
+}
+
+func TestCheckRunsDueActions(t *testing.T) {
+	var ran []string
+	s := &Scheduler{
+		Entries: []Entry{
+			{Cron: mustParse(t, "0 22 * * *"), Action: "fails"},
+			{Cron: mustParse(t, "0 22 * * *"), Action: "standby"},
+		},
+		Run: func(action string) error {
+			ran = append(ran, action)
+			if action == "fails" {
+				return errors.New("speaker unreachable")
+			}
+			return nil
+		},
+	}
+
+	// A failing action doesn't stop the rest
+	s.check(at(2026, 10, 14, 21, 59), at(2026, 10, 14, 22, 0))
+	if !slices.Equal(ran, []string{"fails", "standby"}) {
+		t.Errorf("check() ran %v, want both actions", ran)
+	}
+}
+
+func TestSchedulerClock(t *testing.T) {
+	fixed := at(2026, 10, 14, 10, 0)
+	s := &Scheduler{Now: func() time.Time { return fixed }}
+	if got := s.now(); !got.Equal(fixed) {
+		t.Errorf("now() = %v, want the injected clock's %v", got, fixed)
+	}
+	if got := (&Scheduler{}).now(); time.Since(got) > time.Minute {
+		t.Errorf("now() without a clock = %v, want about now", got)
+	}
+}
@@ -0,0 +1,231 @@
+// Package server provides a local HTTP API for controlling the speaker from
+// scripts and tools such as Stream Deck.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/safe"
+)
+
+// Request limits.
+const (
+	readTimeout  = 5 * time.Second
+	maxBodyBytes = 4 << 10
+)
+
+// Server serves the control API.
+type Server struct {
+	ctrl *controller.Controller
+	http *http.Server
+}
+
+// volumeRequest is the body of POST /volume. Level sets the volume; Delta
+// steps it from the current level.
+type volumeRequest struct {
+	Level *int `json:"level"`
+	Delta *int `json:"delta"`
+}
+
+// muteRequest is the optional body of POST /mute; without it the mute state
+// is toggled.
+type muteRequest struct {
+	Muted *bool `json:"muted"`
+}
+
+// errorResponse is returned for failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a Server listening on addr, or on the default loopback
+// address if addr is empty.
+func New(ctrl *controller.Controller, addr string) *Server {
+	if addr == "" {
+		addr = config.DefaultServerAddress
+	}
+
+	s := &Server{ctrl: ctrl}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /state", s.handleState)
+	mux.HandleFunc("POST /volume", s.handleVolume)
+	mux.HandleFunc("POST /mute", s.handleMute)
+	mux.HandleFunc("POST /next", s.handleNext)
+
+	s.http = &http.Server{
+		Addr:              addr,
+		Handler:           rejectBrowsers(mux, addr),
+		ReadHeaderTimeout: readTimeout,
+		ReadTimeout:       readTimeout,
+	}
+	return s
+}
+
+// Start binds the listen address and serves requests in the background.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.http.Addr, err)
+	}
+
+	slog.Info("Control server listening", "addr", ln.Addr().String())
+	safe.Go("control server", func() {
+		if err := s.http.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Control server stopped", "error", err)
+		}
+	})
+	return nil
+}
+
+// Shutdown stops accepting requests and waits for in-flight ones to finish
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// handleState returns the speaker state.
+func (s *Server) handleState(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.ctrl.GetState())
+}
+
+// handleVolume sets or steps the volume.
+func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
+	var req volumeRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	switch {
+	case req.Level != nil && req.Delta != nil:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("set either level or delta, not both"))
+		return
+	case req.Level != nil:
+		if *req.Level < 0 || *req.Level > 100 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("level must be between 0 and 100"))
+			return
+		}
+		err = s.ctrl.SetVolume(*req.Level)
+	case req.Delta != nil:
+		err = s.ctrl.AdjustVolume(*req.Delta)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("level or delta is required"))
+		return
+	}
+
+	s.respond(w, err)
+}
+
+// handleMute sets the mute state, or toggles it when no body is sent.
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request) {
+	var req muteRequest
+	if r.ContentLength != 0 {
+		if err := decodeBody(w, r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var err error
+	if req.Muted != nil {
+		err = s.ctrl.SetMute(*req.Muted)
+	} else {
+		err = s.ctrl.ToggleMute()
+	}
+	s.respond(w, err)
+}
+
+// handleNext skips to the next track.
+func (s *Server) handleNext(w http.ResponseWriter, _ *http.Request) {
+	s.respond(w, s.ctrl.NextTrack())
+}
+
+// respond writes the speaker state after a command, or the command's error.
+func (s *Server) respond(w http.ResponseWriter, err error) {
+	if err != nil {
+		slog.Warn("Control server command failed", "error", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.ctrl.GetState())
+}
+
+// rejectBrowsers refuses requests sent by web pages. Browsers add an Origin
+// header to cross-site requests, and without this check any page could
+// control the speaker through the loopback address. A page on a domain
+// rebound to the loopback address sends its own name as the Host, so only
+// loopback names and addresses, and the address listened on, are accepted
+// there.
+func rejectBrowsers(next http.Handler, addr string) http.Handler {
+	listenHost, _, err := net.SplitHostPort(addr)
+	if ip := net.ParseIP(listenHost); err != nil || ip == nil || ip.IsUnspecified() {
+		listenHost = ""
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, fmt.Errorf("browser requests are not allowed"))
+			return
+		}
+		if !isLoopbackHost(r.Host) && (listenHost == "" || hostname(r.Host) != listenHost) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether host, with or without a port, is
+// localhost or a loopback address.
+func isLoopbackHost(host string) bool {
+	host = strings.TrimSuffix(hostname(host), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostname returns host without its port or IPv6 brackets.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.Trim(host, "[]")
+}
+
+// decodeBody decodes a JSON request body into v.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write control server response", "error", err)
+	}
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+)
+
+// speaker is a stub KEF speaker at volume 42, unmuted, that records the
+// values written to it.
+type speaker struct {
+	mu     sync.Mutex
+	values map[string]string
+	writes []string // "path=value", oldest first
+}
+
+func newSpeaker() *speaker {
+	return &speaker{values: map[string]string{
+		"player:volume":              `{"type":"i32_","i32_":42}`,
+		"settings:/mediaPlayer/mute": `{"type":"bool_","bool_":false}`,
+	}}
+}
+
+func (s *speaker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/getData":
+		value, ok := s.values[q.Get("path")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[" + value + "]"))
+	case "/api/setData":
+		s.writes = append(s.writes, q.Get("path")+"="+q.Get("value"))
+		s.values[q.Get("path")] = q.Get("value")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// written returns the writes the speaker received.
+func (s *speaker) written() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.writes)
+}
+
+// newTestServer returns a control server for a stub speaker whose volume
+// and mute state the controller has read.
+func newTestServer(t *testing.T) (*Server, *speaker) {
+	t.Helper()
+
+	sp := newSpeaker()
+	stub := httptest.NewServer(sp)
+	t.Cleanup(stub.Close)
+
+	host, portStr, err := net.SplitHostPort(stub.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.SpeakerIP = host
+	cfg.Port = port
+	cfg.WriteIntervalMs = 0
+
+	ctrl := controller.New(cfg, api.WithTransport(stub.Client().Transport))
+	ctrl.SetIP(host)
+	t.Cleanup(ctrl.Close)
+	if _, err := ctrl.GetVolume(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctrl.GetMute(); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(ctrl, ""), sp
+}
+
+// serve sends a request with body, if any, through the server's handler
+// from a local tool.
+func serve(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	req.Host = "127.0.0.1:8766"
+
+	rec := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleState(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := serve(s, "GET", "/state", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /state status = %d, want 200", rec.Code)
+	}
+	var state struct{ Volume int }
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Volume != 42 {
+		t.Errorf("GET /state volume = %d, want 42", state.Volume)
+	}
+}
+
+func TestHandleVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		writes []string
+	}{
+		{"level", `{"level":30}`, http.StatusOK, []string{`player:volume={"type":"i32_","i32_":30}`}},
+		{"delta", `{"delta":-5}`, http.StatusOK, []string{`player:volume={"type":"i32_","i32_":37}`}},
+		{"zero level", `{"level":0}`, http.StatusOK, []string{`player:volume={"type":"i32_","i32_":0}`}},
+		{"level too high", `{"level":101}`, http.StatusBadRequest, nil},
+		{"negative level", `{"level":-1}`, http.StatusBadRequest, nil},
+		{"level and delta", `{"level":30,"delta":5}`, http.StatusBadRequest, nil},
+		{"neither", `{}`, http.StatusBadRequest, nil},
+		{"unknown field", `{"volume":30}`, http.StatusBadRequest, nil},
+		{"not JSON", `30`, http.StatusBadRequest, nil},
+		{"no body", ``, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, sp := newTestServer(t)
+
+			rec := serve(s, "POST", "/volume", tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("POST /volume %s status = %d, want %d: %s", tt.body, rec.Code, tt.status, rec.Body)
+			}
+			if got := sp.written(); !slices.Equal(got, tt.writes) {
+				t.Errorf("POST /volume %s wrote %v, want %v", tt.body, got, tt.writes)
+			}
+		})
+	}
+}
+
+func TestHandleMute(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		writes []string
+	}{
+		{"toggle", ``, http.StatusOK, []string{`settings:/mediaPlayer/mute={"type":"bool_","bool_":true}`}},
+		{"mute", `{"muted":true}`, http.StatusOK, []string{`settings:/mediaPlayer/mute={"type":"bool_","bool_":true}`}},
+		// Explicit, so already unmuted stays unmuted rather than toggling
+		{"unmute", `{"muted":false}`, http.StatusOK, []string{`settings:/mediaPlayer/mute={"type":"bool_","bool_":false}`}},
+		{"empty object toggles", `{}`, http.StatusOK, []string{`settings:/mediaPlayer/mute={"type":"bool_","bool_":true}`}},
+		{"unknown field", `{"mute":true}`, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, sp := newTestServer(t)
+
+			rec := serve(s, "POST", "/mute", tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("POST /mute %s status = %d, want %d: %s", tt.body, rec.Code, tt.status, rec.Body)
+			}
+			if got := sp.written(); !slices.Equal(got, tt.writes) {
+				t.Errorf("POST /mute %s wrote %v, want %v", tt.body, got, tt.writes)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s, sp := newTestServer(t)
+
+	if rec := serve(s, "GET", "/volume", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /volume status = %d, want 405", rec.Code)
+	}
+	if got := sp.written(); len(got) != 0 {
+		t.Errorf("GET /volume wrote %v", got)
+	}
+}
+
+func TestRejectBrowsers(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		status int
+	}{
+		{"local tool", "127.0.0.1:8766", "", http.StatusOK},
+		{"localhost", "localhost:8766", "", http.StatusOK},
+		{"localhost without port", "LOCALHOST", "", http.StatusOK},
+		{"IPv6 loopback", "[::1]:8766", "", http.StatusOK},
+		{"other loopback address", "127.0.0.2", "", http.StatusOK},
+		{"web page", "127.0.0.1:8766", "https://example.com", http.StatusForbidden},
+		{"null origin", "127.0.0.1:8766", "null", http.StatusForbidden},
+		// A domain rebound to the loopback address
+		{"rebound domain", "attacker.example:8766", "", http.StatusForbidden},
+		{"LAN address", "192.168.1.20:8766", "", http.StatusForbidden},
+		{"localhost lookalike", "localhost.attacker.example", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, sp := newTestServer(t)
+
+			req := httptest.NewRequest("POST", "/volume", strings.NewReader(`{"level":30}`))
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			s.http.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if wrote := len(sp.written()) > 0; wrote != (tt.status == http.StatusOK) {
+				t.Errorf("speaker written = %t, want %t", wrote, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestRejectBrowsersListenAddress(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		addr, host string
+		status     int
+	}{
+		// Listening on a LAN address, tools on the LAN use it as the Host
+		{"192.168.1.20:8766", "192.168.1.20:8766", http.StatusOK},
+		{"192.168.1.20:8766", "localhost:8766", http.StatusOK},
+		{"192.168.1.20:8766", "192.168.1.21:8766", http.StatusForbidden},
+		{"[fd00::20]:8766", "[fd00::20]:8766", http.StatusOK},
+		// Any address: only loopback Hosts are known to be safe
+		{"0.0.0.0:8766", "192.168.1.20:8766", http.StatusForbidden},
+		{":8766", "192.168.1.20:8766", http.StatusForbidden},
+		{"localhost:8766", "attacker.example:8766", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/state", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		rejectBrowsers(ok, tt.addr).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("listening on %s, Host %s status = %d, want %d", tt.addr, tt.host, rec.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// appearanceCheckInterval is how often the system appearance is checked
+// for a switch between light and dark mode.
+const appearanceCheckInterval = 5 * time.Second
+
+// appearanceWatcher notices the system switching between light and dark
+// mode.
+type appearanceWatcher struct {
+	isDark func() bool
+	dark   bool
+}
+
+// newAppearanceWatcher creates an appearanceWatcher that reads the
+// appearance with isDark.
+func newAppearanceWatcher(isDark func() bool) *appearanceWatcher {
+	return &appearanceWatcher{isDark: isDark, dark: isDark()}
+}
+
+// changed reports whether the appearance changed since the last check.
+func (w *appearanceWatcher) changed() bool {
+	dark := w.isDark()
+	if dark == w.dark {
+		return false
+	}
+	w.dark = dark
+	return true
+}
+
+// systemDarkMode reports whether macOS is in dark mode. The global
+// AppleInterfaceStyle default is "Dark" then, and unset in light mode.
+func systemDarkMode() bool {
+	out, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "Dark"
+}
+
+// watchAppearance regenerates the menu bar icon whenever the appearance
+// changes, as cached icons were rendered for the old one.
+func (a *App) watchAppearance() {
+	watcher := newAppearanceWatcher(systemDarkMode)
+	ticker := time.NewTicker(appearanceCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if watcher.changed() {
+			slog.Debug("Appearance changed, regenerating icons", "dark", watcher.dark)
+			ResetIconCache()
+			a.icon.Redraw()
+		}
+	}
+}
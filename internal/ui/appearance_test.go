@@ -0,0 +1,37 @@
+package ui
+
+import "testing"
+
+func TestAppearanceWatcher(t *testing.T) {
+	dark := false
+	w := newAppearanceWatcher(func() bool { return dark })
+
+	if w.changed() {
+		t.Error("changed() with the appearance unchanged")
+	}
+	dark = true
+	if !w.changed() {
+		t.Error("changed() missed the switch to dark mode")
+	}
+	if w.changed() {
+		t.Error("changed() twice for one switch")
+	}
+	dark = false
+	if !w.changed() {
+		t.Error("changed() missed the switch back to light mode")
+	}
+}
+
+func TestIconUpdaterRedraw(t *testing.T) {
+	applied := make(chan iconState, 2)
+	u := newIconUpdater(0, func(s iconState) { applied <- s })
+
+	want := iconState{connected: true, volume: 30, muted: true}
+	u.Set(want)
+	<-applied
+
+	u.Redraw()
+	if got := <-applied; got != want {
+		t.Errorf("Redraw() applied %+v, want the last state %+v", got, want)
+	}
+}
@@ -0,0 +1,17 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CopyToClipboard puts text on the macOS clipboard.
+func CopyToClipboard(text string) error {
+	cmd := exec.Command("pbcopy")
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pbcopy failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
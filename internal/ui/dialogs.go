@@ -1,16 +1,54 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github/com/inquire/kefbar-go/internal/config"
-	"github/com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/safe"
 )
 
+// dialogTimeout bounds how long a dialog may stay open before osascript is
+// killed, so an abandoned dialog doesn't hold a goroutine forever.
+const dialogTimeout = 10 * time.Minute
+
+// Dialogs currently open, by name.
+var (
+	openDialogsMu sync.Mutex
+	openDialogs   = make(map[string]bool)
+)
+
+// osascript location, looked up once. Empty if it isn't installed.
+var (
+	osascriptOnce sync.Once
+	osascriptPath string
+)
+
+// errDialogsUnavailable is returned when osascript can't be found.
+var errDialogsUnavailable = errors.New("osascript not available")
+
+// DialogsAvailable reports whether native dialogs can be shown. When they
+// can't (e.g., osascript is missing), alerts are logged instead.
+func DialogsAvailable() bool {
+	osascriptOnce.Do(func() {
+		path, err := exec.LookPath("osascript")
+		if err != nil {
+			slog.Debug("osascript not found", "error", err)
+			return
+		}
+		osascriptPath = path
+	})
+	return osascriptPath != ""
+}
+
 // ShowSettingsDialog displays a native macOS dialog to enter speaker IP.
 func ShowSettingsDialog(ctrl *controller.Controller) {
 	state := ctrl.GetState()
@@ -19,18 +57,19 @@ func ShowSettingsDialog(ctrl *controller.Controller) {
 		currentIP = "192.168.1.100"
 	}
 
-	script := fmt.Sprintf(`
-		set dialogResult to display dialog "Enter KEF Speaker IP Address:" default answer "%s" buttons {"Cancel", "Connect"} default button "Connect" with title "KEF Bar Settings"
-		if button returned of dialogResult is "Connect" then
-			return text returned of dialogResult
-		else
-			return ""
-		end if
-	`, currentIP)
-
-	go func() {
-		cmd := exec.Command("osascript", "-e", script)
-		output, err := cmd.Output()
+	script := `
+		on run argv
+			set dialogResult to display dialog "Enter KEF Speaker IP Address:" default answer (item 1 of argv) buttons {"Cancel", "Connect"} default button "Connect" with title "KEF Bar Settings"
+			if button returned of dialogResult is "Connect" then
+				return text returned of dialogResult
+			else
+				return ""
+			end if
+		end run
+	`
+
+	showDialog("settings dialog", func() {
+		output, err := runAppleScript(script, currentIP)
 		if err != nil {
 			slog.Debug("Settings dialog cancelled or error", "error", err)
 			return
@@ -42,6 +81,14 @@ func ShowSettingsDialog(ctrl *controller.Controller) {
 		}
 
 		slog.Info("Connect requested via settings", "ip", ip)
+
+		// Check the address first so a typo doesn't replace a working one
+		if err := ctrl.Ping(context.Background(), ip); err != nil {
+			slog.Error("Speaker not reachable", "ip", ip, "error", err)
+			ShowAlert("Connection Failed", fmt.Sprintf("No KEF speaker answered at %s: %v", ip, err))
+			return
+		}
+
 		ctrl.SetIP(ip)
 		_ = config.SaveIP(ip)
 
@@ -52,7 +99,7 @@ func ShowSettingsDialog(ctrl *controller.Controller) {
 			slog.Info("Connected to speaker", "ip", ip)
 			ShowAlert("Connected", fmt.Sprintf("Successfully connected to %s", ip))
 		}
-	}()
+	})
 }
 
 // ShowVolumeDialog displays a native macOS dialog to set volume.
@@ -64,19 +111,22 @@ func ShowVolumeDialog(ctrl *controller.Controller) {
 	}
 
 	currentVol := state.Volume
-
-	script := fmt.Sprintf(`
-		set dialogResult to display dialog "Enter volume (0-100):" default answer "%d" buttons {"Cancel", "Set Volume"} default button "Set Volume" with title "KEF Bar Volume"
-		if button returned of dialogResult is "Set Volume" then
-			return text returned of dialogResult
-		else
-			return ""
-		end if
-	`, currentVol)
-
-	go func() {
-		cmd := exec.Command("osascript", "-e", script)
-		output, err := cmd.Output()
+	limit := ctrl.MaxVolume()
+
+	script := `
+		on run argv
+			set dialogResult to display dialog (item 2 of argv) default answer (item 1 of argv) buttons {"Cancel", "Set Volume"} default button "Set Volume" with title "KEF Bar Volume"
+			if button returned of dialogResult is "Set Volume" then
+				return text returned of dialogResult
+			else
+				return ""
+			end if
+		end run
+	`
+
+	showDialog("volume dialog", func() {
+		prompt := fmt.Sprintf("Enter volume (0-%d):", limit)
+		output, err := runAppleScript(script, strconv.Itoa(currentVol), prompt)
 		if err != nil {
 			slog.Debug("Volume dialog cancelled or error", "error", err)
 			return
@@ -87,14 +137,19 @@ func ShowVolumeDialog(ctrl *controller.Controller) {
 			return
 		}
 
+		rangeHint := fmt.Sprintf("between 0 and %d", limit)
+		if limit < 100 {
+			rangeHint += " (the max_volume setting)"
+		}
+
 		vol, err := strconv.Atoi(volStr)
 		if err != nil {
-			ShowAlert("Invalid Volume", "Please enter a number between 0 and 100.")
+			ShowAlert("Invalid Volume", "Please enter a number "+rangeHint+".")
 			return
 		}
 
-		if vol < 0 || vol > 100 {
-			ShowAlert("Invalid Volume", "Volume must be between 0 and 100.")
+		if vol < 0 || vol > limit {
+			ShowAlert("Invalid Volume", "Volume must be "+rangeHint+".")
 			return
 		}
 
@@ -105,14 +160,181 @@ func ShowVolumeDialog(ctrl *controller.Controller) {
 		} else {
 			slog.Info("Volume changed via dialog", "old", oldVol, "new", vol)
 		}
-	}()
+	})
 }
 
-// ShowAlert displays a native macOS alert.
+// ShowRenameDialog displays a dialog to rename the connected speaker.
+func ShowRenameDialog(ctrl *controller.Controller) {
+	state := ctrl.GetState()
+	if !state.Connected {
+		ShowAlert("Not Connected", "Please connect to a speaker first.")
+		return
+	}
+
+	script := `
+		on run argv
+			set dialogResult to display dialog "Speaker name:" default answer (item 1 of argv) buttons {"Cancel", "Rename"} default button "Rename" with title "KEF Bar Rename"
+			if button returned of dialogResult is "Rename" then
+				return text returned of dialogResult
+			else
+				return ""
+			end if
+		end run
+	`
+
+	showDialog("rename dialog", func() {
+		output, err := runAppleScript(script, state.Name)
+		if err != nil {
+			slog.Debug("Rename dialog cancelled or error", "error", err)
+			return
+		}
+
+		// osascript ends its output with a newline
+		name := strings.TrimSpace(string(output))
+		if name == "" || name == state.Name {
+			return
+		}
+
+		if err := ctrl.SetDeviceName(name); err != nil {
+			slog.Error("Failed to rename speaker", "error", err)
+			ShowAlert("Error", fmt.Sprintf("Could not rename the speaker: %v", err))
+		} else {
+			slog.Info("Speaker renamed via dialog", "old", state.Name, "new", name)
+		}
+	})
+}
+
+// runAppleScript runs an AppleScript via osascript and returns its output.
+// The script must declare an "on run argv" handler; args are passed to it as
+// argv rather than formatted into the source, so quotes, backslashes and
+// newlines in user or config values can't break out of a string literal.
+// The process is killed if it runs longer than dialogTimeout.
+func runAppleScript(script string, args ...string) ([]byte, error) {
+	if !DialogsAvailable() {
+		return nil, errDialogsUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialogTimeout)
+	defer cancel()
+
+	cmdArgs := append([]string{"-e", script}, args...)
+	output, err := exec.CommandContext(ctx, osascriptPath, cmdArgs...).Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Warn("Dialog timed out", "timeout", dialogTimeout)
+		return nil, ctx.Err()
+	}
+	return output, err
+}
+
+// showDialog runs fn in the background unless a dialog with the same name is
+// already open, so repeated menu clicks don't stack identical dialogs.
+func showDialog(name string, fn func()) {
+	if !DialogsAvailable() {
+		slog.Warn("Cannot show dialog", "dialog", name, "error", errDialogsUnavailable)
+		return
+	}
+
+	openDialogsMu.Lock()
+	if openDialogs[name] {
+		openDialogsMu.Unlock()
+		slog.Debug("Dialog already open", "dialog", name)
+		return
+	}
+	openDialogs[name] = true
+	openDialogsMu.Unlock()
+
+	safe.Go(name, func() {
+		defer func() {
+			openDialogsMu.Lock()
+			delete(openDialogs, name)
+			openDialogsMu.Unlock()
+		}()
+		fn()
+	})
+}
+
+// ShowAlert displays a native macOS alert, or logs it if dialogs are
+// unavailable or the alert fails to show.
 func ShowAlert(title, message string) {
-	script := fmt.Sprintf(`display alert "%s" message "%s" as informational`, title, message)
-	cmd := exec.Command("osascript", "-e", script)
-	_ = cmd.Run()
+	script := `
+		on run argv
+			display alert (item 1 of argv) message (item 2 of argv) as informational
+		end run
+	`
+	if _, err := runAppleScript(script, title, message); err != nil {
+		slog.Warn("Alert", "title", title, "message", message, "error", err)
+	}
+}
+
+// ShowErrorDetails shows the speaker's last error in full, with its IP,
+// offering to copy both for a bug report.
+func ShowErrorDetails(ip, errMsg string) {
+	script := `
+		on run argv
+			set dialogResult to display alert "Connection Error" message (item 1 of argv) as warning buttons {"Copy to Clipboard", "OK"} default button "OK"
+			return button returned of dialogResult
+		end run
+	`
+
+	if ip == "" {
+		ip = "not set"
+	}
+
+	showDialog("error details", func() {
+		details := fmt.Sprintf("Speaker IP: %s\nError: %s", ip, errMsg)
+		output, err := runAppleScript(script, details)
+		if err != nil {
+			slog.Debug("Error details dialog cancelled or error", "error", err)
+			return
+		}
+
+		if strings.TrimSpace(string(output)) == "Copy to Clipboard" {
+			if err := CopyToClipboard(details); err != nil {
+				slog.Warn("Failed to copy error details", "error", err)
+				ShowAlert("Copy Failed", err.Error())
+			}
+		}
+	})
+}
+
+// ShowNotification posts a macOS notification without waiting for it to
+// be shown. If notifications can't be posted it is only logged.
+func ShowNotification(title, message string) {
+	script := `
+		on run argv
+			display notification (item 2 of argv) with title (item 1 of argv)
+		end run
+	`
+	safe.Go("notification", func() {
+		if _, err := runAppleScript(script, title, message); err != nil {
+			slog.Debug("Notification", "title", title, "message", message, "error", err)
+		}
+	})
+}
+
+// ShowConfirm displays a native macOS confirmation dialog and reports
+// whether the user confirmed. It blocks until the dialog is dismissed.
+// Without dialogs nothing can be confirmed, so it reports false.
+func ShowConfirm(title, message, confirmButton string) bool {
+	if !DialogsAvailable() {
+		slog.Warn("Cannot confirm, treating as cancelled", "title", title, "message", message)
+		return false
+	}
+
+	script := `
+		on run argv
+			set dialogResult to display dialog (item 2 of argv) buttons {"Cancel", item 3 of argv} default button (item 3 of argv) with title (item 1 of argv)
+			return button returned of dialogResult
+		end run
+	`
+
+	output, err := runAppleScript(script, title, message, confirmButton)
+	if err != nil {
+		// osascript exits non-zero when the user cancels
+		return false
+	}
+
+	return strings.TrimSpace(string(output)) == confirmButton
 }
 
 // HotkeyCallback is called when hotkeys are updated.
@@ -124,55 +346,58 @@ func ShowHotkeySettingsDialog(cfg *config.Config, onUpdate HotkeyCallback) {
 	modifierOptions := strings.Join(config.AvailableModifiers, ", ")
 	keyOptions := strings.Join(config.AvailableKeys, ", ")
 
-	script := fmt.Sprintf(`
-		set volumeUpMod to "%s"
-		set volumeUpKey to "%s"
-		set volumeDownMod to "%s"
-		set volumeDownKey to "%s"
-
-		-- Volume Up Modifiers
-		set dialogResult to display dialog "Volume Up - Modifiers:" & return & return & "Options: %s" default answer volumeUpMod buttons {"Cancel", "Next"} default button "Next" with title "KEF Bar - Hotkey Settings (1/4)"
-		if button returned of dialogResult is "Cancel" then
-			return "CANCELLED"
-		end if
-		set volumeUpMod to text returned of dialogResult
-
-		-- Volume Up Key
-		set dialogResult to display dialog "Volume Up - Key:" & return & return & "Options: %s" default answer volumeUpKey buttons {"Cancel", "Next"} default button "Next" with title "KEF Bar - Hotkey Settings (2/4)"
-		if button returned of dialogResult is "Cancel" then
-			return "CANCELLED"
-		end if
-		set volumeUpKey to text returned of dialogResult
-
-		-- Volume Down Modifiers
-		set dialogResult to display dialog "Volume Down - Modifiers:" & return & return & "Options: %s" default answer volumeDownMod buttons {"Cancel", "Next"} default button "Next" with title "KEF Bar - Hotkey Settings (3/4)"
-		if button returned of dialogResult is "Cancel" then
-			return "CANCELLED"
-		end if
-		set volumeDownMod to text returned of dialogResult
-
-		-- Volume Down Key
-		set dialogResult to display dialog "Volume Down - Key:" & return & return & "Options: %s" default answer volumeDownKey buttons {"Cancel", "Save"} default button "Save" with title "KEF Bar - Hotkey Settings (4/4)"
-		if button returned of dialogResult is "Cancel" then
-			return "CANCELLED"
-		end if
-		set volumeDownKey to text returned of dialogResult
-
-		return volumeUpMod & "|" & volumeUpKey & "|" & volumeDownMod & "|" & volumeDownKey
-	`,
-		cfg.VolumeUpHotkey.Modifiers,
-		cfg.VolumeUpHotkey.Key,
-		cfg.VolumeDownHotkey.Modifiers,
-		cfg.VolumeDownHotkey.Key,
-		modifierOptions,
-		keyOptions,
-		modifierOptions,
-		keyOptions,
-	)
-
-	go func() {
-		cmd := exec.Command("osascript", "-e", script)
-		output, err := cmd.Output()
+	script := `
+		on run argv
+			set volumeUpMod to item 1 of argv
+			set volumeUpKey to item 2 of argv
+			set volumeDownMod to item 3 of argv
+			set volumeDownKey to item 4 of argv
+			set modifierOptions to item 5 of argv
+			set keyOptions to item 6 of argv
+
+			-- Volume Up Modifiers
+			set dialogResult to display dialog "Volume Up - Modifiers:" & return & return & "Options: " & modifierOptions default answer volumeUpMod buttons {"Cancel", "Next"} default button "Next" with title "KEF Bar - Hotkey Settings (1/4)"
+			if button returned of dialogResult is "Cancel" then
+				return "CANCELLED"
+			end if
+			set volumeUpMod to text returned of dialogResult
+
+			-- Volume Up Key
+			set dialogResult to display dialog "Volume Up - Key:" & return & return & "Options: " & keyOptions default answer volumeUpKey buttons {"Cancel", "Next"} default button "Next" with title "KEF Bar - Hotkey Settings (2/4)"
+			if button returned of dialogResult is "Cancel" then
+				return "CANCELLED"
+			end if
+			set volumeUpKey to text returned of dialogResult
+
+			-- Volume Down Modifiers
+			set dialogResult to display dialog "Volume Down - Modifiers:" & return & return & "Options: " & modifierOptions default answer volumeDownMod buttons {"Cancel", "Next"} default button "Next" with title "KEF Bar - Hotkey Settings (3/4)"
+			if button returned of dialogResult is "Cancel" then
+				return "CANCELLED"
+			end if
+			set volumeDownMod to text returned of dialogResult
+
+			-- Volume Down Key
+			set dialogResult to display dialog "Volume Down - Key:" & return & return & "Options: " & keyOptions default answer volumeDownKey buttons {"Cancel", "Save"} default button "Save" with title "KEF Bar - Hotkey Settings (4/4)"
+			if button returned of dialogResult is "Cancel" then
+				return "CANCELLED"
+			end if
+			set volumeDownKey to text returned of dialogResult
+
+			return volumeUpMod & "|" & volumeUpKey & "|" & volumeDownMod & "|" & volumeDownKey
+		end run
+	`
+
+	showDialog("hotkey settings dialog", func() {
+		volumeUp := cfg.Hotkey(config.HotkeyVolumeUp)
+		volumeDown := cfg.Hotkey(config.HotkeyVolumeDown)
+		output, err := runAppleScript(script,
+			volumeUp.Modifiers,
+			volumeUp.Key,
+			volumeDown.Modifiers,
+			volumeDown.Key,
+			modifierOptions,
+			keyOptions,
+		)
 		if err != nil {
 			slog.Debug("Hotkey settings cancelled or error", "error", err)
 			return
@@ -190,10 +415,22 @@ func ShowHotkeySettingsDialog(cfg *config.Config, onUpdate HotkeyCallback) {
 		}
 
 		// Update config
-		cfg.VolumeUpHotkey.Modifiers = strings.TrimSpace(parts[0])
-		cfg.VolumeUpHotkey.Key = strings.TrimSpace(parts[1])
-		cfg.VolumeDownHotkey.Modifiers = strings.TrimSpace(parts[2])
-		cfg.VolumeDownHotkey.Key = strings.TrimSpace(parts[3])
+		volumeUp = config.HotkeyBinding{Modifiers: strings.TrimSpace(parts[0]), Key: strings.TrimSpace(parts[1])}
+		volumeDown = config.HotkeyBinding{Modifiers: strings.TrimSpace(parts[2]), Key: strings.TrimSpace(parts[3])}
+
+		// Check the new bindings against each other and the rest before
+		// changing anything
+		updated := *cfg
+		updated.SetHotkey(config.HotkeyVolumeUp, volumeUp)
+		updated.SetHotkey(config.HotkeyVolumeDown, volumeDown)
+		if err := updated.ValidateHotkeys(); err != nil {
+			slog.Warn("Invalid hotkey settings", "error", err)
+			ShowAlert("Invalid Hotkeys", fmt.Sprintf("The hotkeys were not changed:\n\n%v", err))
+			return
+		}
+
+		cfg.SetHotkey(config.HotkeyVolumeUp, volumeUp)
+		cfg.SetHotkey(config.HotkeyVolumeDown, volumeDown)
 
 		// Save config
 		if err := cfg.Save(); err != nil {
@@ -203,8 +440,8 @@ func ShowHotkeySettingsDialog(cfg *config.Config, onUpdate HotkeyCallback) {
 		}
 
 		slog.Info("Hotkey settings updated",
-			"volumeUp", cfg.VolumeUpHotkey.String(),
-			"volumeDown", cfg.VolumeDownHotkey.String())
+			"volumeUp", volumeUp.String(),
+			"volumeDown", volumeDown.String())
 
 		// Notify caller to re-register hotkeys
 		if onUpdate != nil {
@@ -213,8 +450,7 @@ func ShowHotkeySettingsDialog(cfg *config.Config, onUpdate HotkeyCallback) {
 
 		ShowAlert("Hotkeys Updated", fmt.Sprintf(
 			"Volume Up: %s\nVolume Down: %s\n\nHotkeys will be re-registered.",
-			cfg.VolumeUpHotkey.String(),
-			cfg.VolumeDownHotkey.String()))
-	}()
+			volumeUp.Symbols(),
+			volumeDown.Symbols()))
+	})
 }
-
@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/api"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+)
+
+// useFakeOsascript replaces osascript with a shell script running body
+// until the test ends.
+func useFakeOsascript(t *testing.T, body string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "osascript")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	DialogsAvailable() // Look up the real one now, so it isn't looked up later
+	prev := osascriptPath
+	osascriptPath = path
+	t.Cleanup(func() { osascriptPath = prev })
+}
+
+func TestRunAppleScriptArgs(t *testing.T) {
+	// Print each argument NUL-terminated, to see them exactly as received
+	useFakeOsascript(t, `printf '%s\0' "$@"`)
+
+	script := "on run argv\n\treturn item 1 of argv\nend run"
+	args := []string{
+		`Kitchen "Main"`,
+		"two\nlines",
+		`C:\Music\`,
+		`" & (do shell script "say hi") & "`,
+		"",
+	}
+
+	output, err := runAppleScript(script, args...)
+	if err != nil {
+		t.Fatalf("runAppleScript() error = %v", err)
+	}
+
+	got := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	want := append([]string{"-e", script}, args...)
+	if !slices.Equal(got, want) {
+		t.Errorf("osascript got argv %q, want %q", got, want)
+	}
+}
+
+func TestRunAppleScriptUnavailable(t *testing.T) {
+	DialogsAvailable()
+	prev := osascriptPath
+	osascriptPath = ""
+	t.Cleanup(func() { osascriptPath = prev })
+
+	if _, err := runAppleScript("on run argv\nend run"); !errors.Is(err, errDialogsUnavailable) {
+		t.Errorf("runAppleScript() without osascript error = %v, want errDialogsUnavailable", err)
+	}
+}
+
+// speakerStub is a stub KEF speaker holding a JSON value per path, playing
+// Wi-Fi at volume 30.
+type speakerStub struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *speakerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/getData":
+		value, ok := s.values[q.Get("path")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[" + value + "]"))
+	case "/api/setData":
+		s.values[q.Get("path")] = q.Get("value")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newConnectedController returns a controller connected to a speakerStub.
+func newConnectedController(t *testing.T) *controller.Controller {
+	t.Helper()
+
+	server := httptest.NewServer(&speakerStub{values: map[string]string{
+		"player:volume":                     `{"type":"i32_","i32_":30}`,
+		"settings:/mediaPlayer/mute":        `{"type":"bool_","bool_":false}`,
+		"settings:/kef/play/physicalSource": `{"type":"kefPhysicalSource","kefPhysicalSource":"wifi"}`,
+	}})
+	t.Cleanup(server.Close)
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.SpeakerIP = host
+	cfg.Port = port
+	cfg.PollInterval = time.Hour
+	cfg.WriteIntervalMs = 0
+
+	ctrl := controller.New(cfg, api.WithTransport(server.Client().Transport))
+	ctrl.SetIP(host)
+	t.Cleanup(ctrl.Close)
+	if err := ctrl.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return ctrl
+}
+
+// waitForVolume waits for ctrl's volume to reach want.
+func waitForVolume(t *testing.T, ctrl *controller.Controller, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ctrl.GetState().Volume != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("volume = %d, want %d", ctrl.GetState().Volume, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestVolumeStepsWhileDialogOpen(t *testing.T) {
+	// The dialog stays open until answer is written, as a user would
+	// leave it sitting on screen
+	answer := filepath.Join(t.TempDir(), "answer")
+	useFakeOsascript(t, `while [ ! -e '`+answer+`' ]; do sleep 0.01; done; cat '`+answer+`'`)
+	reply := func(text string) {
+		tmp := answer + ".tmp"
+		if err := os.WriteFile(tmp, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, answer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctrl := newConnectedController(t)
+	ShowVolumeDialog(ctrl)
+	t.Cleanup(func() {
+		if _, err := os.Stat(answer); err != nil {
+			reply("")
+		}
+	})
+
+	// Hotkey bursts while the dialog is blocked in osascript
+	want := 30
+	for burst := range 5 {
+		delta := 1
+		if burst%2 == 1 {
+			delta = -1
+		}
+		for range 8 {
+			if ctrl.QueueVolumeStep(delta) {
+				want += delta
+			}
+		}
+		waitForVolume(t, ctrl, want)
+	}
+
+	openDialogsMu.Lock()
+	open := openDialogs["volume dialog"]
+	openDialogsMu.Unlock()
+	if !open {
+		t.Fatal("volume dialog closed before it was answered")
+	}
+
+	// The dialog still works once answered
+	reply("55\n")
+	waitForVolume(t, ctrl, 55)
+}
@@ -7,6 +7,8 @@ import (
 	"image"
 	"image/color"
 	"image/png"
+	"math"
+	"sync"
 
 	"golang.org/x/image/draw"
 )
@@ -17,68 +19,312 @@ var kefLogoPNG []byte
 // Icon size for macOS menu bar.
 const iconSize = 22
 
+// Icon colors.
+var (
+	fillColor   = color.RGBA{0, 0, 0, 255}       // Black fill
+	borderColor = color.RGBA{100, 100, 100, 255} // Gray for outline
+
+	mutedBorderColor = color.RGBA{160, 160, 160, 255} // Lighter gray for the dimmed muted outline
+
+	// Template images only use the alpha channel, so the outline is drawn
+	// translucent to stay distinct from the fill once macOS tints it.
+	templateBorderColor = color.RGBA{0, 0, 0, 110}
+)
+
+// dimFactor scales icon opacity for the dimmed (idle) variants.
+const dimFactor = 0.45
+
+// Icon variants, used as part of the icon cache key.
+const (
+	variantVolume = iota
+	variantVolumeTemplate
+	variantMuted
+	variantMutedTemplate
+)
+
+// iconKey identifies a generated icon in the cache.
+type iconKey struct {
+	variant int
+	volume  int
+	dimmed  bool
+}
+
+// Decoded logo and generated icons. The logo is decoded and scaled once;
+// icons are cached per variant and volume so repeated calls are lookups.
+var (
+	logoOnce   sync.Once
+	scaledLogo *image.RGBA // nil if the embedded logo failed to decode
+	logoTop    int
+	logoBottom int
+
+	iconCacheMu sync.Mutex
+	iconCache   = make(map[iconKey][]byte)
+)
+
 // GenerateVolumeIcon creates the KEF K logo that fills based on volume level.
 // volumePercent should be 0-100.
 // At 0%: just the outline of the logo
 // At 100%: fully filled logo
 func GenerateVolumeIcon(volumePercent int) []byte {
-	// Clamp volume to valid range
+	volumePercent = clampVolume(volumePercent)
+	return cachedIcon(iconKey{variantVolume, volumePercent, false}, func() []byte {
+		return renderVolumeIcon(volumePercent, fillColor, borderColor)
+	})
+}
+
+// GenerateVolumeTemplateIcon creates the volume icon as a macOS template
+// image, which the menu bar tints to match light or dark appearance.
+func GenerateVolumeTemplateIcon(volumePercent int) []byte {
+	volumePercent = clampVolume(volumePercent)
+	return cachedIcon(iconKey{variantVolumeTemplate, volumePercent, false}, func() []byte {
+		return renderVolumeIcon(volumePercent, fillColor, templateBorderColor)
+	})
+}
+
+// GenerateMutedIcon creates the muted icon: the logo outline, dimmed and
+// crossed out with a slash, so it can't be mistaken for a volume level.
+func GenerateMutedIcon() []byte {
+	return cachedIcon(iconKey{variantMuted, 0, false}, func() []byte {
+		return renderMutedIcon(mutedBorderColor, fillColor)
+	})
+}
+
+// GenerateMutedTemplateIcon creates the muted icon as a macOS template image.
+func GenerateMutedTemplateIcon() []byte {
+	return cachedIcon(iconKey{variantMutedTemplate, 0, false}, func() []byte {
+		return renderMutedIcon(templateBorderColor, fillColor)
+	})
+}
+
+// GenerateDimmedIcon creates a faded version of the volume or muted icon,
+// shown after a period of inactivity.
+func GenerateDimmedIcon(volumePercent int, muted bool) []byte {
+	return dimmedIcon(volumePercent, muted, false)
+}
+
+// GenerateDimmedTemplateIcon creates the dimmed icon as a macOS template image.
+func GenerateDimmedTemplateIcon(volumePercent int, muted bool) []byte {
+	return dimmedIcon(volumePercent, muted, true)
+}
+
+// dimmedIcon returns the cached dimmed variant of an icon.
+func dimmedIcon(volumePercent int, muted, template bool) []byte {
+	volumePercent = clampVolume(volumePercent)
+
+	border := borderColor
+	if template {
+		border = templateBorderColor
+	}
+
+	key := iconKey{variantVolume, volumePercent, true}
+	drawIcon := func() *image.RGBA { return drawVolumeImage(volumePercent, fillColor, border) }
+	switch {
+	case muted && template:
+		key = iconKey{variantMutedTemplate, 0, true}
+		drawIcon = func() *image.RGBA { return drawMutedImage(templateBorderColor, fillColor) }
+	case muted:
+		key = iconKey{variantMuted, 0, true}
+		drawIcon = func() *image.RGBA { return drawMutedImage(mutedBorderColor, fillColor) }
+	case template:
+		key.variant = variantVolumeTemplate
+	}
+
+	return cachedIcon(key, func() []byte {
+		img := drawIcon()
+		if img == nil {
+			return getDefaultIcon()
+		}
+		fadeImage(img, dimFactor)
+		return encodeIcon(img)
+	})
+}
+
+// ResetIconCache discards all generated icons. The appearance watcher
+// calls it when the system switches between light and dark mode.
+func ResetIconCache() {
+	iconCacheMu.Lock()
+	defer iconCacheMu.Unlock()
+	iconCache = make(map[iconKey][]byte)
+}
+
+// cachedIcon returns the cached icon for key, rendering it on first use.
+func cachedIcon(key iconKey, render func() []byte) []byte {
+	iconCacheMu.Lock()
+	defer iconCacheMu.Unlock()
+
+	if icon, ok := iconCache[key]; ok {
+		return icon
+	}
+
+	icon := render()
+	iconCache[key] = icon
+	return icon
+}
+
+// loadLogo decodes and scales the embedded logo on first use.
+func loadLogo() *image.RGBA {
+	logoOnce.Do(func() {
+		srcImg, _, err := image.Decode(bytes.NewReader(kefLogoPNG))
+		if err != nil {
+			return
+		}
+
+		// Scale the source logo to icon size
+		scaled := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), srcImg, srcImg.Bounds(), draw.Src, nil)
+
+		scaledLogo = scaled
+		logoTop, logoBottom = logoExtent(scaled)
+	})
+	return scaledLogo
+}
+
+// clampVolume clamps a volume to the valid 0-100 range.
+func clampVolume(volumePercent int) int {
 	if volumePercent < 0 {
-		volumePercent = 0
+		return 0
 	}
 	if volumePercent > 100 {
-		volumePercent = 100
+		return 100
+	}
+	return volumePercent
+}
+
+// renderVolumeIcon draws and encodes the volume icon with the given colors.
+func renderVolumeIcon(volumePercent int, fill, border color.RGBA) []byte {
+	img := drawVolumeImage(volumePercent, fill, border)
+	if img == nil {
+		return getDefaultIcon()
 	}
+	return encodeIcon(img)
+}
 
-	// Decode the embedded logo
-	srcImg, _, err := image.Decode(bytes.NewReader(kefLogoPNG))
-	if err != nil {
+// renderMutedIcon draws and encodes the muted icon with the given colors.
+func renderMutedIcon(border, slash color.RGBA) []byte {
+	img := drawMutedImage(border, slash)
+	if img == nil {
 		return getDefaultIcon()
 	}
+	return encodeIcon(img)
+}
+
+// drawMutedImage draws the logo outline crossed out with a slash. It returns
+// nil if the embedded logo can't be decoded.
+func drawMutedImage(border, slash color.RGBA) *image.RGBA {
+	img := drawVolumeImage(0, border, border)
+	if img == nil {
+		return nil
+	}
+
+	// Two-pixel diagonal slash from top-left to bottom-right
+	for i := 1; i < iconSize-1; i++ {
+		img.SetRGBA(i, i, slash)
+		img.SetRGBA(i+1, i, slash)
+	}
+
+	return img
+}
+
+// fadeImage scales the opacity of every pixel by factor. Colors are
+// premultiplied, so all channels are scaled together.
+func fadeImage(img *image.RGBA, factor float64) {
+	for i, v := range img.Pix {
+		img.Pix[i] = uint8(math.Round(float64(v) * factor))
+	}
+}
+
+// encodeIcon encodes an icon image as PNG.
+func encodeIcon(img *image.RGBA) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return getDefaultIcon()
+	}
+	return buf.Bytes()
+}
+
+// drawVolumeImage draws the logo filled up to the volume level. It returns
+// nil if the embedded logo can't be decoded.
+func drawVolumeImage(volumePercent int, fill, border color.RGBA) *image.RGBA {
+	volumePercent = clampVolume(volumePercent)
+
+	logo := loadLogo()
+	if logo == nil {
+		return nil
+	}
 
 	// Create output image at icon size
 	img := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
 
-	// Scale the source logo to icon size
-	scaledLogo := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
-	draw.CatmullRom.Scale(scaledLogo, scaledLogo.Bounds(), srcImg, srcImg.Bounds(), draw.Src, nil)
+	// Fill line position (from bottom up), in fractional pixel rows. It
+	// tracks the logo's vertical extent rather than the whole canvas.
+	height := float64(logoBottom - logoTop + 1)
+	fillPos := float64(logoBottom+1) - height*float64(volumePercent)/100.0
 
-	// Calculate fill threshold (from bottom up)
-	fillY := int(float64(iconSize) * (1.0 - float64(volumePercent)/100.0))
+	// Process each pixel
+	for y := 0; y < iconSize; y++ {
+		// Fraction of this row below the fill line; the boundary row is
+		// partially covered, which anti-aliases the fill edge
+		coverage := math.Max(0, math.Min(1, float64(y+1)-fillPos))
 
-	// Colors
-	fillColor := color.RGBA{0, 0, 0, 255}         // Black fill
-	borderColor := color.RGBA{100, 100, 100, 255} // Gray for outline
+		for x := 0; x < iconSize; x++ {
+			if !isLogoPixel(logo, x, y) {
+				continue
+			}
 
-	// Process each pixel
+			// Above the fill line the logo shows as an outline only
+			var empty color.RGBA
+			if isEdgePixel(logo, x, y, iconSize) {
+				empty = border
+			}
+
+			switch {
+			case coverage >= 1:
+				img.SetRGBA(x, y, fill)
+			case coverage > 0:
+				img.SetRGBA(x, y, blend(empty, fill, coverage))
+			case empty.A > 0:
+				img.SetRGBA(x, y, empty)
+			}
+		}
+	}
+
+	return img
+}
+
+// isLogoPixel checks if a pixel is part of the logo (non-transparent and dark).
+func isLogoPixel(img *image.RGBA, x, y int) bool {
+	r, g, b, a := img.At(x, y).RGBA()
+	return a > 32768 && (r+g+b)/3 < 32768
+}
+
+// logoExtent returns the first and last rows containing logo pixels. An
+// image without any logo pixels spans the whole canvas.
+func logoExtent(img *image.RGBA) (top, bottom int) {
+	top, bottom = -1, -1
 	for y := 0; y < iconSize; y++ {
 		for x := 0; x < iconSize; x++ {
-			r, g, b, a := scaledLogo.At(x, y).RGBA()
-
-			// Check if this pixel is part of the logo (non-transparent and dark)
-			isLogo := a > 32768 && (r+g+b)/3 < 32768
-
-			if isLogo {
-				if y >= fillY {
-					// Below fill line - show filled color
-					img.SetRGBA(x, y, fillColor)
-				} else {
-					// Above fill line - show as outline/border
-					if isEdgePixel(scaledLogo, x, y, iconSize) {
-						img.SetRGBA(x, y, borderColor)
-					}
+			if isLogoPixel(img, x, y) {
+				if top < 0 {
+					top = y
 				}
+				bottom = y
+				break
 			}
 		}
 	}
 
-	// Encode to PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return getDefaultIcon()
+	if top < 0 {
+		return 0, iconSize - 1
 	}
+	return top, bottom
+}
 
-	return buf.Bytes()
+// blend linearly interpolates between two premultiplied colors.
+func blend(from, to color.RGBA, t float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.RGBA{mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), mix(from.A, to.A)}
 }
 
 // isEdgePixel checks if a pixel is on the edge of the logo.
@@ -87,10 +333,7 @@ func isEdgePixel(img *image.RGBA, x, y, size int) bool {
 		for dx := -1; dx <= 1; dx++ {
 			nx, ny := x+dx, y+dy
 			if nx >= 0 && nx < size && ny >= 0 && ny < size {
-				_, _, _, na := img.At(nx, ny).RGBA()
-				nr, ng, nb, _ := img.At(nx, ny).RGBA()
-				neighborIsLogo := na > 32768 && (nr+ng+nb)/3 < 32768
-				if !neighborIsLogo {
+				if !isLogoPixel(img, nx, ny) {
 					return true
 				}
 			} else {
@@ -120,5 +363,3 @@ func getDefaultIcon() []byte {
 		0xAE, 0x42, 0x60, 0x82,
 	}
 }
-
-
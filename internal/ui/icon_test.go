@@ -0,0 +1,28 @@
+package ui
+
+import "testing"
+
+func BenchmarkGenerateVolumeIcon(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			ResetIconCache()
+			GenerateVolumeIcon(i % 101)
+			i++
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for v := 0; v <= 100; v++ {
+			GenerateVolumeIcon(v)
+		}
+
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			GenerateVolumeIcon(i % 101)
+			i++
+		}
+	})
+}
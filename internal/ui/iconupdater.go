@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// iconState is everything that determines which menu bar icon is shown. It
+// is comparable, so the icon is only regenerated when it changes.
+type iconState struct {
+	volume    int
+	muted     bool
+	connected bool
+	dimmed    bool
+}
+
+// iconStateFor picks the icon-affecting fields out of the speaker state.
+// Anything else (source, playback, errors, …) changing leaves the icon
+// alone.
+func iconStateFor(state kef.SpeakerState, dimmed bool) iconState {
+	if !state.Connected {
+		return iconState{}
+	}
+	return iconState{
+		volume:    state.Volume,
+		muted:     state.Muted,
+		connected: true,
+		dimmed:    dimmed,
+	}
+}
+
+// iconUpdater throttles menu bar icon changes so that rapid volume changes
+// (e.g., a held hotkey) regenerate the icon at most once per interval. The
+// last requested state is always applied once the interval has passed.
+type iconUpdater struct {
+	interval time.Duration
+	apply    func(iconState)
+
+	mu      sync.Mutex
+	pending iconState
+	timer   *time.Timer
+	last    time.Time
+}
+
+// newIconUpdater creates an iconUpdater that calls apply with the latest
+// state at most once per interval.
+func newIconUpdater(interval time.Duration, apply func(iconState)) *iconUpdater {
+	return &iconUpdater{
+		interval: interval,
+		apply:    apply,
+	}
+}
+
+// Set requests that the icon show the given state.
+func (u *iconUpdater) Set(s iconState) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.pending = s
+	if u.timer != nil {
+		// A trailing update is already scheduled and will pick this up
+		return
+	}
+
+	wait := u.interval - time.Since(u.last)
+	if wait < 0 {
+		wait = 0
+	}
+	u.timer = time.AfterFunc(wait, u.flush)
+}
+
+// Redraw applies the most recently requested state again, e.g. after the
+// icon cache was reset.
+func (u *iconUpdater) Redraw() {
+	u.mu.Lock()
+	s := u.pending
+	u.mu.Unlock()
+
+	u.Set(s)
+}
+
+// flush applies the most recently requested state.
+func (u *iconUpdater) flush() {
+	u.mu.Lock()
+	s := u.pending
+	u.timer = nil
+	u.last = time.Now()
+	u.mu.Unlock()
+
+	u.apply(s)
+}
+
+// applyIcon sets the menu bar icon for the given state.
+func applyIcon(s iconState) {
+	if !s.connected {
+		setVolumeIcon(0)
+	} else if s.dimmed {
+		setDimmedIcon(s.volume, s.muted)
+	} else if s.muted {
+		setMutedIcon()
+	} else {
+		setVolumeIcon(s.volume)
+	}
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+func TestIconStateFor(t *testing.T) {
+	base := kef.SpeakerState{Connected: true, Volume: 40, Source: kef.SourceWifi}
+
+	changes := []struct {
+		name       string
+		change     func(*kef.SpeakerState)
+		dimmed     bool
+		regenerate bool
+	}{
+		{name: "mute", change: func(s *kef.SpeakerState) { s.Muted = true }, regenerate: true},
+		{name: "volume", change: func(s *kef.SpeakerState) { s.Volume = 41 }, regenerate: true},
+		{name: "disconnect", change: func(s *kef.SpeakerState) { s.Connected = false }, regenerate: true},
+		{name: "dimmed", change: func(s *kef.SpeakerState) {}, dimmed: true, regenerate: true},
+		{name: "source", change: func(s *kef.SpeakerState) { s.Source = kef.SourceTV }},
+		{name: "playback", change: func(s *kef.SpeakerState) { s.PlaybackInfo = &kef.PlaybackInfo{Title: "Xtal"} }},
+		{name: "error", change: func(s *kef.SpeakerState) { s.Error = "timeout" }},
+		{name: "name", change: func(s *kef.SpeakerState) { s.Name = "Office" }},
+	}
+
+	for _, tt := range changes {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+
+			got := iconStateFor(changed, tt.dimmed) != iconStateFor(base, false)
+			if got != tt.regenerate {
+				t.Errorf("icon regenerated = %t, want %t", got, tt.regenerate)
+			}
+		})
+	}
+}
+
+func TestIconStateForDisconnected(t *testing.T) {
+	// Nothing but the connection matters while disconnected
+	a := kef.SpeakerState{Volume: 10, Muted: true}
+	b := kef.SpeakerState{Volume: 90, Error: "no route to host"}
+	if iconStateFor(a, true) != iconStateFor(b, false) {
+		t.Error("disconnected states gave different icons")
+	}
+}
+
+func TestIconUpdaterAppliesLatest(t *testing.T) {
+	var mu sync.Mutex
+	var applied []iconState
+	done := make(chan struct{}, 10)
+	u := newIconUpdater(50*time.Millisecond, func(s iconState) {
+		mu.Lock()
+		applied = append(applied, s)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	// The first change shows at once; a burst right after it is throttled
+	// down to its last value
+	u.Set(iconState{connected: true, volume: 1})
+	<-done
+	for v := 2; v <= 10; v++ {
+		u.Set(iconState{connected: true, volume: v})
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("trailing update never applied")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(applied) != 2 {
+		t.Fatalf("applied %d icons, want 2: %v", len(applied), applied)
+	}
+	if applied[1].volume != 10 {
+		t.Errorf("trailing update showed volume %d, want the latest 10", applied[1].volume)
+	}
+}
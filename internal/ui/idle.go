@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"sync"
+	"time"
+)
+
+// idleTracker decides when the menu bar icon should dim: after a period
+// with no playback and no user interaction.
+type idleTracker struct {
+	timeout time.Duration
+	now     func() time.Time
+
+	mu         sync.Mutex
+	lastActive time.Time
+}
+
+// newIdleTracker creates an idleTracker that dims after timeout. now is the
+// clock to use; nil means time.Now.
+func newIdleTracker(timeout time.Duration, now func() time.Time) *idleTracker {
+	if now == nil {
+		now = time.Now
+	}
+	return &idleTracker{
+		timeout:    timeout,
+		now:        now,
+		lastActive: now(),
+	}
+}
+
+// Touch records activity, undimming the icon.
+func (t *idleTracker) Touch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastActive = t.now()
+}
+
+// Dimmed reports whether the icon should be dimmed. Ongoing playback counts
+// as activity. A non-positive timeout never dims.
+func (t *idleTracker) Dimmed(playing bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if playing {
+		t.lastActive = now
+	}
+	return t.timeout > 0 && now.Sub(t.lastActive) >= t.timeout
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestIdleTracker(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 10, 14, 21, 0, 0, 0, time.UTC)}
+	idle := newIdleTracker(5*time.Minute, clock.now)
+
+	if idle.Dimmed(false) {
+		t.Fatal("dimmed right after starting")
+	}
+
+	clock.advance(5*time.Minute - time.Second)
+	if idle.Dimmed(false) {
+		t.Error("dimmed before the timeout")
+	}
+	clock.advance(time.Second)
+	if !idle.Dimmed(false) {
+		t.Error("not dimmed at the timeout")
+	}
+
+	// Interaction undims, and the timeout starts over
+	idle.Touch()
+	if idle.Dimmed(false) {
+		t.Error("still dimmed after Touch")
+	}
+	clock.advance(4 * time.Minute)
+	if idle.Dimmed(false) {
+		t.Error("dimmed 4 minutes after Touch")
+	}
+
+	// Playback keeps it lit however long it lasts, and counts as activity
+	// once it stops
+	clock.advance(time.Hour)
+	if idle.Dimmed(true) {
+		t.Error("dimmed while playing")
+	}
+	clock.advance(time.Minute)
+	if idle.Dimmed(false) {
+		t.Error("dimmed a minute after playback stopped")
+	}
+	clock.advance(4 * time.Minute)
+	if !idle.Dimmed(false) {
+		t.Error("not dimmed 5 minutes after playback stopped")
+	}
+}
+
+func TestIdleTrackerDisabled(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 10, 14, 21, 0, 0, 0, time.UTC)}
+
+	for _, timeout := range []time.Duration{0, -time.Minute} {
+		idle := newIdleTracker(timeout, clock.now)
+		clock.advance(24 * time.Hour)
+		if idle.Dimmed(false) {
+			t.Errorf("timeout %v dimmed", timeout)
+		}
+	}
+}
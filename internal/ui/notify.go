@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+// trackNotifyDelay is how long a new track must stay current before it is
+// announced, so quickly skipping through tracks only announces the last.
+const trackNotifyDelay = 2 * time.Second
+
+// trackNotifier announces track changes, debounced. A track is announced
+// at most once in a row, so metadata refreshes and seeking stay quiet.
+type trackNotifier struct {
+	delay time.Duration
+	show  func(title, message string)
+
+	mu        sync.Mutex
+	pending   kef.PlaybackInfo
+	timer     *time.Timer
+	announced string // key of the last track announced
+}
+
+// newTrackNotifier creates a trackNotifier that calls show once a new track
+// has been current for delay.
+func newTrackNotifier(delay time.Duration, show func(title, message string)) *trackNotifier {
+	return &trackNotifier{
+		delay: delay,
+		show:  show,
+	}
+}
+
+// TrackChanged records that info is now playing.
+func (n *trackNotifier) TrackChanged(info kef.PlaybackInfo) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pending = info
+	if n.timer != nil {
+		n.timer.Reset(n.delay)
+		return
+	}
+	n.timer = time.AfterFunc(n.delay, n.fire)
+}
+
+// fire announces the pending track unless it was the last one announced.
+func (n *trackNotifier) fire() {
+	n.mu.Lock()
+	info := n.pending
+	n.timer = nil
+	key := trackKey(info)
+	if key == n.announced {
+		n.mu.Unlock()
+		return
+	}
+	n.announced = key
+	n.mu.Unlock()
+
+	n.show(trackNotification(info))
+}
+
+// trackKey identifies a track for deduplication.
+func trackKey(info kef.PlaybackInfo) string {
+	return info.Title + "\x00" + info.Artist
+}
+
+// trackNotification returns the notification title and message for a track.
+func trackNotification(info kef.PlaybackInfo) (title, message string) {
+	message = info.Artist
+	if info.Album != "" {
+		if message != "" {
+			message += " — "
+		}
+		message += info.Album
+	}
+	return info.Title, message
+}
@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"errors"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inquire/kefbar-go/internal/discovery"
+)
+
+// permissionCheckDelay is how long the startup check waits for permission
+// reports from elsewhere, such as hotkey registration, before deciding.
+const permissionCheckDelay = 3 * time.Second
+
+// Permission is a macOS privacy permission the app depends on.
+type Permission int
+
+// Permissions checked at startup.
+const (
+	PermissionLocalNetwork Permission = iota
+	PermissionAccessibility
+	PermissionNotifications
+)
+
+// permissionPane names a permission and links to its System Settings pane.
+type permissionPane struct {
+	perm Permission
+	name string
+	why  string
+	url  string
+}
+
+// permissionPanes lists the panes in the order the guidance dialog shows
+// them.
+var permissionPanes = []permissionPane{
+	{PermissionLocalNetwork, "Local Network", "to find and control the speaker",
+		"x-apple.systempreferences:com.apple.preference.security?Privacy_LocalNetwork"},
+	{PermissionAccessibility, "Accessibility", "for global hotkeys (also check Input Monitoring)",
+		"x-apple.systempreferences:com.apple.preference.security?Privacy_Accessibility"},
+	{PermissionNotifications, "Notifications", "for track change notifications",
+		"x-apple.systempreferences:com.apple.preference.notifications"},
+}
+
+// permissionState is what is known about a permission.
+type permissionState int
+
+const (
+	permissionUnknown permissionState = iota
+	permissionGranted
+	permissionDenied
+)
+
+// PermissionCheck finds missing permissions at startup and explains them
+// in one dialog rather than leaving the user with features that silently
+// don't work. Each permission is brought up at most once per run.
+type PermissionCheck struct {
+	notify bool // whether track notifications are enabled
+
+	mu     sync.Mutex
+	states map[Permission]permissionState
+	shown  map[Permission]bool
+	done   bool // the startup check has decided
+}
+
+// NewPermissionCheck creates a check. notify says whether track
+// notifications are enabled, so their permission matters.
+func NewPermissionCheck(notify bool) *PermissionCheck {
+	return &PermissionCheck{
+		notify: notify,
+		states: make(map[Permission]permissionState),
+		shown:  make(map[Permission]bool),
+	}
+}
+
+// Report records whether perm was found to be granted. A denial reported
+// after the startup check has decided is brought up on its own.
+func (p *PermissionCheck) Report(perm Permission, granted bool) {
+	state := permissionDenied
+	if granted {
+		state = permissionGranted
+	}
+
+	p.mu.Lock()
+	p.states[perm] = state
+	done := p.done
+	p.mu.Unlock()
+
+	if done {
+		p.prompt()
+	}
+}
+
+// Run probes what it can, waits briefly for other reports and then shows
+// the guidance dialog if anything is missing. It blocks, so run it in the
+// background.
+func (p *PermissionCheck) Run() {
+	err := discovery.CheckLocalNetwork()
+	switch {
+	case err == nil:
+		p.Report(PermissionLocalNetwork, true)
+	case errors.Is(err, discovery.ErrLocalNetworkDenied):
+		p.Report(PermissionLocalNetwork, false)
+	default:
+		// No network, say; that isn't a permission problem
+		slog.Debug("Local network check inconclusive", "error", err)
+	}
+
+	// Notifications are posted through osascript, so they can't work
+	// without it. Whether they are allowed can't be asked from here.
+	if p.notify && !DialogsAvailable() {
+		p.Report(PermissionNotifications, false)
+	}
+
+	time.Sleep(permissionCheckDelay)
+
+	p.mu.Lock()
+	p.done = true
+	p.mu.Unlock()
+	p.prompt()
+}
+
+// prompt shows the guidance dialog for any missing permissions not yet
+// brought up.
+func (p *PermissionCheck) prompt() {
+	missing := p.takeMissing()
+	if len(missing) == 0 {
+		return
+	}
+
+	var names []string
+	for _, perm := range missing {
+		name := paneFor(perm).name
+		slog.Warn("Missing macOS permission", "permission", name)
+		names = append(names, name)
+	}
+	showDialog("permissions "+strings.Join(names, ","), func() { showPermissionGuidance(missing) })
+}
+
+// takeMissing returns the denied permissions not yet shown, in dialog
+// order, and marks them shown. Notifications only count while they are
+// enabled.
+func (p *PermissionCheck) takeMissing() []Permission {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var missing []Permission
+	for _, pane := range permissionPanes {
+		if p.states[pane.perm] != permissionDenied || p.shown[pane.perm] {
+			continue
+		}
+		if pane.perm == PermissionNotifications && !p.notify {
+			continue
+		}
+		p.shown[pane.perm] = true
+		missing = append(missing, pane.perm)
+	}
+	return missing
+}
+
+// showPermissionGuidance lists the missing permissions and opens the
+// settings pane for the one picked. It blocks until the dialog is closed.
+func showPermissionGuidance(missing []Permission) {
+	var lines, names []string
+	for _, perm := range missing {
+		pane := paneFor(perm)
+		lines = append(lines, "• "+pane.name+" — "+pane.why)
+		names = append(names, pane.name)
+	}
+	message := "KEF Bar is missing these permissions in System Settings:\n\n" +
+		strings.Join(lines, "\n") + "\n\nPick one to open its settings."
+
+	script := `
+		on run argv
+			set choice to choose from list (items 2 thru -1 of argv) with title "KEF Bar Permissions" with prompt (item 1 of argv) default items {item 2 of argv} OK button name "Open Settings" cancel button name "Later"
+			if choice is false then return ""
+			return item 1 of choice
+		end run
+	`
+	output, err := runAppleScript(script, append([]string{message}, names...)...)
+	if err != nil {
+		slog.Warn("Permissions", "message", message, "error", err)
+		return
+	}
+
+	choice := strings.TrimSpace(string(output))
+	for _, pane := range permissionPanes {
+		if pane.name == choice {
+			openSettings(pane.url)
+		}
+	}
+}
+
+// paneFor returns the settings pane of perm.
+func paneFor(perm Permission) permissionPane {
+	for _, pane := range permissionPanes {
+		if pane.perm == perm {
+			return pane
+		}
+	}
+	return permissionPane{perm: perm, name: "Unknown"}
+}
+
+// openSettings opens a System Settings deep link.
+func openSettings(url string) {
+	if out, err := exec.Command("open", url).CombinedOutput(); err != nil {
+		slog.Warn("Failed to open System Settings", "url", url, "error", err, "output", string(out))
+	}
+}
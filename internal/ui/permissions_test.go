@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// captureLogs sends log output to the returned buffer until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestTakeMissingOrder(t *testing.T) {
+	p := NewPermissionCheck(true)
+	// Reported out of dialog order, along with one that is fine
+	p.Report(PermissionNotifications, false)
+	p.Report(PermissionAccessibility, false)
+	p.Report(PermissionLocalNetwork, false)
+	p.Report(PermissionLocalNetwork, true)
+
+	want := []Permission{PermissionAccessibility, PermissionNotifications}
+	if got := p.takeMissing(); !slices.Equal(got, want) {
+		t.Errorf("takeMissing() = %v, want %v", got, want)
+	}
+	if got := p.takeMissing(); len(got) != 0 {
+		t.Errorf("takeMissing() again = %v, want nothing: each is brought up once", got)
+	}
+
+	// Denied again later, it still isn't brought up a second time
+	p.Report(PermissionAccessibility, false)
+	if got := p.takeMissing(); len(got) != 0 {
+		t.Errorf("takeMissing() after a repeat denial = %v, want nothing", got)
+	}
+}
+
+func TestTakeMissingNotificationsDisabled(t *testing.T) {
+	p := NewPermissionCheck(false)
+	p.Report(PermissionNotifications, false)
+	p.Report(PermissionLocalNetwork, false)
+
+	if got, want := p.takeMissing(), []Permission{PermissionLocalNetwork}; !slices.Equal(got, want) {
+		t.Errorf("takeMissing() = %v, want %v: notifications are off", got, want)
+	}
+}
+
+func TestPermissionLateReport(t *testing.T) {
+	logs := captureLogs(t)
+	p := NewPermissionCheck(true)
+
+	// Before the startup check decides, reports are only collected
+	p.Report(PermissionLocalNetwork, false)
+	if strings.Contains(logs.String(), "Missing macOS permission") {
+		t.Fatalf("Report() before the check prompted: %q", logs.String())
+	}
+
+	// The check decides, as Run does after its delay
+	p.mu.Lock()
+	p.done = true
+	p.mu.Unlock()
+	p.prompt()
+	if !strings.Contains(logs.String(), "permission=\"Local Network\"") {
+		t.Fatalf("prompt() didn't bring up Local Network: %q", logs.String())
+	}
+
+	// A denial reported later, e.g. by hotkey registration, is brought up
+	// on its own
+	logs.Reset()
+	p.Report(PermissionAccessibility, false)
+	out := logs.String()
+	if !strings.Contains(out, "permission=Accessibility") || strings.Contains(out, "Local Network") {
+		t.Errorf("late report logged %q, want Accessibility alone", out)
+	}
+
+	// And only once
+	logs.Reset()
+	p.Report(PermissionAccessibility, false)
+	if strings.Contains(logs.String(), "Missing macOS permission") {
+		t.Errorf("repeated late report prompted again: %q", logs.String())
+	}
+}
@@ -2,31 +2,77 @@ package ui
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/systray"
-	"github/com/inquire/kefbar-go/internal/config"
-	"github/com/inquire/kefbar-go/internal/controller"
-	"github/com/inquire/kefbar-go/internal/discovery"
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/internal/controller"
+	"github.com/inquire/kefbar-go/internal/discovery"
+	"github.com/inquire/kefbar-go/internal/safe"
+	"github.com/inquire/kefbar-go/pkg/kef"
 )
 
 // App represents the systray application.
 type App struct {
 	ctrl           *controller.Controller
 	cfg            *config.Config
-	lastVolume     int
+	lastIcon       iconState // Icon last shown; volume -1 until the first update sets one
+	idle           *idleTracker
+	icon           *iconUpdater
 	onHotkeyUpdate func()
 	playPauseItem  *systray.MenuItem
+	muteItem       *systray.MenuItem
+	volumeUpItem   *systray.MenuItem
+	volumeDownItem *systray.MenuItem
+	presetItems    []*systray.MenuItem
+	sourceItem     *systray.MenuItem
+	sourceItems    map[string]*systray.MenuItem
+	moreItem       *systray.MenuItem
+	speakerItems   []speakerItem
+	reconnectItem  *systray.MenuItem
+	statusItem     *systray.MenuItem // clickable while there is an error
+	copyTrackItem  *systray.MenuItem
+	qualityItem    *systray.MenuItem
+	pairItem       *systray.MenuItem
+	reconnecting   atomic.Bool
+	scanner        *discovery.Scanner
+
+	// cancelDiscovery cancels the discovery in progress, if any
+	discoveryMu     sync.Mutex
+	cancelDiscovery context.CancelFunc
+
+	titleVolumeItem    *systray.MenuItem
+	voiceAssistantItem *systray.MenuItem
+	autoPowerOnItem    *systray.MenuItem
+	headphonesItem     *systray.MenuItem
+}
+
+// speakerItem ties a speaker profile to its menu item.
+type speakerItem struct {
+	profile config.SpeakerProfile
+	item    *systray.MenuItem
 }
 
 // NewApp creates a new systray application.
 func NewApp(ctrl *controller.Controller, cfg *config.Config) *App {
+	scanner := discovery.NewScanner()
+	scanner.Scheme = cfg.Scheme
+	scanner.Port = cfg.Port
+
 	return &App{
-		ctrl:       ctrl,
-		cfg:        cfg,
-		lastVolume: -1,
+		ctrl:     ctrl,
+		cfg:      cfg,
+		lastIcon: iconState{volume: -1}, // matches no state, so the first update sets the icon
+		scanner:  scanner,
+		icon:     newIconUpdater(time.Duration(cfg.IconDebounceMs)*time.Millisecond, applyIcon),
+		idle:     newIdleTracker(time.Duration(cfg.DimAfterMinutes)*time.Minute, nil),
 	}
 }
 
@@ -42,82 +88,495 @@ func (a *App) Run(onExit func()) {
 
 // onReady sets up the systray menu.
 func (a *App) onReady() {
-	systray.SetIcon(GenerateVolumeIcon(0))
+	setVolumeIcon(0)
 	systray.SetTitle("")
 	systray.SetTooltip("KEF Speaker Controller")
 
 	// Menu items
-	statusItem := systray.AddMenuItem("🔌 Not Connected", "")
-	statusItem.Disable()
+	a.statusItem = systray.AddMenuItem("🔌 Not Connected", "")
+	a.statusItem.Disable()
+
+	a.reconnectItem = systray.AddMenuItem("🔄 Reconnect", "")
+	a.reconnectItem.Hide()
 
 	volumeItem := systray.AddMenuItem("🔊 Volume: --", "")
 	volumeItem.Disable()
+	setVolumeItem := a.addVolumeSubmenu(volumeItem)
 
 	playbackItem := systray.AddMenuItem("🎵 No playback info", "")
 	playbackItem.Disable()
+	a.qualityItem = systray.AddMenuItem("🎚️ --", "")
+	a.qualityItem.Disable()
+	a.qualityItem.Hide()
+	a.copyTrackItem = a.addAdvancedMenuItem("📋 Copy Now Playing")
+	a.copyTrackItem.Disable()
+
+	a.headphonesItem = systray.AddMenuItem("🎧 Headphones in use – speakers may be silent", "")
+	a.headphonesItem.Disable()
+	a.headphonesItem.Hide()
 
 	systray.AddSeparator()
 
 	prevItem := systray.AddMenuItem("⏮️ Previous Track", "")
-	a.playPauseItem = systray.AddMenuItem("⏸️ Pause", "")
+	a.playPauseItem = systray.AddMenuItem(withHotkey("▶️ Play", a.cfg.Hotkey(config.HotkeyPlayPause)), "")
+	a.playPauseItem.Disable()
 	nextItem := systray.AddMenuItem("⏭️ Next Track", "")
+	a.muteItem = systray.AddMenuItem("🔇 Mute", "")
+	a.muteItem.Disable()
+
+	systray.AddSeparator()
+
+	a.sourceItem = systray.AddMenuItem(a.sourceTitle(""), "")
+	a.sourceItem.Disable()
+	a.addSourceSubmenu()
+	a.pairItem = a.addAdvancedMenuItem("📶 Pair Bluetooth")
+	a.pairItem.Hide()
 
 	systray.AddSeparator()
 
-	discoverItem := systray.AddMenuItem("🔍 Discover Speaker", "")
+	discoverItem := a.addAdvancedMenuItem("🔍 Discover Speaker")
+	exportItem := a.addAdvancedMenuItem("📋 Copy Speaker List")
+	configDumpItem := a.addAdvancedMenuItem("🧾 Copy Effective Config")
+	a.addSpeakersSubmenu()
+	a.addMacrosSubmenu()
+	a.addPresetsSubmenu()
 
 	systray.AddSeparator()
 
 	// Settings submenu
 	settingsItem := systray.AddMenuItem("⚙️ Speaker Settings", "")
-	hotkeyItem := systray.AddMenuItem("⌨️ Hotkey Settings", "")
+	renameItem := a.addAdvancedMenuItem("✏️ Rename Speaker…")
+	a.titleVolumeItem = systray.AddMenuItemCheckbox("💯 Show Volume in Menu Bar", "", a.cfg.ShowVolumeInTitle)
+	a.voiceAssistantItem = systray.AddMenuItemCheckbox("🎙️ Voice Assistant", "", false)
+	a.voiceAssistantItem.Hide()
+	a.autoPowerOnItem = systray.AddMenuItemCheckbox("🌅 Wake on Signal", "", false)
+	a.autoPowerOnItem.Hide()
+	hotkeyItem := a.addAdvancedMenuItem("⌨️ Hotkey Settings")
 
 	// Show current hotkey bindings
-	hotkeyInfoItem := systray.AddMenuItem(
+	hotkeyInfoItem := a.addAdvancedMenuItem(
 		fmt.Sprintf("   Vol+: %s  Vol-: %s  Play/Pause: %s",
-			a.cfg.VolumeUpHotkey.String(),
-			a.cfg.VolumeDownHotkey.String(),
-			a.cfg.PlayPauseHotkey.String()),
-		"")
+			a.cfg.Hotkey(config.HotkeyVolumeUp).Symbols(),
+			a.cfg.Hotkey(config.HotkeyVolumeDown).Symbols(),
+			a.cfg.Hotkey(config.HotkeyPlayPause).Symbols()))
 	hotkeyInfoItem.Disable()
 
 	systray.AddSeparator()
 
 	quitItem := systray.AddMenuItem("🚪 Quit", "")
 
+	// Announce new tracks if enabled
+	if a.cfg.NotifyOnTrackChange {
+		notifier := newTrackNotifier(trackNotifyDelay, ShowNotification)
+		a.ctrl.SetTrackChangeCallback(notifier.TrackChanged)
+	}
+
 	// Start update loop
-	go a.updateLoop(statusItem, volumeItem, playbackItem, hotkeyInfoItem)
+	safe.GoRestart("ui update loop", func() {
+		a.updateLoop(volumeItem, playbackItem, hotkeyInfoItem)
+	})
+
+	// Redraw the icon for light and dark mode
+	safe.GoRestart("appearance watcher", a.watchAppearance)
 
 	// Handle menu clicks
-	go a.handleMenuClicks(
-		prevItem, nextItem, discoverItem,
-		settingsItem, hotkeyItem, volumeItem, quitItem,
-	)
+	safe.GoRestart("menu click handler", func() {
+		a.handleMenuClicks(
+			prevItem, nextItem, discoverItem, exportItem, configDumpItem,
+			settingsItem, renameItem, hotkeyItem, setVolumeItem, quitItem,
+		)
+	})
+}
+
+// volumePresets are the levels offered in the volume submenu.
+var volumePresets = []int{0, 10, 25, 50, 75, 100}
+
+// addVolumeSubmenu builds the volume submenu with preset levels and step
+// items under the live volume readout. It returns the "Set Volume…" item.
+func (a *App) addVolumeSubmenu(volumeItem *systray.MenuItem) *systray.MenuItem {
+	for _, level := range volumePresets {
+		item := volumeItem.AddSubMenuItemCheckbox(fmt.Sprintf("%d%%", level), "", false)
+		a.presetItems = append(a.presetItems, item)
+
+		safe.GoRestart("volume preset item", func() {
+			for range item.ClickedCh {
+				slog.Info("Volume preset selected", "level", level)
+				if err := a.ctrl.SetVolume(level); err != nil {
+					slog.Error("Failed to set volume", "error", err)
+				}
+			}
+		})
+	}
+
+	volumeItem.AddSeparator()
+	upTitle, downTitle := a.volumeStepTitles()
+	a.volumeUpItem = volumeItem.AddSubMenuItem(upTitle, "")
+	a.volumeDownItem = volumeItem.AddSubMenuItem(downTitle, "")
+	volumeItem.AddSeparator()
+
+	return volumeItem.AddSubMenuItem("✏️ Set Volume…", "")
+}
+
+// addSourceSubmenu adds a child item per physical source under the input
+// item. Sources the model doesn't support are rejected by the speaker.
+func (a *App) addSourceSubmenu() {
+	a.sourceItems = make(map[string]*systray.MenuItem, len(kef.Sources))
+	for _, source := range kef.Sources {
+		item := a.sourceItem.AddSubMenuItemCheckbox(kef.SourceLabel(source), "", false)
+		a.sourceItems[source] = item
+
+		safe.GoRestart("source item", func() {
+			for range item.ClickedCh {
+				slog.Info("Source selected", "source", source)
+				if err := a.ctrl.SetSource(source); err != nil {
+					slog.Error("Failed to set source", "source", source, "error", err)
+				}
+			}
+		})
+	}
 }
 
-// updateLoop periodically updates the UI with current state.
-func (a *App) updateLoop(statusItem, volumeItem, playbackItem, hotkeyInfoItem *systray.MenuItem) {
+// addSpeakersSubmenu lists the configured speaker profiles for switching.
+// Nothing is added when there are no profiles.
+func (a *App) addSpeakersSubmenu() {
+	if len(a.cfg.Speakers) == 0 {
+		return
+	}
+
+	speakersItem := systray.AddMenuItem("🔈 Speakers", "")
+	for _, profile := range a.cfg.Speakers {
+		item := speakersItem.AddSubMenuItemCheckbox(profile.Name+" ("+profile.IP+")", "", false)
+		a.speakerItems = append(a.speakerItems, speakerItem{profile: profile, item: item})
+
+		safe.GoRestart("speaker item", func() {
+			for range item.ClickedCh {
+				a.switchSpeaker(profile)
+			}
+		})
+	}
+}
+
+// shouldConfirmSwitch reports whether switching to the target speaker needs
+// confirmation: only when enabled and the current speaker is playing.
+func shouldConfirmSwitch(cfg *config.Config, state kef.SpeakerState, target string) bool {
+	if !cfg.ConfirmSpeakerSwitch || !state.Connected || state.IPAddress == target {
+		return false
+	}
+	return state.PlaybackInfo != nil && state.PlaybackInfo.State == "playing"
+}
+
+// switchSpeaker makes the given profile the active speaker, confirming
+// first if the current speaker is playing.
+func (a *App) switchSpeaker(profile config.SpeakerProfile) {
+	state := a.ctrl.GetState()
+	if state.IPAddress == profile.IP && state.Connected {
+		return
+	}
+
+	playing := state.PlaybackInfo != nil && state.PlaybackInfo.State == "playing"
+	if shouldConfirmSwitch(a.cfg, state, profile.IP) {
+		message := fmt.Sprintf("Switch to %s? This will stop controlling %s.",
+			profile.Name, a.cfg.SpeakerName(state.IPAddress))
+		if !ShowConfirm("KEF Bar", message, "Switch") {
+			slog.Info("Speaker switch cancelled", "target", profile.Name)
+			return
+		}
+	}
+
+	if playing && a.cfg.PauseOnSpeakerSwitch {
+		if err := a.ctrl.PlayPause(); err != nil {
+			slog.Warn("Failed to pause before switching speakers", "error", err)
+		}
+	}
+
+	slog.Info("Switching speaker", "name", profile.Name, "ip", profile.IP)
+	a.ctrl.SetIP(profile.IP)
+	_ = config.SaveIP(profile.IP)
+
+	if err := a.ctrl.Connect(); err != nil {
+		slog.Error("Connection failed after switching speakers", "error", err)
+	}
+}
+
+// closestPreset returns the index of the volume preset nearest to volume.
+func closestPreset(volume int) int {
+	best := 0
+	for i, level := range volumePresets {
+		if abs(level-volume) < abs(volumePresets[best]-volume) {
+			best = i
+		}
+	}
+	return best
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// addAdvancedMenuItem adds a menu item that is not essential for everyday
+// use, where advancedPlacement says.
+func (a *App) addAdvancedMenuItem(title string) *systray.MenuItem {
+	switch advancedPlacement(a.cfg.CompactMenu, a.moreItem != nil) {
+	case placeInNewMore:
+		a.moreItem = systray.AddMenuItem("➕ More…", "")
+	case placeTopLevel:
+		return systray.AddMenuItem(title, "")
+	}
+	return a.moreItem.AddSubMenuItem(title, "")
+}
+
+// menuPlacement is where an advanced menu item goes.
+type menuPlacement int
+
+const (
+	placeTopLevel  menuPlacement = iota // In the menu itself
+	placeInMore                         // In the existing "More…" submenu
+	placeInNewMore                      // In a "More…" submenu created for it
+)
+
+// advancedPlacement decides where an advanced menu item goes. In compact
+// mode these items are collapsed into a "More…" submenu, which is created
+// on first use so it takes the position of the first advanced item.
+// haveMore says whether it has been created.
+func advancedPlacement(compact, haveMore bool) menuPlacement {
+	switch {
+	case !compact:
+		return placeTopLevel
+	case !haveMore:
+		return placeInNewMore
+	default:
+		return placeInMore
+	}
+}
+
+// addPresetsSubmenu lists the play presets, if any are configured.
+func (a *App) addPresetsSubmenu() {
+	if len(a.cfg.PlayPresets) == 0 {
+		return
+	}
+
+	presetsItem := systray.AddMenuItem("📻 Presets", "")
+	for _, preset := range a.cfg.PlayPresets {
+		item := presetsItem.AddSubMenuItem(preset.Name, "")
+
+		safe.GoRestart("preset item", func() {
+			for range item.ClickedCh {
+				if err := a.ctrl.PlayPreset(preset.Name); err != nil {
+					slog.Error("Preset failed", "preset", preset.Name, "error", err)
+					ShowAlert("Preset Failed", fmt.Sprintf("%s: %v", preset.Name, err))
+				}
+			}
+		})
+	}
+}
+
+// addMacrosSubmenu adds a submenu for running the configured macros. It is
+// omitted when there are none.
+func (a *App) addMacrosSubmenu() {
+	if len(a.cfg.Macros) == 0 {
+		return
+	}
+
+	macrosItem := systray.AddMenuItem("⚡ Macros", "")
+	for _, macro := range a.cfg.Macros {
+		title := macro.Name
+		if macro.Hotkey != nil {
+			title = withHotkey(title, *macro.Hotkey)
+		}
+		item := macrosItem.AddSubMenuItem(title, "")
+
+		safe.GoRestart("macro item", func() {
+			for range item.ClickedCh {
+				if err := a.ctrl.RunMacro(macro.Name); err != nil {
+					slog.Error("Macro failed", "macro", macro.Name, "error", err)
+					ShowAlert("Macro Failed", fmt.Sprintf("%s: %v", macro.Name, err))
+				}
+			}
+		})
+	}
+}
+
+// setVolumeIcon sets the menu bar icon for the given volume. On macOS the
+// template variant is used so the icon adapts to light and dark menu bars.
+func setVolumeIcon(volume int) {
+	systray.SetTemplateIcon(GenerateVolumeTemplateIcon(volume), GenerateVolumeIcon(volume))
+}
+
+// setMutedIcon sets the muted menu bar icon.
+func setMutedIcon() {
+	systray.SetTemplateIcon(GenerateMutedTemplateIcon(), GenerateMutedIcon())
+}
+
+// setDimmedIcon sets the faded menu bar icon shown while idle.
+func setDimmedIcon(volume int, muted bool) {
+	systray.SetTemplateIcon(GenerateDimmedTemplateIcon(volume, muted), GenerateDimmedIcon(volume, muted))
+}
+
+// setChecked sets a checkbox item's checkmark.
+func setChecked(item *systray.MenuItem, checked bool) {
+	if checked {
+		item.Check()
+	} else {
+		item.Uncheck()
+	}
+}
+
+// withHotkey appends the hotkey glyphs to a menu item title so users can
+// learn the shortcut for the action. Unbound hotkeys are skipped.
+func withHotkey(title string, bindings ...config.HotkeyBinding) string {
+	for _, binding := range bindings {
+		if binding.Key != "" {
+			title += "    " + binding.Symbols()
+		}
+	}
+	return title
+}
+
+// volumeStepTitles returns the titles of the volume step items, with their
+// hotkeys.
+func (a *App) volumeStepTitles() (up, down string) {
+	up = withHotkey(fmt.Sprintf("➕ %d%%", a.cfg.VolumeStep), a.cfg.Hotkey(config.HotkeyVolumeUp))
+	down = withHotkey(fmt.Sprintf("➖ %d%%", a.cfg.VolumeStep), a.cfg.Hotkey(config.HotkeyVolumeDown))
+	return up, down
+}
+
+// sourceTitle returns the input item's title for source, or for no known
+// source if it is empty, with the source hotkeys that can run.
+func (a *App) sourceTitle(source string) string {
+	title := "🎛️ Input"
+	if source != "" {
+		title += ": " + kef.SourceLabel(source)
+	}
+
+	var bindings []config.HotkeyBinding
+	if a.cfg.SourceToggleA != "" && a.cfg.SourceToggleB != "" {
+		bindings = append(bindings, a.cfg.Hotkey(config.HotkeySourceToggle))
+	}
+	if len(a.cfg.CycleSources) >= 2 {
+		bindings = append(bindings, a.cfg.Hotkey(config.HotkeyCycleSource))
+	}
+	return withHotkey(title, bindings...)
+}
+
+// statusTitle describes the connected speaker for the status line, by
+// name where it has one, and shows when it's in standby.
+func statusTitle(state kef.SpeakerState) string {
+	icon := "✅ "
+	if !state.IsPoweredOn {
+		icon = "😴 Standby · "
+	}
+	switch {
+	case state.Name != "" && state.Model != "":
+		return icon + state.Name + " · " + state.Model + " (" + state.IPAddress + ")"
+	case state.Name != "":
+		return icon + state.Name + " (" + state.IPAddress + ")"
+	case state.Model != "":
+		return icon + state.Model + " (" + state.IPAddress + ")"
+	case !state.IsPoweredOn:
+		return "😴 Standby: " + state.IPAddress
+	default:
+		return "✅ Connected: " + state.IPAddress
+	}
+}
+
+// updateLoop updates the UI whenever the speaker state changes. A slow
+// ticker also refreshes it for UI-only changes such as idle dimming.
+func (a *App) updateLoop(volumeItem, playbackItem, hotkeyInfoItem *systray.MenuItem) {
 	ticker := time.NewTicker(config.DefaultUIInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	updates := a.ctrl.Subscribe()
+
+	for {
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+		}
+
 		state := a.ctrl.GetState()
+		dimmed := false
 
 		if state.Connected {
-			statusText := "✅ Connected: " + state.IPAddress
-			if state.Model != "" {
-				statusText = "✅ " + state.Model + " (" + state.IPAddress + ")"
+			a.statusItem.SetTitle(statusTitle(state))
+			if state.Muted {
+				volumeItem.SetTitle("🔇 Volume: Muted")
+				a.muteItem.SetTitle("🔊 Unmute")
+			} else {
+				volumeItem.SetTitle(fmt.Sprintf("🔊 Volume: %d%%", state.Volume))
+				a.muteItem.SetTitle("🔇 Mute")
 			}
-			statusItem.SetTitle(statusText)
-			volumeItem.SetTitle(fmt.Sprintf("🔊 Volume: %d%%", state.Volume))
 			volumeItem.Enable()
+			a.muteItem.Enable()
 
-			// Update icon if volume changed
-			if state.Volume != a.lastVolume {
-				systray.SetIcon(GenerateVolumeIcon(state.Volume))
-				a.lastVolume = state.Volume
+			if state.Source != "" {
+				a.sourceItem.SetTitle(a.sourceTitle(state.Source))
+			}
+			a.sourceItem.Enable()
+			for source, item := range a.sourceItems {
+				setChecked(item, source == state.Source)
+				// Hide inputs the model doesn't have, unless somehow active
+				if source == state.Source || a.ctrl.Supports(kef.SourceFeature(source)) {
+					item.Show()
+				} else {
+					item.Hide()
+				}
 			}
 
+			// Only show device toggles the speaker supports
+			if a.ctrl.Supports(kef.FeatureVoiceAssistant) {
+				setChecked(a.voiceAssistantItem, state.VoiceAssistant)
+				a.voiceAssistantItem.Show()
+			} else {
+				a.voiceAssistantItem.Hide()
+			}
+			if a.ctrl.Supports(kef.FeatureAutoPowerOn) {
+				setChecked(a.autoPowerOnItem, state.AutoPowerOn)
+				a.autoPowerOnItem.Show()
+			} else {
+				a.autoPowerOnItem.Hide()
+			}
+			if a.ctrl.SupportsBluetooth() {
+				a.pairItem.Show()
+			} else {
+				a.pairItem.Hide()
+			}
+
+			// Explain silent speakers when headphones have taken over
+			if a.ctrl.Supports(kef.FeatureHeadphones) && state.Headphones {
+				a.headphonesItem.Show()
+			} else {
+				a.headphonesItem.Hide()
+			}
+
+			// Check the preset closest to the current volume
+			closest := closestPreset(state.Volume)
+			for i, item := range a.presetItems {
+				setChecked(item, i == closest && !state.Muted)
+			}
+
+			if a.cfg.ShowVolumeInTitle {
+				if state.Muted {
+					systray.SetTitle("🔇")
+				} else {
+					systray.SetTitle(fmt.Sprintf("%d%%", state.Volume))
+				}
+			} else {
+				systray.SetTitle("")
+			}
+
+			// A volume or mute change is activity, wherever it came from
+			if state.Volume != a.lastIcon.volume || state.Muted != a.lastIcon.muted {
+				a.idle.Touch()
+			}
+			dimmed = a.cfg.DimWhenIdle && a.idle.Dimmed(a.ctrl.IsPlaying())
+
 			if state.PlaybackInfo != nil {
 				info := state.PlaybackInfo
 				title := "No title"
@@ -127,47 +586,106 @@ func (a *App) updateLoop(statusItem, volumeItem, playbackItem, hotkeyInfoItem *s
 				if info.Artist != "" {
 					title += " - " + info.Artist
 				}
-				playbackItem.SetTitle("🎵 " + title)
-
-				// Update play/pause button based on state
-				if info.State == "playing" {
-					a.playPauseItem.SetTitle("⏸️ Pause")
-				} else {
-					a.playPauseItem.SetTitle("▶️ Play")
+				if info.QueueLength > 0 {
+					title += fmt.Sprintf(" (Track %d of %d)", info.QueueIndex, info.QueueLength)
 				}
+				playbackItem.SetTitle("🎵 " + title)
 			} else {
 				playbackItem.SetTitle("🎵 No playback info")
-				a.playPauseItem.SetTitle("▶️ Play")
 			}
+			if quality := streamQuality(state.PlaybackInfo); quality != "" {
+				a.qualityItem.SetTitle("🎚️ " + quality)
+				a.qualityItem.Show()
+			} else {
+				a.qualityItem.Hide()
+			}
+			if nowPlayingText(state.PlaybackInfo) != "" {
+				a.copyTrackItem.Enable()
+			} else {
+				a.copyTrackItem.Disable()
+			}
+
+			// Update play/pause button based on state
+			if a.ctrl.IsPlaying() {
+				a.playPauseItem.SetTitle(withHotkey("⏸️ Pause", a.cfg.Hotkey(config.HotkeyPlayPause)))
+			} else {
+				a.playPauseItem.SetTitle(withHotkey("▶️ Play", a.cfg.Hotkey(config.HotkeyPlayPause)))
+			}
+			a.playPauseItem.Enable()
 		} else {
-			statusItem.SetTitle("🔌 Not Connected")
+			a.statusItem.SetTitle("🔌 Not Connected")
 			volumeItem.SetTitle("🔊 Volume: --")
 			volumeItem.Disable()
+			a.muteItem.SetTitle("🔇 Mute")
+			a.muteItem.Disable()
+			a.sourceItem.SetTitle(a.sourceTitle(""))
+			a.sourceItem.Disable()
+			a.voiceAssistantItem.Hide()
+			a.autoPowerOnItem.Hide()
+			a.pairItem.Hide()
+			a.headphonesItem.Hide()
 			playbackItem.SetTitle("🎵 No playback info")
-			a.playPauseItem.SetTitle("▶️ Play")
+			a.copyTrackItem.Disable()
+			a.qualityItem.Hide()
+			a.playPauseItem.SetTitle(withHotkey("▶️ Play", a.cfg.Hotkey(config.HotkeyPlayPause)))
+			a.playPauseItem.Disable()
+			systray.SetTitle("")
+		}
 
-			if a.lastVolume != -1 {
-				systray.SetIcon(GenerateVolumeIcon(0))
-				a.lastVolume = -1
-			}
+		// Only regenerate the icon when something it shows has changed
+		if icon := iconStateFor(state, dimmed); icon != a.lastIcon {
+			a.icon.Set(icon)
+			a.lastIcon = icon
 		}
 
+		// Long errors are cut short here; clicking shows them in full
 		if state.Error != "" {
-			statusItem.SetTitle("❌ Error: " + state.Error)
+			a.statusItem.SetTitle(errorTitle(state.Error))
+			a.statusItem.Enable()
+		} else {
+			a.statusItem.Disable()
+		}
+
+		for _, sp := range a.speakerItems {
+			setChecked(sp.item, sp.profile.IP == state.IPAddress)
+		}
+
+		// Offer a reconnect while disconnected or errored
+		if !a.reconnecting.Load() {
+			if state.IPAddress != "" && (!state.Connected || state.Error != "") {
+				a.reconnectItem.Show()
+			} else {
+				a.reconnectItem.Hide()
+			}
 		}
 
-		// Update hotkey info display
+		// Update hotkey info display and the items showing hotkeys
 		hotkeyInfoItem.SetTitle(fmt.Sprintf("   Vol+: %s  Vol-: %s  Play/Pause: %s",
-			a.cfg.VolumeUpHotkey.String(),
-			a.cfg.VolumeDownHotkey.String(),
-			a.cfg.PlayPauseHotkey.String()))
+			a.cfg.Hotkey(config.HotkeyVolumeUp).Symbols(),
+			a.cfg.Hotkey(config.HotkeyVolumeDown).Symbols(),
+			a.cfg.Hotkey(config.HotkeyPlayPause).Symbols()))
+		upTitle, downTitle := a.volumeStepTitles()
+		a.volumeUpItem.SetTitle(upTitle)
+		a.volumeDownItem.SetTitle(downTitle)
+	}
+}
+
+// maxErrorTitleLen is how many characters of an error the status line
+// shows before cutting it short.
+const maxErrorTitleLen = 60
+
+// errorTitle is the status line for an error, cut to maxErrorTitleLen.
+func errorTitle(msg string) string {
+	if r := []rune(msg); len(r) > maxErrorTitleLen {
+		msg = string(r[:maxErrorTitleLen]) + "…"
 	}
+	return "❌ Error: " + msg
 }
 
 // handleMenuClicks processes menu item clicks.
 func (a *App) handleMenuClicks(
-	prevItem, nextItem, discoverItem,
-	settingsItem, hotkeyItem, volumeItem, quitItem *systray.MenuItem,
+	prevItem, nextItem, discoverItem, exportItem, configDumpItem,
+	settingsItem, renameItem, hotkeyItem, setVolumeItem, quitItem *systray.MenuItem,
 ) {
 	for {
 		select {
@@ -194,18 +712,88 @@ func (a *App) handleMenuClicks(
 				slog.Error("Failed to skip next", "error", err)
 			}
 
+		case <-a.muteItem.ClickedCh:
+			slog.Info("Mute toggle requested")
+			if err := a.ctrl.ToggleMute(); err != nil {
+				slog.Error("Failed to toggle mute", "error", err)
+			}
+
+		case <-a.titleVolumeItem.ClickedCh:
+			a.cfg.ShowVolumeInTitle = !a.cfg.ShowVolumeInTitle
+			setChecked(a.titleVolumeItem, a.cfg.ShowVolumeInTitle)
+			if err := a.cfg.Save(); err != nil {
+				slog.Error("Failed to save menu preferences", "error", err)
+			}
+
+		case <-a.voiceAssistantItem.ClickedCh:
+			enabled := !a.ctrl.GetState().VoiceAssistant
+			slog.Info("Voice assistant toggle requested", "enabled", enabled)
+			if err := a.ctrl.SetVoiceAssistant(enabled); err != nil {
+				slog.Error("Failed to set voice assistant", "error", err)
+			}
+
+		case <-a.autoPowerOnItem.ClickedCh:
+			enabled := !a.ctrl.GetState().AutoPowerOn
+			slog.Info("Wake on signal toggle requested", "enabled", enabled)
+			if err := a.ctrl.SetAutoPowerOn(enabled); err != nil {
+				slog.Error("Failed to set wake on signal", "error", err)
+			}
+
+		case <-a.pairItem.ClickedCh:
+			slog.Info("Bluetooth pairing requested")
+			safe.Go("bluetooth pairing", func() {
+				if err := a.ctrl.EnterBluetoothPairing(); err != nil {
+					slog.Error("Failed to start Bluetooth pairing", "error", err)
+					ShowAlert("Bluetooth Pairing Failed", err.Error())
+					return
+				}
+				ShowNotification("Bluetooth Pairing", "The speaker is ready to pair. Connect to it from your device's Bluetooth settings.")
+			})
+
+		case <-a.copyTrackItem.ClickedCh:
+			a.copyNowPlaying()
+
+		case <-a.statusItem.ClickedCh:
+			state := a.ctrl.GetState()
+			if state.Error != "" {
+				ShowErrorDetails(state.IPAddress, state.Error)
+			}
+
+		case <-a.reconnectItem.ClickedCh:
+			safe.Go("reconnect", a.handleReconnect)
+
 		case <-discoverItem.ClickedCh:
-			go a.handleDiscovery(discoverItem)
+			a.toggleDiscovery(discoverItem)
+
+		case <-exportItem.ClickedCh:
+			safe.Go("speaker export", func() { a.handleExport(exportItem) })
+
+		case <-configDumpItem.ClickedCh:
+			a.copyEffectiveConfig()
 
 		case <-settingsItem.ClickedCh:
 			slog.Info("Speaker settings opened")
 			ShowSettingsDialog(a.ctrl)
 
+		case <-renameItem.ClickedCh:
+			slog.Info("Rename dialog opened")
+			ShowRenameDialog(a.ctrl)
+
 		case <-hotkeyItem.ClickedCh:
 			slog.Info("Hotkey settings opened")
 			ShowHotkeySettingsDialog(a.cfg, a.onHotkeyUpdate)
 
-		case <-volumeItem.ClickedCh:
+		case <-a.volumeUpItem.ClickedCh:
+			if err := a.ctrl.VolumeUp(); err != nil {
+				slog.Error("Failed to increase volume", "error", err)
+			}
+
+		case <-a.volumeDownItem.ClickedCh:
+			if err := a.ctrl.VolumeDown(); err != nil {
+				slog.Error("Failed to decrease volume", "error", err)
+			}
+
+		case <-setVolumeItem.ClickedCh:
 			slog.Info("Volume dialog opened")
 			ShowVolumeDialog(a.ctrl)
 
@@ -214,30 +802,240 @@ func (a *App) handleMenuClicks(
 			systray.Quit()
 			return
 		}
+
+		// Any menu interaction undims the icon
+		a.idle.Touch()
+	}
+}
+
+// handleReconnect re-attempts a connection to the current speaker IP.
+func (a *App) handleReconnect() {
+	runReconnect(&a.reconnecting, a.reconnectItem, a.ctrl.GetState().IPAddress, a.ctrl.Connect)
+}
+
+// menuItem is the part of *systray.MenuItem that menu handlers update, so
+// they can run without a menu bar.
+type menuItem interface {
+	SetTitle(title string)
+	Enable()
+	Disable()
+}
+
+// runReconnect calls connect to reconnect to ip, showing progress on item
+// until it returns. It does nothing if running shows a reconnect is
+// already under way, and reports whether it tried.
+func runReconnect(running *atomic.Bool, item menuItem, ip string, connect func() error) bool {
+	if !running.CompareAndSwap(false, true) {
+		return false
+	}
+	defer running.Store(false)
+
+	slog.Info("Reconnect requested", "ip", ip)
+	item.SetTitle("🔄 Reconnecting...")
+	item.Disable()
+
+	if err := connect(); err != nil {
+		slog.Warn("Reconnect failed", "ip", ip, "error", err)
+	} else {
+		slog.Info("Reconnected to speaker", "ip", ip)
 	}
+
+	item.SetTitle("🔄 Reconnect")
+	item.Enable()
+	return true
 }
 
-// handleDiscovery performs speaker discovery.
-func (a *App) handleDiscovery(discoverItem *systray.MenuItem) {
+// discoveryTimeout bounds a discovery started from the menu.
+const discoveryTimeout = 10 * time.Second
+
+// toggleDiscovery starts discovery, or cancels the one in progress. Until a
+// cancelled discovery has wound down the item is disabled, so two never
+// share the scanner.
+func (a *App) toggleDiscovery(discoverItem *systray.MenuItem) {
+	a.discoveryMu.Lock()
+	defer a.discoveryMu.Unlock()
+
+	if a.cancelDiscovery != nil {
+		slog.Info("Cancelling discovery")
+		a.cancelDiscovery()
+		discoverItem.SetTitle("🔄 Cancelling...")
+		discoverItem.Disable()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancelDiscovery = cancel
+	safe.Go("discovery", func() {
+		defer func() {
+			a.discoveryMu.Lock()
+			a.cancelDiscovery = nil
+			a.discoveryMu.Unlock()
+			cancel()
+
+			discoverItem.SetTitle("🔍 Discover Speaker")
+			discoverItem.Enable()
+		}()
+		a.handleDiscovery(ctx, discoverItem)
+	})
+}
+
+// handleDiscovery performs speaker discovery, connecting to the speaker
+// found unless ctx is cancelled first.
+func (a *App) handleDiscovery(ctx context.Context, discoverItem *systray.MenuItem) {
 	slog.Info("Starting discovery")
-	discoverItem.SetTitle("🔄 Discovering...")
-	discoverItem.Disable()
+	discoverItem.SetTitle("🔄 Discovering... (click to cancel)")
 
-	ip, err := discovery.Discover(context.Background(), 10*time.Second)
-	if err == nil {
-		slog.Info("Discovery found speaker", "ip", ip)
-		a.ctrl.SetIP(ip)
-		_ = config.SaveIP(ip)
+	// Show scan progress; an interrupted scan resumes on the next discovery
+	a.scanner.OnProgress = func(p discovery.ScanProgress) {
+		if ctx.Err() == nil {
+			discoverItem.SetTitle(fmt.Sprintf("🔄 Discovering... %d%% (click to cancel)", p.Scanned*100/p.Total))
+		}
+	}
 
-		if err := a.ctrl.Connect(); err != nil {
-			slog.Error("Connection failed after discovery", "error", err)
-		} else {
-			slog.Info("Connected to discovered speaker", "ip", ip)
+	speaker, err := discovery.DiscoverSpeaker(ctx, discoveryTimeout, discovery.Options{
+		Methods:           a.cfg.DiscoveryMethods,
+		SSDPBudgetPercent: a.cfg.SSDPBudgetPercent,
+		Scanner:           a.scanner,
+	})
+	if err == nil {
+		// SSDP may find a speaker whose API isn't where it's configured
+		if pingErr := a.ctrl.Ping(ctx, speaker.IP); pingErr != nil {
+			err = fmt.Errorf("speaker at %s not reachable: %w", speaker.IP, pingErr)
 		}
-	} else {
+	}
+	if ctx.Err() != nil {
+		slog.Info("Discovery cancelled")
+		return
+	}
+	if err != nil {
 		slog.Warn("Discovery failed", "error", err)
+		return
+	}
+
+	ip := speaker.IP
+	slog.Info("Discovery found speaker", "ip", ip, "model", speaker.Model)
+	a.ctrl.SetIP(ip)
+	a.ctrl.SetDiscoveredModel(speaker.Model)
+	_ = config.SaveIP(ip)
+
+	if err := a.ctrl.Connect(); err != nil {
+		slog.Error("Connection failed after discovery", "error", err)
+	} else {
+		slog.Info("Connected to discovered speaker", "ip", ip)
+	}
+}
+
+// handleExport finds every speaker on the network and copies the list to
+// the clipboard as JSON.
+func (a *App) handleExport(exportItem *systray.MenuItem) {
+	slog.Info("Exporting speaker list")
+	exportItem.SetTitle("🔄 Finding Speakers...")
+	exportItem.Disable()
+	defer func() {
+		exportItem.SetTitle("📋 Copy Speaker List")
+		exportItem.Enable()
+	}()
+
+	speakers, err := discovery.DiscoverAll(context.Background(), 10*time.Second, discovery.Options{
+		Methods: a.cfg.DiscoveryMethods,
+		Scheme:  a.cfg.Scheme,
+		Port:    a.cfg.Port,
+	})
+	if err != nil {
+		slog.Warn("Speaker export failed", "error", err)
+		ShowAlert("Export Failed", err.Error())
+		return
+	}
+
+	data, err := json.MarshalIndent(speakers, "", "  ")
+	if err != nil {
+		slog.Error("Failed to encode speaker list", "error", err)
+		return
+	}
+
+	if err := CopyToClipboard(string(data)); err != nil {
+		slog.Warn("Failed to copy speaker list", "error", err)
+		ShowAlert("Export Failed", err.Error())
+		return
+	}
+
+	slog.Info("Copied speaker list", "count", len(speakers))
+	ShowAlert("Speaker List Copied", fmt.Sprintf("Copied %d speaker(s) to the clipboard as JSON.", len(speakers)))
+}
+
+// streamQuality describes the quality of the playing stream, e.g.
+// "96kHz/24bit FLAC", or is empty when the source doesn't report it.
+func streamQuality(info *kef.PlaybackInfo) string {
+	if info == nil {
+		return ""
+	}
+
+	var format []string
+	if info.SampleRate > 0 {
+		format = append(format, strconv.FormatFloat(float64(info.SampleRate)/1000, 'f', -1, 64)+"kHz")
+	}
+	if info.BitDepth > 0 {
+		format = append(format, strconv.Itoa(info.BitDepth)+"bit")
 	}
 
-	discoverItem.SetTitle("🔍 Discover Speaker")
-	discoverItem.Enable()
+	var parts []string
+	if len(format) > 0 {
+		parts = append(parts, strings.Join(format, "/"))
+	}
+	if info.Codec != "" {
+		parts = append(parts, info.Codec)
+	}
+	return strings.Join(parts, " ")
+}
+
+// nowPlayingText is the track as "Title - Artist", or just the title, or
+// empty when there is no title.
+func nowPlayingText(info *kef.PlaybackInfo) string {
+	if info == nil {
+		return ""
+	}
+	title, artist := strings.TrimSpace(info.Title), strings.TrimSpace(info.Artist)
+	switch {
+	case title == "":
+		return ""
+	case artist == "":
+		return title
+	default:
+		return title + " - " + artist
+	}
+}
+
+// copyNowPlaying copies the current track to the clipboard.
+func (a *App) copyNowPlaying() {
+	text := nowPlayingText(a.ctrl.GetState().PlaybackInfo)
+	if text == "" {
+		slog.Info("Nothing playing to copy")
+		return
+	}
+
+	if err := CopyToClipboard(text); err != nil {
+		slog.Warn("Failed to copy now playing", "error", err)
+		ShowAlert("Copy Failed", err.Error())
+	}
+}
+
+// copyEffectiveConfig copies the resolved configuration, with secrets
+// redacted, to the clipboard as JSON for debugging.
+func (a *App) copyEffectiveConfig() {
+	values, err := a.cfg.Effective(a.ctrl.GetState().IPAddress)
+	if err != nil {
+		slog.Error("Failed to resolve effective config", "error", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		slog.Error("Failed to encode effective config", "error", err)
+		return
+	}
+
+	if err := CopyToClipboard(string(data)); err != nil {
+		slog.Warn("Failed to copy effective config", "error", err)
+		ShowAlert("Copy Failed", err.Error())
+	}
 }
@@ -0,0 +1,200 @@
+package ui
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/inquire/kefbar-go/internal/config"
+	"github.com/inquire/kefbar-go/pkg/kef"
+)
+
+func TestShouldConfirmSwitch(t *testing.T) {
+	const current, target = "192.168.1.20", "192.168.1.30"
+	playing := kef.SpeakerState{Connected: true, IPAddress: current, PlaybackInfo: &kef.PlaybackInfo{State: "playing"}}
+	paused := kef.SpeakerState{Connected: true, IPAddress: current, PlaybackInfo: &kef.PlaybackInfo{State: "paused"}}
+	idle := kef.SpeakerState{Connected: true, IPAddress: current}
+	disconnected := playing
+	disconnected.Connected = false
+
+	tests := []struct {
+		name    string
+		confirm bool
+		state   kef.SpeakerState
+		target  string
+		want    bool
+	}{
+		{"playing", true, playing, target, true},
+		{"paused", true, paused, target, false},
+		{"idle", true, idle, target, false},
+		{"disconnected", true, disconnected, target, false},
+		{"same speaker", true, playing, current, false},
+		{"confirmation off", false, playing, target, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.New()
+			cfg.ConfirmSpeakerSwitch = tt.confirm
+			if got := shouldConfirmSwitch(cfg, tt.state, tt.target); got != tt.want {
+				t.Errorf("shouldConfirmSwitch() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeMenuItem records what is done to a menu item.
+type fakeMenuItem struct {
+	calls []string
+}
+
+func (m *fakeMenuItem) SetTitle(title string) { m.calls = append(m.calls, "title "+title) }
+func (m *fakeMenuItem) Enable()               { m.calls = append(m.calls, "enable") }
+func (m *fakeMenuItem) Disable()              { m.calls = append(m.calls, "disable") }
+
+func TestRunReconnect(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+	}{
+		{"success", nil},
+		{"failure", errors.New("no route to host")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var running atomic.Bool
+			item := &fakeMenuItem{}
+			connects := 0
+			connect := func() error {
+				connects++
+				item.calls = append(item.calls, "connect")
+				if !running.Load() {
+					t.Error("connect called without the reconnect marked running")
+				}
+				return tt.err
+			}
+
+			if !runReconnect(&running, item, "192.168.1.20", connect) {
+				t.Fatal("runReconnect() didn't try")
+			}
+			if connects != 1 {
+				t.Errorf("connect called %d times, want once", connects)
+			}
+			// The item shows progress, then is ready for another try
+			// whatever the outcome
+			want := []string{"title 🔄 Reconnecting...", "disable", "connect", "title 🔄 Reconnect", "enable"}
+			if !slices.Equal(item.calls, want) {
+				t.Errorf("item calls = %q, want %q", item.calls, want)
+			}
+			if running.Load() {
+				t.Error("reconnect still marked running")
+			}
+		})
+	}
+}
+
+func TestRunReconnectAlreadyRunning(t *testing.T) {
+	var running atomic.Bool
+	running.Store(true)
+	item := &fakeMenuItem{}
+
+	tried := runReconnect(&running, item, "192.168.1.20", func() error {
+		t.Error("connect called during another reconnect")
+		return nil
+	})
+	if tried || len(item.calls) != 0 {
+		t.Errorf("runReconnect() = %t with item calls %q, want it to do nothing", tried, item.calls)
+	}
+}
+
+func TestAdvancedPlacement(t *testing.T) {
+	// Everyday items and advanced ones (marked *) in the order onReady adds
+	// them
+	items := []string{"Volume", "*Copy Now Playing", "Play", "*Pair Bluetooth", "*Discover Speaker", "Quit"}
+
+	// layout builds the top level of the menu, with the "More…" submenu's
+	// items after it in brackets
+	layout := func(compact bool) []string {
+		var top []string
+		more := -1
+		for _, item := range items {
+			title, advanced := strings.CutPrefix(item, "*")
+			if !advanced {
+				top = append(top, title)
+				continue
+			}
+			switch advancedPlacement(compact, more >= 0) {
+			case placeTopLevel:
+				top = append(top, title)
+			case placeInNewMore:
+				more = len(top)
+				top = append(top, "More…["+title+"]")
+			case placeInMore:
+				top[more] = strings.TrimSuffix(top[more], "]") + ", " + title + "]"
+			}
+		}
+		return top
+	}
+
+	if got, want := layout(false), []string{"Volume", "Copy Now Playing", "Play", "Pair Bluetooth", "Discover Speaker", "Quit"}; !slices.Equal(got, want) {
+		t.Errorf("full menu = %q, want %q", got, want)
+	}
+	// The submenu sits where the first advanced item would have
+	if got, want := layout(true), []string{"Volume", "More…[Copy Now Playing, Pair Bluetooth, Discover Speaker]", "Play", "Quit"}; !slices.Equal(got, want) {
+		t.Errorf("compact menu = %q, want %q", got, want)
+	}
+}
+
+func TestVolumeStepTitles(t *testing.T) {
+	cfg := config.New()
+	cfg.VolumeStep = 5
+	cfg.SetHotkey(config.HotkeyVolumeDown, config.HotkeyBinding{})
+	a := &App{cfg: cfg}
+
+	up, down := a.volumeStepTitles()
+	if want := "➕ 5%    " + cfg.Hotkey(config.HotkeyVolumeUp).Symbols(); up != want {
+		t.Errorf("up title = %q, want %q", up, want)
+	}
+	if want := "➖ 5%"; down != want {
+		t.Errorf("down title with no hotkey = %q, want %q", down, want)
+	}
+}
+
+func TestSourceTitle(t *testing.T) {
+	toggle := config.HotkeyBinding{Modifiers: "Ctrl+Alt", Key: "i"}
+	cycle := config.HotkeyBinding{Modifiers: "Ctrl+Alt", Key: "o"}
+
+	tests := []struct {
+		name         string
+		toggleA      string
+		cycleSources []string
+		source       string
+		want         string
+	}{
+		{"no source hotkeys", "", nil, kef.SourceWifi, "🎛️ Input: " + kef.SourceLabel(kef.SourceWifi)},
+		{"unknown source", "", nil, "", "🎛️ Input"},
+		{"toggle", kef.SourceWifi, nil, kef.SourceWifi, "🎛️ Input: " + kef.SourceLabel(kef.SourceWifi) + "    ⌃⌥I"},
+		{"cycle", "", []string{kef.SourceWifi, kef.SourceUSB}, "", "🎛️ Input    ⌃⌥O"},
+		{"both", kef.SourceWifi, []string{kef.SourceWifi, kef.SourceUSB}, "", "🎛️ Input    ⌃⌥I    ⌃⌥O"},
+		// Neither hotkey runs without the sources it needs
+		{"toggle without sources", "", []string{kef.SourceWifi}, "", "🎛️ Input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.New()
+			cfg.SetHotkey(config.HotkeySourceToggle, toggle)
+			cfg.SetHotkey(config.HotkeyCycleSource, cycle)
+			if tt.toggleA != "" {
+				cfg.SourceToggleA, cfg.SourceToggleB = tt.toggleA, kef.SourceBluetooth
+			}
+			cfg.CycleSources = tt.cycleSources
+			a := &App{cfg: cfg}
+
+			if got := a.sourceTitle(tt.source); got != tt.want {
+				t.Errorf("sourceTitle(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
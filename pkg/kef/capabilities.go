@@ -0,0 +1,79 @@
+package kef
+
+import "strings"
+
+// Model capabilities, fixed by the hardware rather than probed.
+const (
+	FeatureSubwooferOut = "subwoofer_out" // Subwoofer output
+)
+
+// SourceFeature is the capability of having the given physical source, for
+// use with the capability map.
+func SourceFeature(source string) string {
+	return "source:" + source
+}
+
+// modelCapabilities lists what each known model has, keyed by the model as
+// reported in its release text. Models not listed are assumed to have
+// everything.
+var modelCapabilities = map[string][]string{
+	"LSX": {
+		SourceFeature(SourceWifi), SourceFeature(SourceBluetooth),
+		SourceFeature(SourceOptical), SourceFeature(SourceAnalog),
+		FeatureSubwooferOut,
+	},
+	"LSXII": {
+		SourceFeature(SourceWifi), SourceFeature(SourceBluetooth), SourceFeature(SourceTV),
+		SourceFeature(SourceOptical), SourceFeature(SourceAnalog), SourceFeature(SourceUSB),
+		FeatureSubwooferOut,
+	},
+	"LSXIILT": {
+		SourceFeature(SourceWifi), SourceFeature(SourceBluetooth), SourceFeature(SourceTV),
+		SourceFeature(SourceOptical), SourceFeature(SourceUSB),
+		FeatureSubwooferOut,
+	},
+	"LS50WII": {
+		SourceFeature(SourceWifi), SourceFeature(SourceBluetooth), SourceFeature(SourceTV),
+		SourceFeature(SourceOptical), SourceFeature(SourceCoaxial), SourceFeature(SourceAnalog),
+		FeatureSubwooferOut,
+	},
+	"LS60": {
+		SourceFeature(SourceWifi), SourceFeature(SourceBluetooth), SourceFeature(SourceTV),
+		SourceFeature(SourceOptical), SourceFeature(SourceCoaxial), SourceFeature(SourceAnalog),
+		FeatureSubwooferOut,
+	},
+}
+
+// modelFeatures are the features the capability map covers. Others, such
+// as the probed optional features, are outside its say.
+var modelFeatures = func() map[string]bool {
+	features := map[string]bool{FeatureSubwooferOut: true}
+	for _, source := range Sources {
+		features[SourceFeature(source)] = true
+	}
+	return features
+}()
+
+// ModelSupports reports whether the model has the feature according to the
+// capability map. Unknown models, and features the map doesn't cover, are
+// assumed supported.
+func ModelSupports(model, feature string) bool {
+	capabilities, ok := modelCapabilities[normalizeModel(model)]
+	if !ok || !modelFeatures[feature] {
+		return true
+	}
+	for _, capability := range capabilities {
+		if capability == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeModel turns model names as shown elsewhere (e.g., "LSX II" or
+// "LS50 Wireless II") into the release text form used as map keys.
+func normalizeModel(model string) string {
+	model = strings.ToUpper(model)
+	model = strings.NewReplacer(" ", "", "-", "", "WIRELESS", "W").Replace(model)
+	return model
+}
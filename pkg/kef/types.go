@@ -1,6 +1,103 @@
 // Package kef provides shared types for KEF speaker control.
 package kef
 
+import "strings"
+
+// Physical sources as reported by settings:/kef/play/physicalSource.
+const (
+	SourceWifi      = "wifi"
+	SourceBluetooth = "bluetooth"
+	SourceTV        = "tv"
+	SourceOptical   = "optic"
+	SourceCoaxial   = "coaxial"
+	SourceAnalog    = "analog"
+	SourceUSB       = "usb"
+	SourceStandby   = "standby"
+)
+
+// Sources lists the selectable physical sources in menu order.
+var Sources = []string{
+	SourceWifi,
+	SourceBluetooth,
+	SourceTV,
+	SourceOptical,
+	SourceCoaxial,
+	SourceAnalog,
+	SourceUSB,
+}
+
+// sourceLabels maps physical sources to display names.
+var sourceLabels = map[string]string{
+	SourceWifi:      "WiFi",
+	SourceBluetooth: "Bluetooth",
+	SourceTV:        "TV",
+	SourceOptical:   "Optical",
+	SourceCoaxial:   "Coax",
+	SourceAnalog:    "Aux",
+	SourceUSB:       "USB",
+	SourceStandby:   "Standby",
+}
+
+// SourceLabel returns the display name for a physical source.
+func SourceLabel(source string) string {
+	if label, ok := sourceLabels[source]; ok {
+		return label
+	}
+	return source
+}
+
+// IsStreamingSource reports whether transport controls (play/pause, next,
+// previous) have any effect on the given source.
+func IsStreamingSource(source string) bool {
+	return source == SourceWifi || source == SourceBluetooth
+}
+
+// IsWiredInput reports whether the source is a wired input (TV, optical,
+// coaxial, analog, USB) whose playback the speaker can't control.
+func IsWiredInput(source string) bool {
+	switch source {
+	case SourceTV, SourceOptical, SourceCoaxial, SourceAnalog, SourceUSB:
+		return true
+	}
+	return false
+}
+
+// Optional features that only some models expose.
+const (
+	FeatureVoiceAssistant = "voice_assistant"
+	FeatureHeadphones     = "headphones"    // Headphone jack with insertion detection
+	FeatureAutoPowerOn    = "auto_power_on" // Wake from standby on input signal
+)
+
+// Roles of a speaker in a stereo pair.
+const (
+	RolePrimary   = "primary"   // Hosts the API and controls the pair
+	RoleSecondary = "secondary" // Follows the primary; can't be controlled
+)
+
+// SpeakerRole maps the role a speaker reports, as read from
+// settings:/kef/host/speakerRole, to RolePrimary or RoleSecondary. Older
+// firmware says "master" and "slave". Anything else gives "".
+func SpeakerRole(reported string) string {
+	switch strings.ToLower(strings.TrimSpace(reported)) {
+	case "primary", "master":
+		return RolePrimary
+	case "secondary", "slave":
+		return RoleSecondary
+	}
+	return ""
+}
+
+// Repeat modes.
+const (
+	RepeatOff = "off"
+	RepeatOne = "one" // Repeat the current track
+	RepeatAll = "all" // Repeat the queue
+)
+
+// RepeatModes lists the repeat modes in cycling order.
+var RepeatModes = []string{RepeatOff, RepeatAll, RepeatOne}
+
 // PlaybackInfo contains information about the currently playing track.
 type PlaybackInfo struct {
 	Title    string `json:"title"`
@@ -10,18 +107,51 @@ type PlaybackInfo struct {
 	Duration int    `json:"duration"`
 	Position int    `json:"position"`
 	State    string `json:"state"`
+
+	// Play mode; Repeat is one of the Repeat* modes, empty if unknown
+	Shuffle bool   `json:"shuffle"`
+	Repeat  string `json:"repeat,omitempty"`
+
+	// Queue position, one-based; both zero when the source has no queue
+	QueueIndex  int `json:"queue_index,omitempty"`
+	QueueLength int `json:"queue_length,omitempty"`
+
+	// Stream quality; zero or empty when the source doesn't report it
+	SampleRate int    `json:"sample_rate,omitempty"` // Hz, e.g. 96000
+	BitDepth   int    `json:"bit_depth,omitempty"`   // e.g. 24
+	Codec      string `json:"codec,omitempty"`       // e.g. "FLAC"
+}
+
+// QueueItem is a track in the play queue.
+type QueueItem struct {
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album,omitempty"`
+	Duration int    `json:"duration"` // As in PlaybackInfo; zero if unknown
 }
 
 // SpeakerState represents the current state of a KEF speaker.
 type SpeakerState struct {
-	IPAddress    string
-	Port         int
-	Connected    bool
-	Volume       int
-	PlaybackInfo *PlaybackInfo
-	IsPoweredOn  bool
-	Error        string
-	Model        string // Speaker model (e.g., "LSXII", "LS50WII")
+	IPAddress    string        `json:"ip_address"`
+	Port         int           `json:"port"`
+	Connected    bool          `json:"connected"`
+	Volume       int           `json:"volume"`
+	Muted        bool          `json:"muted"`
+	Source       string        `json:"source"` // Active physical source (e.g., "wifi", "tv")
+	PlaybackInfo *PlaybackInfo `json:"playback_info"`
+	IsPoweredOn  bool          `json:"is_powered_on"`
+	Error        string        `json:"error,omitempty"`
+	Model        string        `json:"model"`          // Speaker model (e.g., "LSXII", "LS50WII")
+	Name         string        `json:"name"`           // Name set in the KEF app (e.g., "Living Room")
+	Firmware     string        `json:"firmware"`       // Firmware version (e.g., "4.0.1")
+	MaxVolume    int           `json:"max_volume"`     // Volume limit set in the KEF app, 100 if none
+	Role         string        `json:"role,omitempty"` // RolePrimary or RoleSecondary in a stereo pair; empty if not reported
+
+	// Optional features; only meaningful when supported by the model
+	VoiceAssistant bool `json:"voice_assistant"`
+	Headphones     bool `json:"headphones"`    // Headphones plugged in; the speakers may be silent
+	AutoPowerOn    bool `json:"auto_power_on"` // Wakes from standby on input signal
+	Balance        int  `json:"balance"`       // Left/right balance, negative is left
 }
 
 // Speaker defines the interface for controlling a KEF speaker.
@@ -35,6 +165,11 @@ type Speaker interface {
 	// Volume
 	GetVolume() (int, error)
 	SetVolume(level int) error
+	SetMute(muted bool) error
+
+	// Source
+	GetSource() (string, error)
+	SetSource(source string) error
 
 	// Playback
 	GetPlaybackInfo() (*PlaybackInfo, error)
@@ -43,5 +178,5 @@ type Speaker interface {
 
 	// Info
 	GetSpeakerModel() (string, error)
+	GetFirmwareVersion() (string, error)
 }
-